@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/backup"
+	"github.com/spf13/cobra"
+)
+
+func backupCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "backup [file]",
+		Short: "Save all data into a backup file",
+		Long: "Save users, accounts, entries and the other data into a JSON backup file.\n" +
+			"If the file name ends with .gz, it will be compressed. If the file name is not\n" +
+			"specified, it will be named after the current time.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: backupHandler,
+	}
+}
+
+func restoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore [file]",
+		Short: "Restore data from a backup file",
+		Long: "Restore data from a backup file. The database must be empty, unless --force\n" +
+			"is used which will replace all of the existing data. If the database schema\n" +
+			"is older than the backup, it will be migrated first.",
+		Args: cobra.ExactArgs(1),
+		RunE: restoreHandler,
+	}
+
+	cmd.Flags().Bool("force", false, "replace the existing data in database")
+	return cmd
+}
+
+func backupHandler(cmd *cobra.Command, args []string) error {
+	// Prepare file name
+	path := fmt.Sprintf("duit-%s.json", time.Now().Format("20060102-150405"))
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	// Open database
+	db, err := openDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Create and save the archive
+	archive, err := backup.Create(db)
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	err = backup.WriteFile(path, archive)
+	if err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	fmt.Println("Backup saved to", path)
+	return nil
+}
+
+func restoreHandler(cmd *cobra.Command, args []string) error {
+	// Get flags value
+	force, _ := cmd.Flags().GetBool("force")
+
+	// Read the archive
+	archive, err := backup.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Open database
+	db, err := openDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Restore the archive
+	err = backup.Restore(db, archive, force)
+	if err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	fmt.Printf("Backup from %s restored\n", archive.CreatedAt)
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/api"
+	"github.com/spf13/cobra"
+)
+
+func exportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export all accounts and entries",
+		Long: "Export all accounts and entries as CSV, JSON, hledger journal or beancount.\n" +
+			"In hledger and beancount, transfers become balanced postings between both accounts\n" +
+			"and initial amount of each account becomes an opening balance transaction.",
+		Args: cobra.NoArgs,
+		RunE: exportHandler,
+	}
+
+	cmd.Flags().StringP("format", "f", "json", "export format, i.e. csv, json, ledger or beancount")
+	cmd.Flags().StringP("output", "o", "", "path to output file, print to stdout if not specified")
+	return cmd
+}
+
+func exportHandler(cmd *cobra.Command, args []string) error {
+	// Get flags value
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+
+	// Open database
+	config, err := readConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	handler, err := api.NewHandler(db, nil, config)
+	if err != nil {
+		return err
+	}
+
+	// Prepare output
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	// Write the exported data
+	return handler.WriteExport(w, format)
+}
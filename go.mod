@@ -8,6 +8,8 @@ require (
 	github.com/gofrs/uuid v3.2.0+incompatible
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/julienschmidt/httprouter v1.3.0
+	github.com/lib/pq v1.3.0
+	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/shopspring/decimal v0.0.0-20200227202807-02e2044944cc
 	github.com/shurcooL/httpfs v0.0.0-20190707220628-8d4bc4ba7749 // indirect
 	github.com/shurcooL/vfsgen v0.0.0-20181202132449-6a9ea43bcacd // indirect
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/api"
+	"github.com/RadhiFadlillah/duit/internal/importer"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/spf13/cobra"
+)
+
+func importCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import entries from bank statement",
+	}
+
+	ofxCmd := &cobra.Command{
+		Use:   "ofx [file]",
+		Short: "Import entries from OFX or QFX file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return importHandler(cmd, args[0], func(f *os.File) ([]importer.Transaction, error) {
+				return importer.ParseOFX(f)
+			})
+		},
+	}
+
+	qifCmd := &cobra.Command{
+		Use:   "qif [file]",
+		Short: "Import entries from QIF file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			layout := ""
+			if dateFormat, _ := cmd.Flags().GetString("date-format"); dateFormat != "" {
+				layout = importer.DateLayout(dateFormat)
+			}
+
+			decimalSeparator, _ := cmd.Flags().GetString("decimal-separator")
+			return importHandler(cmd, args[0], func(f *os.File) ([]importer.Transaction, error) {
+				return importer.ParseQIF(f, layout, decimalSeparator)
+			})
+		},
+	}
+
+	qifCmd.Flags().String("date-format", "", "date format in the file, e.g. DD/MM/YYYY")
+	qifCmd.Flags().String("decimal-separator", "", "decimal separator in the file, either . or ,")
+
+	for _, subCmd := range []*cobra.Command{ofxCmd, qifCmd} {
+		subCmd.Flags().Int64P("account", "a", 0, "ID of the destination account")
+		subCmd.Flags().Bool("preview", false, "only print the parsed entries without saving them")
+		subCmd.MarkFlagRequired("account")
+		cmd.AddCommand(subCmd)
+	}
+
+	journalCmd := &cobra.Command{
+		Use:   "journal [file]",
+		Short: "Import accounts and entries from hledger, ledger or beancount journal",
+		Long: "Import accounts and entries from hledger, ledger or beancount journal.\n" +
+			"Assets and liabilities become accounts, while income and expenses become\n" +
+			"categories. Transactions that can't be represented in duit are reported.",
+		Args: cobra.ExactArgs(1),
+		RunE: importJournalHandler,
+	}
+
+	journalCmd.Flags().Bool("preview", false, "only print the parsed entries without saving them")
+	cmd.AddCommand(journalCmd)
+
+	return cmd
+}
+
+func importHandler(cmd *cobra.Command, path string, parse func(*os.File) ([]importer.Transaction, error)) error {
+	// Get flags value
+	accountID, _ := cmd.Flags().GetInt64("account")
+	preview, _ := cmd.Flags().GetBool("preview")
+
+	// Read transactions from file
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	transactions, err := parse(f)
+	if err != nil {
+		return err
+	}
+
+	rows := importer.Rows(transactions)
+	if preview {
+		printImportRows(rows)
+		return nil
+	}
+
+	// Open database
+	config, err := readConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Save the entries
+	handler, err := api.NewHandler(db, nil, config)
+	if err != nil {
+		return err
+	}
+
+	n, err := handler.ImportEntries(accountID, rows)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d entries, skipped %d duplicates\n", n, len(rows)-n)
+	return nil
+}
+
+func importJournalHandler(cmd *cobra.Command, args []string) error {
+	// Get flags value
+	preview, _ := cmd.Flags().GetBool("preview")
+
+	// Read journal from file
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	journal, err := importer.ParseJournal(f)
+	if err != nil {
+		return err
+	}
+
+	if preview {
+		for _, account := range journal.Accounts {
+			fmt.Printf("account\t%s\t%s\t%s\n", account.Name, account.Currency, account.InitialAmount)
+		}
+		printImportRows(journal.Rows)
+		return nil
+	}
+
+	// Open database
+	config, err := readConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Save the journal
+	handler, err := api.NewHandler(db, nil, config)
+	if err != nil {
+		return err
+	}
+
+	n, err := handler.SaveJournal(&journal)
+	if err != nil {
+		return err
+	}
+
+	// Report the transactions that can't be imported
+	nFailed := 0
+	for _, row := range journal.Rows {
+		if row.Error != "" {
+			nFailed++
+			fmt.Printf("line %d: %s\n", row.Line, row.Error)
+		}
+	}
+
+	fmt.Printf("Imported %d entries, skipped %d duplicates and %d failed transactions\n",
+		n, len(journal.Rows)-n-nFailed, nFailed)
+	return nil
+}
+
+// printImportRows prints the parsed rows, one row per line.
+func printImportRows(rows []model.ImportRow) {
+	types := map[int]string{1: "income", 2: "expense", 3: "transfer"}
+	for _, row := range rows {
+		if row.Error != "" {
+			fmt.Printf("%d\terror: %s\n", row.Line, row.Error)
+			continue
+		}
+
+		entry := row.Entry
+		fmt.Printf("%d\t%s\t%s\t%s\t%s\n", row.Line, entry.Date,
+			types[entry.Type], entry.Amount, entry.Description.String)
+	}
+}
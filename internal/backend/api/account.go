@@ -3,9 +3,13 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
 	"github.com/julienschmidt/httprouter"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v3"
 )
 
 // SelectAccounts is handler for GET /api/accounts
@@ -21,14 +25,17 @@ func (h *Handler) SelectAccounts(w http.ResponseWriter, r *http.Request, ps http
 
 	// Prepare SQL statement
 	stmtSelectAccounts, err := tx.Preparex(`
-		SELECT id, name, initial_amount, total
+		SELECT id, name, COALESCE(currency, ?) currency, initial_amount, total
 		FROM account_total
 		ORDER BY name`)
 	checkError(err)
 
 	// Fetch from database
 	accounts := []model.Account{}
-	err = stmtSelectAccounts.Select(&accounts)
+	err = stmtSelectAccounts.Select(&accounts, h.baseCurrency)
+	checkError(err)
+
+	err = h.fillBaseTotal(tx, accounts)
 	checkError(err)
 
 	// Return accounts
@@ -60,8 +67,9 @@ func (h *Handler) InsertAccount(w http.ResponseWriter, r *http.Request, ps httpr
 	}()
 
 	// Save to database
-	res := tx.MustExec(`INSERT INTO account (name, initial_amount) VALUES (?, ?)`,
-		account.Name, account.InitialAmount)
+	currency := h.prepareAccount(&account)
+	res := tx.MustExec(`INSERT INTO account (name, currency, initial_amount) VALUES (?, ?, ?)`,
+		account.Name, currency, account.InitialAmount)
 	account.ID, _ = res.LastInsertId()
 
 	// Commit transaction
@@ -97,18 +105,24 @@ func (h *Handler) UpdateAccount(w http.ResponseWriter, r *http.Request, ps httpr
 	}()
 
 	// Update database
+	currency := h.prepareAccount(&account)
 	tx.MustExec(`UPDATE account 
-		SET name = ?, initial_amount = ? WHERE id = ?`,
-		account.Name, account.InitialAmount, account.ID)
+		SET name = ?, currency = ?, initial_amount = ? WHERE id = ?`,
+		account.Name, currency, account.InitialAmount, account.ID)
 
 	// Fetch the updated account
 	err = tx.Get(&account, `
-		SELECT id, name, initial_amount, total
+		SELECT id, name, COALESCE(currency, ?) currency, initial_amount, total
 		FROM account_total
 		WHERE id = ?`,
-		account.ID)
+		h.baseCurrency, account.ID)
 	checkError(err)
 
+	accounts := []model.Account{account}
+	err = h.fillBaseTotal(tx, accounts)
+	checkError(err)
+	account = accounts[0]
+
 	// Commit transaction
 	err = tx.Commit()
 	checkError(err)
@@ -149,7 +163,56 @@ func (h *Handler) DeleteAccounts(w http.ResponseWriter, r *http.Request, ps http
 		stmt.MustExec(id)
 	}
 
+	// Entries of the deleted accounts are deleted as well,
+	// so their tags might not be used anymore
+	err = removeUnusedTags(tx)
+	checkError(err)
+
 	// Commit transaction
 	err = tx.Commit()
 	checkError(err)
 }
+
+// prepareAccount validates the account and rounds its initial
+// amount. It returns the currency that should be saved to database, which
+// is null if the account uses base currency.
+func (h *Handler) prepareAccount(account *model.Account) null.String {
+	mustValidAccount(*account)
+
+	var currency null.String
+	if account.Currency != "" {
+		code, err := normalizeCurrency(account.Currency)
+		checkError(err)
+		currency = null.StringFrom(code)
+	}
+
+	account.Currency = currency.ValueOrZero()
+	if account.Currency == "" {
+		account.Currency = h.baseCurrency
+	}
+
+	account.InitialAmount = roundCurrency(account.InitialAmount, account.Currency)
+	return currency
+}
+
+// fillBaseTotal rounds total of each account following its currency, then
+// converts it into base currency using the latest exchange rate.
+func (h *Handler) fillBaseTotal(tx *sqlx.Tx, accounts []model.Account) error {
+	rates, err := loadExchangeRates(tx)
+	if err != nil {
+		return err
+	}
+
+	today := time.Now().Format("2006-01-02")
+	for i, account := range accounts {
+		account.Total = roundCurrency(account.Total, account.Currency)
+		accounts[i].Total = account.Total
+
+		total, err := rates.convert(account.Total, account.Currency, h.baseCurrency, today)
+		if err == nil {
+			accounts[i].BaseTotal = decimal.NullDecimal{Decimal: total, Valid: true}
+		}
+	}
+
+	return nil
+}
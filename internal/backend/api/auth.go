@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/RadhiFadlillah/duit/internal/backend/auth"
 	"github.com/julienschmidt/httprouter"
 )
 
-// Login is handler for POST /api/login
+// Login is handler for POST /api/login. If user has enabled two-factor
+// authentication, instead of session it returns token that must be sent
+// with the TOTP code to POST /api/login/2fa.
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	// Decode request
 	var request struct {
@@ -19,20 +22,28 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 	checkError(err)
 
 	// Login using the authenticator
-	session, user, err := h.auth.Login(request.Username, request.Password)
+	result, err := h.auth.Login(r, request.Username, request.Password)
 	checkError(err)
 
-	// Send login result
-	loginResult := map[string]interface{}{}
-	loginResult["session"] = session
-	if user.ID != 0 && user.Username != "" {
-		loginResult["user"] = user
+	writeLoginResult(w, result)
+}
+
+// LoginTwoFactor is handler for POST /api/login/2fa
+func (h *Handler) LoginTwoFactor(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Decode request
+	var request struct {
+		Token string `json:"token"`
+		Code  string `json:"code"`
 	}
 
-	w.Header().Add("Content-Encoding", "gzip")
-	w.Header().Set("Content-Type", "application/json")
-	err = encodeGzippedJSON(w, &loginResult)
+	err := json.NewDecoder(r.Body).Decode(&request)
+	checkError(err)
+
+	// Finish login using the authenticator
+	result, err := h.auth.LoginTwoFactor(r, request.Token, request.Code)
 	checkError(err)
+
+	writeLoginResult(w, result)
 }
 
 // Logout is handler for POST /api/logout
@@ -40,3 +51,34 @@ func (h *Handler) Logout(w http.ResponseWriter, r *http.Request, ps httprouter.P
 	err := h.auth.Logout(r)
 	checkError(err)
 }
+
+// UnlockUser is handler for DELETE /api/admin/users/:id/lockout.
+// It removes the lock caused by failed logins of the specified user.
+func (h *Handler) UnlockUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Unlock the user
+	userID := int64(strToInt(ps.ByName("id")))
+	err := h.auth.UnlockUser(userID)
+	checkError(err)
+}
+
+// writeLoginResult sends login result to client.
+func writeLoginResult(w http.ResponseWriter, result auth.LoginResult) {
+	loginResult := map[string]interface{}{}
+	if result.PendingToken != "" {
+		loginResult["twoFactor"] = true
+		loginResult["token"] = result.PendingToken
+	} else {
+		loginResult["session"] = result.Session
+		if result.User.ID != 0 && result.User.Username != "" {
+			loginResult["user"] = result.User
+		}
+	}
+
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Set("Content-Type", "application/json")
+	err := encodeGzippedJSON(w, &loginResult)
+	checkError(err)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/backup"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SelectBackups is handler for GET /api/admin/backups.
+// It returns the scheduled backups, sorted from the newest.
+func (h *Handler) SelectBackups(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Make sure scheduled backup is enabled
+	if h.backupDir == "" {
+		panic(apierr.NotFound("scheduled backup is not enabled"))
+	}
+
+	// Fetch from backup directory
+	files, err := backup.ListFiles(h.backupDir)
+	checkError(err)
+
+	// Return list of backups
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &files)
+	checkError(err)
+}
+
+// DownloadBackup is handler for GET /api/admin/backups/:name.
+// It returns the backup file as downloadable file.
+func (h *Handler) DownloadBackup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Make sure scheduled backup is enabled
+	if h.backupDir == "" {
+		panic(apierr.NotFound("scheduled backup is not enabled"))
+	}
+
+	// Make sure the file is a scheduled backup, so
+	// the files outside backup directory can't be read
+	name := ps.ByName("name")
+	if !backup.ValidFileName(name) {
+		panic(apierr.NotFound("backup %s doesn't exist", name))
+	}
+
+	f, err := os.Open(filepath.Join(h.backupDir, name))
+	checkError(err)
+	defer f.Close()
+
+	info, err := f.Stat()
+	checkError(err)
+
+	// Return the backup file
+	w.Header().Add("Content-Type", "application/gzip")
+	w.Header().Add("Content-Disposition", `attachment; filename="`+name+`"`)
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
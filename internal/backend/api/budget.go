@@ -0,0 +1,364 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/julienschmidt/httprouter"
+	"github.com/shopspring/decimal"
+)
+
+// Budget for category uses base currency, while
+// budget for account uses currency of the account.
+const selectBudgetQuery = `
+	SELECT b.id, b.category_id, b.account_id, b.amount, b.rollover,
+		b.start_month, c.name category, a.name account,
+		COALESCE(a.currency, ?) currency
+	FROM budget b
+	LEFT JOIN category c ON b.category_id = c.id
+	LEFT JOIN account a ON b.account_id = a.id`
+
+// SelectBudgets is handler for GET /api/budgets
+func (h *Handler) SelectBudgets(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Fetch from database
+	budgets := []model.Budget{}
+	err := h.db.Select(&budgets, selectBudgetQuery+` ORDER BY b.id`, h.baseCurrency)
+	checkError(err)
+
+	// Return list of budgets
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &budgets)
+	checkError(err)
+}
+
+// InsertBudget is handler for POST /api/budget
+func (h *Handler) InsertBudget(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var budget model.Budget
+	err := json.NewDecoder(r.Body).Decode(&budget)
+	checkError(err)
+
+	// Validate input
+	mustValidBudget(&budget)
+
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Save to database
+	res := tx.MustExec(`INSERT INTO budget
+		(category_id, account_id, amount, rollover, start_month)
+		VALUES (?, ?, ?, ?, ?)`,
+		budget.CategoryID, budget.AccountID, budget.Amount,
+		budget.Rollover, budget.StartMonth)
+	budget.ID, _ = res.LastInsertId()
+
+	// Fetch the inserted data
+	err = tx.Get(&budget, selectBudgetQuery+` WHERE b.id = ?`, h.baseCurrency, budget.ID)
+	checkError(err)
+
+	// Commit transaction
+	err = tx.Commit()
+	checkError(err)
+
+	// Return inserted budget
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &budget)
+	checkError(err)
+}
+
+// UpdateBudget is handler for PUT /api/budget
+func (h *Handler) UpdateBudget(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var budget model.Budget
+	err := json.NewDecoder(r.Body).Decode(&budget)
+	checkError(err)
+
+	// Validate input
+	mustValidBudget(&budget)
+
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Update database
+	tx.MustExec(`UPDATE budget
+		SET category_id = ?, account_id = ?, amount = ?, rollover = ?, start_month = ?
+		WHERE id = ?`,
+		budget.CategoryID, budget.AccountID, budget.Amount,
+		budget.Rollover, budget.StartMonth, budget.ID)
+
+	// Fetch the updated data
+	err = tx.Get(&budget, selectBudgetQuery+` WHERE b.id = ?`, h.baseCurrency, budget.ID)
+	checkError(err)
+
+	// Commit transaction
+	err = tx.Commit()
+	checkError(err)
+
+	// Return updated budget
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &budget)
+	checkError(err)
+}
+
+// DeleteBudgets is handler for DELETE /api/budgets
+func (h *Handler) DeleteBudgets(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var ids []int64
+	err := json.NewDecoder(r.Body).Decode(&ids)
+	checkError(err)
+
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Delete from database
+	stmt, err := tx.Preparex(`DELETE FROM budget WHERE id = ?`)
+	checkError(err)
+
+	for _, id := range ids {
+		stmt.MustExec(id)
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	checkError(err)
+}
+
+// GetBudgetsStatus is handler for GET /api/budgets/status.
+// Budgets that started after the specified month are excluded.
+func (h *Handler) GetBudgetsStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Get URL parameter
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		month = time.Now().Format("2006-01")
+	} else if _, err := time.Parse("2006-01", month); err != nil {
+		panic(apierr.Validation("month must be formatted as YYYY-MM").WithField("month"))
+	}
+
+	// Start transaction
+	// We only use it to fetch the data,
+	// so just rollback it later
+	tx := h.db.MustBegin()
+	defer tx.Rollback()
+
+	// Fetch from database
+	budgets := []model.Budget{}
+	err := tx.Select(&budgets, selectBudgetQuery+`
+		WHERE b.start_month <= ?
+		ORDER BY b.id`, h.baseCurrency, month)
+	checkError(err)
+
+	rates, err := loadExchangeRates(tx)
+	checkError(err)
+
+	// Calculate status of each budget
+	result := []model.BudgetStatus{}
+	for _, budget := range budgets {
+		// If rollover is enabled, we need spending since the first month
+		// of budget to calculate the amount that carried to this month
+		firstMonth := month
+		if budget.Rollover {
+			firstMonth = budget.StartMonth
+		}
+
+		missing := missingRates{}
+		spending, err := h.budgetSpending(tx, rates, missing, budget, firstMonth, month)
+		checkError(err)
+
+		status := budgetStatus(budget, month, spending)
+		status.MissingRates = missing.list()
+		result = append(result, status)
+	}
+
+	// Return final result
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &result)
+	checkError(err)
+}
+
+// budgetStatus calculates status of a budget in the month from its spending
+// in each month. If rollover is enabled, the unspent amount in each month
+// since the start month is carried to the next month. The overspent amount
+// is not carried, so the carried amount is never negative.
+func budgetStatus(budget model.Budget, month string, spending map[string]decimal.Decimal) model.BudgetStatus {
+	carried := decimal.Zero
+	if budget.Rollover {
+		for m := budget.StartMonth; m < month; m = nextMonth(m) {
+			carried = budget.Amount.Add(carried).Sub(spending[m])
+			if carried.IsNegative() {
+				carried = decimal.Zero
+			}
+		}
+	}
+
+	limit := budget.Amount.Add(carried)
+	spent := spending[month]
+
+	percentage := decimal.Zero
+	if limit.IsPositive() {
+		percentage = spent.Div(limit).Mul(decimal.New(100, 0)).Round(2)
+	} else if spent.IsPositive() {
+		percentage = decimal.New(100, 0)
+	}
+
+	return model.BudgetStatus{
+		Budget:     budget,
+		Month:      month,
+		Carried:    carried,
+		Spent:      spent,
+		Remaining:  limit.Sub(spent),
+		Percentage: percentage,
+	}
+}
+
+// budgetSpending returns total expense for a budget in each month between
+// first and last month, converted to the currency of budget. For category
+// budget, the expense in its sub categories and splits are included. The
+// expenses that can't be converted are skipped and added to missing rates.
+func (h *Handler) budgetSpending(tx *sqlx.Tx, rates *exchangeRates, missing missingRates, budget model.Budget, firstMonth, lastMonth string) (map[string]decimal.Decimal, error) {
+	// Prepare query
+	dateStart := firstMonth + "-01"
+	dateEnd := nextMonth(lastMonth) + "-01"
+
+	var query string
+	var args []interface{}
+
+	if budget.CategoryID.Valid {
+		categoryIDs, err := categoryDescendants(tx, budget.CategoryID.Int64)
+		if err != nil {
+			return nil, err
+		}
+
+		query = `
+			SELECT e.date, e.amount, COALESCE(a.currency, ?) currency
+			FROM entry e
+			JOIN account a ON a.id = e.account_id
+			WHERE e.type = 2 AND e.date >= ? AND e.date < ?
+			AND e.category_id IN (?)
+			AND e.id NOT IN (SELECT entry_id FROM entry_split)
+			UNION ALL
+			SELECT e.date, s.amount, COALESCE(a.currency, ?) currency
+			FROM entry_split s
+			JOIN entry e ON e.id = s.entry_id
+			JOIN account a ON a.id = e.account_id
+			WHERE e.type = 2 AND e.date >= ? AND e.date < ?
+			AND s.category_id IN (?)`
+		args = []interface{}{
+			h.baseCurrency, dateStart, dateEnd, categoryIDs,
+			h.baseCurrency, dateStart, dateEnd, categoryIDs}
+	} else {
+		query = `
+			SELECT e.date, e.amount, COALESCE(a.currency, ?) currency
+			FROM entry e
+			JOIN account a ON a.id = e.account_id
+			WHERE e.type = 2 AND e.date >= ? AND e.date < ?
+			AND e.account_id = ?`
+		args = []interface{}{h.baseCurrency, dateStart, dateEnd, budget.AccountID}
+	}
+
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch expenses
+	expenses := []struct {
+		Date     string          `db:"date"`
+		Amount   decimal.Decimal `db:"amount"`
+		Currency string          `db:"currency"`
+	}{}
+
+	err = tx.Select(&expenses, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sum it by month
+	spending := make(map[string]decimal.Decimal)
+	for _, expense := range expenses {
+		amount, err := rates.convert(expense.Amount, expense.Currency, budget.Currency, expense.Date)
+		if err != nil {
+			missing.add(expense.Currency)
+			continue
+		}
+
+		month := expense.Date[:7]
+		spending[month] = spending[month].Add(amount)
+	}
+
+	return spending, nil
+}
+
+// mustValidBudget panics if the budget is not valid.
+func mustValidBudget(budget *model.Budget) {
+	if budget.CategoryID.Valid == budget.AccountID.Valid {
+		panic(apierr.Validation("budget must be for either a category or an account"))
+	}
+
+	if !budget.Amount.IsPositive() {
+		panic(apierr.Validation("amount must be positive").WithField("amount"))
+	}
+
+	if budget.StartMonth == "" {
+		budget.StartMonth = time.Now().Format("2006-01")
+	} else if _, err := time.Parse("2006-01", budget.StartMonth); err != nil {
+		panic(apierr.Validation("start month must be formatted as YYYY-MM").WithField("startMonth"))
+	}
+}
+
+// nextMonth returns the month after the specified month.
+// Both of them are formatted as YYYY-MM.
+func nextMonth(month string) string {
+	t, err := time.Parse("2006-01", month)
+	if err != nil {
+		return month
+	}
+
+	return t.AddDate(0, 1, 0).Format("2006-01")
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestBudgetStatus(t *testing.T) {
+	d := decimal.RequireFromString
+
+	tests := []struct {
+		name       string
+		amount     string
+		rollover   bool
+		startMonth string
+		month      string
+		spending   map[string]string
+		carried    string
+		spent      string
+		remaining  string
+		percentage string
+	}{{
+		name:       "without rollover only this month counted",
+		amount:     "100",
+		month:      "2020-03",
+		spending:   map[string]string{"2020-01": "10", "2020-03": "40"},
+		carried:    "0",
+		spent:      "40",
+		remaining:  "60",
+		percentage: "40",
+	}, {
+		name:       "unspent amount carried each month",
+		amount:     "100",
+		rollover:   true,
+		month:      "2020-03",
+		spending:   map[string]string{"2020-01": "70", "2020-02": "80", "2020-03": "50"},
+		carried:    "50",
+		spent:      "50",
+		remaining:  "100",
+		percentage: "33.33",
+	}, {
+		name:       "overspent month resets carried amount",
+		amount:     "100",
+		rollover:   true,
+		month:      "2020-03",
+		spending:   map[string]string{"2020-01": "130", "2020-02": "90"},
+		carried:    "10",
+		spent:      "0",
+		remaining:  "110",
+		percentage: "0",
+	}, {
+		name:       "across new year",
+		amount:     "100",
+		rollover:   true,
+		startMonth: "2020-11",
+		month:      "2021-02",
+		spending:   map[string]string{"2020-11": "100", "2020-12": "60", "2021-01": "100"},
+		carried:    "40",
+		spent:      "0",
+		remaining:  "140",
+		percentage: "0",
+	}, {
+		name:       "rollover in the start month",
+		amount:     "100",
+		rollover:   true,
+		month:      "2020-01",
+		spending:   map[string]string{"2020-01": "25"},
+		carried:    "0",
+		spent:      "25",
+		remaining:  "75",
+		percentage: "25",
+	}, {
+		name:       "overspent this month",
+		amount:     "100",
+		month:      "2020-01",
+		spending:   map[string]string{"2020-01": "150"},
+		carried:    "0",
+		spent:      "150",
+		remaining:  "-50",
+		percentage: "150",
+	}, {
+		name:       "zero limit with spending",
+		amount:     "0",
+		month:      "2020-01",
+		spending:   map[string]string{"2020-01": "20"},
+		carried:    "0",
+		spent:      "20",
+		remaining:  "-20",
+		percentage: "100",
+	}, {
+		name:       "zero limit without spending",
+		amount:     "0",
+		month:      "2020-01",
+		carried:    "0",
+		spent:      "0",
+		remaining:  "0",
+		percentage: "0",
+	}}
+
+	for _, test := range tests {
+		startMonth := test.startMonth
+		if startMonth == "" {
+			startMonth = "2020-01"
+		}
+
+		budget := model.Budget{
+			Amount:     d(test.amount),
+			Rollover:   test.rollover,
+			StartMonth: startMonth,
+		}
+
+		spending := make(map[string]decimal.Decimal)
+		for month, amount := range test.spending {
+			spending[month] = d(amount)
+		}
+
+		got := budgetStatus(budget, test.month, spending)
+		if !got.Carried.Equal(d(test.carried)) || !got.Spent.Equal(d(test.spent)) ||
+			!got.Remaining.Equal(d(test.remaining)) || !got.Percentage.Equal(d(test.percentage)) {
+			t.Errorf("%s: expected carried %s, spent %s, remaining %s, percentage %s; "+
+				"got %s, %s, %s, %s", test.name,
+				test.carried, test.spent, test.remaining, test.percentage,
+				got.Carried, got.Spent, got.Remaining, got.Percentage)
+		}
+	}
+}
+
+func TestBudgetSpending(t *testing.T) {
+	db := openTestDB(t)
+	db.MustExec(`INSERT INTO account (id, name, currency) VALUES
+		(1, 'Cash', NULL), (2, 'Dollar', 'USD'), (3, 'Euro', 'EUR')`)
+	db.MustExec(`INSERT INTO category (id, parent_id, name) VALUES
+		(1, NULL, 'Food'), (2, 1, 'Groceries'), (3, NULL, 'Transport')`)
+	db.MustExec(`INSERT INTO currency_rate (date, from_currency, to_currency, rate)
+		VALUES ('2020-01-01', 'USD', 'IDR', 15000)`)
+	db.MustExec(`INSERT INTO entry (id, account_id, category_id, type, amount, date) VALUES
+		(1, 1, 1,    2, 100, '2020-01-05'),
+		(2, 1, 2,    2, 50,  '2020-01-10'),
+		(3, 1, NULL, 2, 200, '2020-02-01'),
+		(4, 1, 3,    2, 30,  '2020-02-01'),
+		(5, 1, 1,    1, 500, '2020-02-01'),
+		(6, 2, 1,    2, 10,  '2020-02-02'),
+		(7, 3, 1,    2, 7,   '2020-02-03'),
+		(8, 1, 1,    2, 999, '2020-03-01')`)
+	db.MustExec(`INSERT INTO entry_split (entry_id, category_id, amount) VALUES
+		(3, 2, 60), (3, 3, 140)`)
+
+	tx := db.MustBegin()
+	defer tx.Rollback()
+
+	rates, err := loadExchangeRates(tx)
+	if err != nil {
+		t.Fatalf("failed to load rates: %v", err)
+	}
+
+	h := &Handler{baseCurrency: "IDR"}
+	tests := []struct {
+		name     string
+		budget   model.Budget
+		expected map[string]string
+		missing  []string
+	}{{
+		name:   "category with sub categories and splits",
+		budget: model.Budget{CategoryID: null.IntFrom(1), Currency: "IDR"},
+		expected: map[string]string{
+			"2020-01": "150",
+			"2020-02": "150060",
+		},
+		missing: []string{"EUR"},
+	}, {
+		name:   "sub category only",
+		budget: model.Budget{CategoryID: null.IntFrom(2), Currency: "IDR"},
+		expected: map[string]string{
+			"2020-01": "50",
+			"2020-02": "60",
+		},
+		missing: []string{},
+	}, {
+		name:     "account in its own currency",
+		budget:   model.Budget{AccountID: null.IntFrom(2), Currency: "USD"},
+		expected: map[string]string{"2020-02": "10"},
+		missing:  []string{},
+	}}
+
+	for _, test := range tests {
+		missing := missingRates{}
+		spending, err := h.budgetSpending(tx, rates, missing, test.budget, "2020-01", "2020-02")
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+
+		got := make(map[string]string)
+		for month, amount := range spending {
+			got[month] = amount.String()
+		}
+
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected spending %v, got %v", test.name, test.expected, got)
+		}
+
+		if !reflect.DeepEqual(missing.list(), test.missing) {
+			t.Errorf("%s: expected missing rates %v, got %v", test.name, test.missing, missing.list())
+		}
+	}
+}
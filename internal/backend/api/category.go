@@ -0,0 +1,284 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SelectCategories is handler for GET /api/categories
+func (h *Handler) SelectCategories(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Fetch from database
+	categories := []model.Category{}
+	err := h.db.Select(&categories,
+		`SELECT id, parent_id, name FROM category ORDER BY name`)
+	checkError(err)
+
+	// Return list of categories
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &categories)
+	checkError(err)
+}
+
+// InsertCategory is handler for POST /api/category
+func (h *Handler) InsertCategory(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var category model.Category
+	err := json.NewDecoder(r.Body).Decode(&category)
+	checkError(err)
+
+	// Validate input
+	if category.Name == "" {
+		panic(apierr.Validation("name must not empty").WithField("name"))
+	}
+
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Make sure parent exists
+	if category.ParentID.Valid {
+		mustCategoryExist(tx, category.ParentID.Int64)
+	}
+
+	// Save to database
+	res := tx.MustExec(`INSERT INTO category (parent_id, name) VALUES (?, ?)`,
+		category.ParentID, category.Name)
+	category.ID, _ = res.LastInsertId()
+
+	// Commit transaction
+	err = tx.Commit()
+	checkError(err)
+
+	// Return inserted category
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &category)
+	checkError(err)
+}
+
+// UpdateCategory is handler for PUT /api/category
+func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var category model.Category
+	err := json.NewDecoder(r.Body).Decode(&category)
+	checkError(err)
+
+	// Validate input
+	if category.Name == "" {
+		panic(apierr.Validation("name must not empty").WithField("name"))
+	}
+
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Make sure category exists
+	mustCategoryExist(tx, category.ID)
+
+	// Make sure the new parent is not the category itself or its
+	// descendant, since it will create a cycle in category tree
+	if category.ParentID.Valid {
+		mustCategoryExist(tx, category.ParentID.Int64)
+
+		descendants, err := categoryDescendants(tx, category.ID)
+		checkError(err)
+
+		for _, id := range descendants {
+			if id == category.ParentID.Int64 {
+				panic(apierr.Validation("category can't be moved into itself or its sub category"))
+			}
+		}
+	}
+
+	// Update database
+	tx.MustExec(`UPDATE category SET parent_id = ?, name = ? WHERE id = ?`,
+		category.ParentID, category.Name, category.ID)
+
+	// Commit transaction
+	err = tx.Commit()
+	checkError(err)
+
+	// Return updated category
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &category)
+	checkError(err)
+}
+
+// DeleteCategories is handler for DELETE /api/categories.
+// Entries that use the deleted categories will be moved into category that
+// specified in URL parameter `reassign`, or become uncategorized if it's
+// not specified. Budgets are moved the same way, so if the category has
+// budgets it can only be deleted with `reassign`. Sub categories will be
+// moved into the parent of the deleted category.
+func (h *Handler) DeleteCategories(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Get URL parameter
+	var reassignID sql.NullInt64
+	if strReassign := r.URL.Query().Get("reassign"); strReassign != "" {
+		reassignID.Int64 = int64(strToInt(strReassign))
+		reassignID.Valid = true
+	}
+
+	// Decode request
+	var ids []int64
+	err := json.NewDecoder(r.Body).Decode(&ids)
+	checkError(err)
+
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Make sure the replacement category is valid
+	if reassignID.Valid {
+		mustCategoryExist(tx, reassignID.Int64)
+
+		for _, id := range ids {
+			if id == reassignID.Int64 {
+				panic(apierr.Validation("entries can't be moved into deleted category"))
+			}
+		}
+	}
+
+	// Prepare statements
+	stmtGetParent, err := tx.Preparex(`SELECT parent_id FROM category WHERE id = ?`)
+	checkError(err)
+
+	stmtCountBudgets, err := tx.Preparex(`SELECT COUNT(*) FROM budget WHERE category_id = ?`)
+	checkError(err)
+
+	stmtReassignBudgets, err := tx.Preparex(`UPDATE budget
+		SET category_id = ? WHERE category_id = ?`)
+	checkError(err)
+
+	stmtReassignEntries, err := tx.Preparex(`UPDATE entry
+		SET category_id = ? WHERE category_id = ?`)
+	checkError(err)
+
+	stmtReassignSplits, err := tx.Preparex(`UPDATE entry_split
+		SET category_id = ? WHERE category_id = ?`)
+	checkError(err)
+
+	stmtMoveChildren, err := tx.Preparex(`UPDATE category
+		SET parent_id = ? WHERE parent_id = ?`)
+	checkError(err)
+
+	stmtDelete, err := tx.Preparex(`DELETE FROM category WHERE id = ?`)
+	checkError(err)
+
+	// Delete from database
+	for _, id := range ids {
+		var parentID sql.NullInt64
+		err = stmtGetParent.Get(&parentID, id)
+		checkError(err)
+		if err == sql.ErrNoRows {
+			continue
+		}
+
+		// Budget can't be uncategorized, and without this
+		// it will be silently deleted along with the category
+		if !reassignID.Valid {
+			var nBudgets int
+			err = stmtCountBudgets.Get(&nBudgets, id)
+			checkError(err)
+
+			if nBudgets > 0 {
+				panic(apierr.Conflict("category %d has budgets, "+
+					"reassign it into another category or delete its budgets first", id))
+			}
+		}
+
+		stmtReassignEntries.MustExec(reassignID, id)
+		stmtReassignSplits.MustExec(reassignID, id)
+		stmtReassignBudgets.MustExec(reassignID, id)
+		stmtMoveChildren.MustExec(parentID, id)
+		stmtDelete.MustExec(id)
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	checkError(err)
+}
+
+// mustCategoryExist panics if category with specified ID doesn't exist.
+func mustCategoryExist(tx *sqlx.Tx, id int64) {
+	var tmpID int64
+	err := tx.Get(&tmpID, `SELECT id FROM category WHERE id = ?`, id)
+	checkError(err)
+
+	if err == sql.ErrNoRows {
+		panic(apierr.NotFound("category %d doesn't exist", id))
+	}
+}
+
+// categoryDescendants returns ID of the specified category
+// along with ID of all of its sub categories.
+func categoryDescendants(tx *sqlx.Tx, id int64) ([]int64, error) {
+	// Fetch all categories. There shouldn't be many
+	// of them, so it's fine to process it in memory.
+	categories := []model.Category{}
+	err := tx.Select(&categories, `SELECT id, parent_id FROM category`)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[int64][]int64)
+	for _, c := range categories {
+		if c.ParentID.Valid {
+			children[c.ParentID.Int64] = append(children[c.ParentID.Int64], c.ID)
+		}
+	}
+
+	// Walk through the tree
+	result := []int64{id}
+	visited := map[int64]struct{}{id: {}}
+	for i := 0; i < len(result); i++ {
+		for _, childID := range children[result[i]] {
+			if _, exist := visited[childID]; !exist {
+				visited[childID] = struct{}{}
+				result = append(result, childID)
+			}
+		}
+	}
+
+	return result, nil
+}
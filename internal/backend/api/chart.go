@@ -2,6 +2,8 @@ package api
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/RadhiFadlillah/duit/internal/model"
@@ -27,49 +29,86 @@ func (h *Handler) GetChartsData(w http.ResponseWriter, r *http.Request, ps httpr
 	defer tx.Rollback()
 
 	// Prepare statements
-	stmtSelectAccounts, err := tx.Preparex(`SELECT id, name FROM account`)
+	stmtSelectAccounts, err := tx.Preparex(`
+		SELECT id, name, COALESCE(currency, ?) currency FROM account`)
 	checkError(err)
 
-	stmtGetChartSeries, err := tx.Preparex(`
-		SELECT account_id, MONTH(CONCAT(month, "-01")) month, amount
-		FROM cumulative_amount
-		WHERE YEAR(CONCAT(month, "-01")) = ?`)
-	checkError(err)
-
-	stmtGetLimit, err := tx.Preparex(`
-		SELECT MIN(amount) min_amount, MAX(amount) max_amount
-		FROM cumulative_amount`)
+	stmtSelectCumulativeAmount, err := tx.Preparex(`
+		SELECT c.account_id, c.month, c.amount, COALESCE(a.currency, ?) currency
+		FROM cumulative_amount c
+		JOIN account a ON a.id = c.account_id
+		ORDER BY c.account_id, c.month`)
 	checkError(err)
 
 	// Fetch from database
 	accounts := []model.Account{}
-	err = stmtSelectAccounts.Select(&accounts)
+	err = stmtSelectAccounts.Select(&accounts, h.baseCurrency)
 	checkError(err)
 
-	chartSeries := []model.ChartSeries{}
-	err = stmtGetChartSeries.Select(&chartSeries, year)
+	cumulativeAmounts := []struct {
+		AccountID int64           `db:"account_id"`
+		Month     string          `db:"month"`
+		Amount    decimal.Decimal `db:"amount"`
+		Currency  string          `db:"currency"`
+	}{}
+	err = stmtSelectCumulativeAmount.Select(&cumulativeAmounts, h.baseCurrency)
 	checkError(err)
 
-	chartLimit := struct {
-		MinAmount decimal.Decimal `db:"min_amount"`
-		MaxAmount decimal.Decimal `db:"max_amount"`
-	}{}
-	err = stmtGetLimit.Get(&chartLimit)
+	rates, err := loadExchangeRates(tx)
 	checkError(err)
 
+	// Convert amounts into base currency, using exchange rate at the end of
+	// month. Month is formatted as YYYY-MM, so by appending "-31" it will be
+	// compared as the last day of month. Limit is calculated from all data,
+	// while series only contains data for the specified year. Amounts that
+	// don't have exchange rate are skipped, and their currency is reported.
+	strYear := strconv.Itoa(year)
+	chartSeries := []model.ChartSeries{}
+	missing := missingRates{}
+	var minAmount, maxAmount decimal.Decimal
+
+	nConverted := 0
+	for _, ca := range cumulativeAmounts {
+		amount, err := rates.convert(ca.Amount, ca.Currency, h.baseCurrency, ca.Month+"-31")
+		if err != nil {
+			missing.add(ca.Currency)
+			continue
+		}
+
+		if nConverted == 0 || amount.LessThan(minAmount) {
+			minAmount = amount
+		}
+
+		if nConverted == 0 || amount.GreaterThan(maxAmount) {
+			maxAmount = amount
+		}
+		nConverted++
+
+		if strings.HasPrefix(ca.Month, strYear+"-") {
+			chartSeries = append(chartSeries, model.ChartSeries{
+				AccountID: ca.AccountID,
+				Month:     strToInt(ca.Month[5:]),
+				Amount:    amount,
+			})
+		}
+	}
+
 	// Calculate limit
-	lenMaxAmount := len(chartLimit.MaxAmount.StringFixed(0))
+	lenMaxAmount := len(maxAmount.StringFixed(0))
 	divisor := decimal.New(1, int32(lenMaxAmount-1))
-	max := chartLimit.MaxAmount.Div(divisor).Ceil().Mul(divisor)
-	min := chartLimit.MinAmount.Div(divisor).Ceil().Mul(divisor)
+	max := maxAmount.Div(divisor).Ceil().Mul(divisor)
+	min := minAmount.Div(divisor).Ceil().Mul(divisor)
 
 	// Return final result
 	result := map[string]interface{}{
 		"year":     year,
+		"currency": h.baseCurrency,
 		"accounts": accounts,
 		"series":   chartSeries,
 		"min":      min,
 		"max":      max,
+
+		"missingRates": missing.list(),
 	}
 
 	w.Header().Add("Content-Encoding", "gzip")
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+)
+
+const defaultCurrency = "IDR"
+
+// currencyMinorUnits is list of currencies whose minor units is not 2,
+// based on ISO 4217. Any currency that not listed here uses 2 minor units.
+var currencyMinorUnits = map[string]int32{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0,
+	"KMF": 0, "KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0,
+	"VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// normalizeCurrency converts currency code to uppercase and
+// makes sure it's formatted like ISO 4217 code.
+func normalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", apierr.Validation("currency %q is not valid", code).WithField("currency")
+	}
+
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", apierr.Validation("currency %q is not valid", code).WithField("currency")
+		}
+	}
+
+	return code, nil
+}
+
+// roundCurrency rounds amount following minor units of the currency.
+func roundCurrency(amount decimal.Decimal, currency string) decimal.Decimal {
+	places, exist := currencyMinorUnits[currency]
+	if !exist {
+		places = 2
+	}
+
+	return amount.Round(places)
+}
+
+// accountCurrency returns currency of an account. If the account doesn't
+// have any currency, it uses the base currency.
+func (h *Handler) accountCurrency(tx *sqlx.Tx, accountID int64) string {
+	var currency string
+	err := tx.Get(&currency, `SELECT COALESCE(currency, ?) FROM account WHERE id = ?`,
+		h.baseCurrency, accountID)
+	checkError(err)
+
+	if err != nil {
+		panic(apierr.NotFound("account %d doesn't exist", accountID))
+	}
+
+	return currency
+}
+
+// exchangeRates is list of exchange rates, grouped by currency pair.
+type exchangeRates struct {
+	pairs      map[string][]model.Rate
+	currencies []string
+}
+
+// loadExchangeRates fetches all exchange rates from database.
+func loadExchangeRates(tx *sqlx.Tx) (*exchangeRates, error) {
+	rates := []model.Rate{}
+	err := tx.Select(&rates, `
+		SELECT id, date, from_currency, to_currency, rate
+		FROM currency_rate
+		ORDER BY date`)
+	if err != nil {
+		return nil, err
+	}
+
+	return newExchangeRates(rates), nil
+}
+
+// newExchangeRates groups the rates, which must be sorted by date.
+func newExchangeRates(rates []model.Rate) *exchangeRates {
+	er := &exchangeRates{pairs: make(map[string][]model.Rate)}
+	exist := make(map[string]struct{})
+	for _, rate := range rates {
+		key := rate.From + "/" + rate.To
+		er.pairs[key] = append(er.pairs[key], rate)
+
+		for _, currency := range []string{rate.From, rate.To} {
+			if _, found := exist[currency]; !found {
+				exist[currency] = struct{}{}
+				er.currencies = append(er.currencies, currency)
+			}
+		}
+	}
+
+	sort.Strings(er.currencies)
+	return er
+}
+
+// directRate returns rate from a currency to another currency at the
+// specified date, either using the rate for that pair or its inverse.
+// It uses the latest rate on or before the date. If there are none,
+// the earliest rate will be used instead.
+func (er *exchangeRates) directRate(from, to, date string) (decimal.Decimal, bool) {
+	pick := func(rates []model.Rate) decimal.Decimal {
+		idx := sort.Search(len(rates), func(i int) bool {
+			return rates[i].Date > date
+		})
+
+		if idx == 0 {
+			return rates[0].Rate
+		}
+
+		return rates[idx-1].Rate
+	}
+
+	if rates := er.pairs[from+"/"+to]; len(rates) > 0 {
+		return pick(rates), true
+	}
+
+	if rates := er.pairs[to+"/"+from]; len(rates) > 0 {
+		rate := pick(rates)
+		if !rate.IsZero() {
+			return decimal.New(1, 0).Div(rate), true
+		}
+	}
+
+	return decimal.Zero, false
+}
+
+// rate returns rate from a currency to another currency at the specified
+// date. If there are no rate for the pair, it will try to find an
+// intermediate currency that connects both of them.
+func (er *exchangeRates) rate(from, to, date string) (decimal.Decimal, bool) {
+	if from == to {
+		return decimal.New(1, 0), true
+	}
+
+	if rate, found := er.directRate(from, to, date); found {
+		return rate, true
+	}
+
+	for _, currency := range er.currencies {
+		if currency == from || currency == to {
+			continue
+		}
+
+		rate1, found1 := er.directRate(from, currency, date)
+		rate2, found2 := er.directRate(currency, to, date)
+		if found1 && found2 {
+			return rate1.Mul(rate2), true
+		}
+	}
+
+	return decimal.Zero, false
+}
+
+// convert converts amount from a currency to another currency
+// using the exchange rate at the specified date.
+func (er *exchangeRates) convert(amount decimal.Decimal, from, to, date string) (decimal.Decimal, error) {
+	rate, found := er.rate(from, to, date)
+	if !found {
+		return decimal.Zero, fmt.Errorf("no exchange rate from %s to %s", from, to)
+	}
+
+	return roundCurrency(amount.Mul(rate), to), nil
+}
+
+// missingRates is set of currencies whose amounts can't be converted since
+// their exchange rate is not entered yet. It's reported to client instead
+// of failing the whole request, so user knows which rates to enter.
+type missingRates map[string]struct{}
+
+func (m missingRates) add(currency string) {
+	m[currency] = struct{}{}
+}
+
+// list returns the currencies, sorted alphabetically.
+func (m missingRates) list() []string {
+	currencies := make([]string, 0, len(m))
+	for currency := range m {
+		currencies = append(currencies, currency)
+	}
+
+	sort.Strings(currencies)
+	return currencies
+}
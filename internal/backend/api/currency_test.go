@@ -0,0 +1,127 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+func TestNormalizeCurrency(t *testing.T) {
+	tests := map[string]string{
+		"usd":   "USD",
+		" Eur ": "EUR",
+		"US$":   "",
+		"12A":   "",
+		"USDT":  "",
+		"":      "",
+	}
+
+	for input, expected := range tests {
+		got, err := normalizeCurrency(input)
+		if expected == "" {
+			if err == nil {
+				t.Errorf("%q: expected error, got %q", input, got)
+			}
+			continue
+		}
+
+		if err != nil || got != expected {
+			t.Errorf("%q: expected %q, got %q (%v)", input, expected, got, err)
+		}
+	}
+}
+
+func TestRoundCurrency(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		expected string
+	}{
+		{"1234.5678", "USD", "1234.57"},
+		{"1234.5678", "IDR", "1234.57"},
+		{"1234.5678", "JPY", "1235"},
+		{"1234.5678", "KWD", "1234.568"},
+		{"1234.56789", "CLF", "1234.5679"},
+		{"0.005", "EUR", "0.01"},
+	}
+
+	for _, test := range tests {
+		got := roundCurrency(decimal.RequireFromString(test.amount), test.currency)
+		if !got.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("%s %s: expected %s, got %s", test.amount, test.currency, test.expected, got)
+		}
+	}
+}
+
+func TestExchangeRate(t *testing.T) {
+	rate := func(date, from, to, value string) model.Rate {
+		return model.Rate{Date: date, From: from, To: to, Rate: decimal.RequireFromString(value)}
+	}
+
+	rates := newExchangeRates([]model.Rate{
+		rate("2020-01-01", "USD", "IDR", "14000"),
+		rate("2020-01-01", "EUR", "USD", "1.25"),
+		rate("2020-02-01", "USD", "IDR", "15000"),
+		rate("2020-03-01", "JPY", "USD", "0.01"),
+	})
+
+	tests := []struct {
+		name     string
+		from, to string
+		date     string
+		expected string
+	}{
+		{"same currency", "IDR", "IDR", "2020-01-15", "1"},
+		{"direct rate on its date", "USD", "IDR", "2020-01-01", "14000"},
+		{"direct rate uses the latest before date", "USD", "IDR", "2020-01-31", "14000"},
+		{"direct rate after the newer rate", "USD", "IDR", "2020-05-01", "15000"},
+		{"date before any rate uses the earliest", "USD", "IDR", "2019-06-01", "14000"},
+		{"inverse rate", "USD", "EUR", "2020-01-15", "0.8"},
+		{"one hop through intermediate currency", "EUR", "IDR", "2020-02-15", "18750"},
+		{"one hop with inverse rates", "IDR", "JPY", "2020-03-15", "0.00666666666667"},
+	}
+
+	for _, test := range tests {
+		got, found := rates.rate(test.from, test.to, test.date)
+		if !found {
+			t.Errorf("%s: rate not found", test.name)
+			continue
+		}
+
+		if !got.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, got)
+		}
+	}
+
+	// Two hops are not supported
+	rates = newExchangeRates([]model.Rate{
+		rate("2020-01-01", "USD", "IDR", "14000"),
+		rate("2020-01-01", "EUR", "USD", "1.25"),
+		rate("2020-01-01", "GBP", "EUR", "1.2"),
+	})
+
+	if _, found := rates.rate("GBP", "IDR", "2020-01-01"); found {
+		t.Errorf("rate that needs two hops must not be found")
+	}
+
+	if _, found := rates.rate("USD", "CHF", "2020-01-01"); found {
+		t.Errorf("rate for unknown currency must not be found")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	rates := newExchangeRates([]model.Rate{{
+		Date: "2020-01-01", From: "USD", To: "JPY",
+		Rate: decimal.RequireFromString("108.456"),
+	}})
+
+	got, err := rates.convert(decimal.RequireFromString("10.5"), "USD", "JPY", "2020-01-10")
+	if err != nil || !got.Equal(decimal.New(1139, 0)) {
+		t.Errorf("expected 1139, got %s (%v)", got, err)
+	}
+
+	if _, err = rates.convert(decimal.New(1, 0), "USD", "EUR", "2020-01-10"); err == nil {
+		t.Errorf("expected error for missing rate")
+	}
+}
@@ -2,12 +2,21 @@ package api
 
 import (
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
 	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
 	"github.com/julienschmidt/httprouter"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v3"
 )
 
 // SelectEntries is handler for GET /api/entries
@@ -18,6 +27,35 @@ func (h *Handler) SelectEntries(w http.ResponseWriter, r *http.Request, ps httpr
 	// Get URL parameter
 	page := strToInt(r.URL.Query().Get("page"))
 	accountID := strToInt(r.URL.Query().Get("account"))
+	categoryID := strToInt(r.URL.Query().Get("category"))
+	tags := normalizeTags(r.URL.Query()["tag"])
+	tagMode := r.URL.Query().Get("tagMode")
+	search := strings.TrimSpace(r.URL.Query().Get("search"))
+	dateFrom := r.URL.Query().Get("from")
+	dateTo := r.URL.Query().Get("to")
+	minAmount := r.URL.Query().Get("minAmount")
+	maxAmount := r.URL.Query().Get("maxAmount")
+	entryType := strToInt(r.URL.Query().Get("type"))
+	strCursor := r.URL.Query().Get("cursor")
+
+	// Make sure page size is within limit
+	limit := pageLength
+	if strLimit := r.URL.Query().Get("limit"); strLimit != "" {
+		limit = strToInt(strLimit)
+		if limit < 1 || limit > maxPageLength {
+			panic(apierr.Validation("limit must be between 1 and %d", maxPageLength).WithField("limit"))
+		}
+	}
+
+	// Cursor is used instead of page if it's specified
+	var cursor entryCursor
+	if strCursor != "" {
+		var err error
+		cursor, err = decodeEntryCursor(strCursor)
+		if err != nil {
+			panic(apierr.Validation("invalid cursor").WithField("cursor"))
+		}
+	}
 
 	// Start transaction
 	// We only use it to fetch the data,
@@ -25,27 +63,116 @@ func (h *Handler) SelectEntries(w http.ResponseWriter, r *http.Request, ps httpr
 	tx := h.db.MustBegin()
 	defer tx.Rollback()
 
-	// Prepare SQL statement
-	stmtGetAccount, err := tx.Preparex(`SELECT id FROM account WHERE id = ?`)
+	// Prepare filter for entries
+	filter := `(e.account_id = ? OR e.affected_account_id = ?)`
+	filterArgs := []interface{}{accountID, accountID}
+
+	if categoryID != 0 {
+		// Entries in sub categories are included as well,
+		// along with entries that have split in those categories
+		categoryIDs, err := categoryDescendants(tx, int64(categoryID))
+		checkError(err)
+
+		filter += ` AND (e.category_id IN (?) OR e.id IN (
+			SELECT entry_id FROM entry_split WHERE category_id IN (?)))`
+		filterArgs = append(filterArgs, categoryIDs, categoryIDs)
+	}
+
+	if len(tags) > 0 {
+		// By default entry must have all of the specified tags.
+		// If tag mode is "or", entry only need to have one of them.
+		tagFilter := `SELECT et.entry_id FROM entry_tag et
+			JOIN tag t ON t.id = et.tag_id
+			WHERE t.name IN (?)`
+		filterArgs = append(filterArgs, tags)
+
+		if tagMode != "or" {
+			tagFilter += ` GROUP BY et.entry_id HAVING COUNT(*) = ?`
+			filterArgs = append(filterArgs, len(tags))
+		}
+
+		filter += ` AND e.id IN (` + tagFilter + `)`
+	}
+
+	if search != "" {
+		searchFilter, searchArgs := descriptionFilter(search)
+		filter += ` AND ` + searchFilter
+		filterArgs = append(filterArgs, searchArgs...)
+	}
+
+	if dateFrom != "" {
+		if _, err := time.Parse("2006-01-02", dateFrom); err != nil {
+			panic(apierr.Validation("from date must be formatted as YYYY-MM-DD").WithField("from"))
+		}
+
+		filter += ` AND e.date >= ?`
+		filterArgs = append(filterArgs, dateFrom)
+	}
+
+	if dateTo != "" {
+		if _, err := time.Parse("2006-01-02", dateTo); err != nil {
+			panic(apierr.Validation("to date must be formatted as YYYY-MM-DD").WithField("to"))
+		}
+
+		filter += ` AND e.date <= ?`
+		filterArgs = append(filterArgs, dateTo)
+	}
+
+	// For transfer into this account, the amount received by this
+	// account is the one compared. The limit is passed as float since
+	// SQLite compares text and number differently inside CASE.
+	amountExpr := `CASE WHEN e.affected_account_id = ?
+		THEN COALESCE(e.affected_amount, e.amount) ELSE e.amount END`
+
+	if minAmount != "" {
+		amount, err := decimal.NewFromString(minAmount)
+		if err != nil {
+			panic(apierr.Validation("min amount must be a number").WithField("minAmount"))
+		}
+
+		value, _ := amount.Float64()
+		filter += ` AND ` + amountExpr + ` >= ?`
+		filterArgs = append(filterArgs, accountID, value)
+	}
+
+	if maxAmount != "" {
+		amount, err := decimal.NewFromString(maxAmount)
+		if err != nil {
+			panic(apierr.Validation("max amount must be a number").WithField("maxAmount"))
+		}
+
+		value, _ := amount.Float64()
+		filter += ` AND ` + amountExpr + ` <= ?`
+		filterArgs = append(filterArgs, accountID, value)
+	}
+
+	if entryType != 0 {
+		if entryType < 1 || entryType > 3 {
+			panic(apierr.Validation("type must be 1 (income), 2 (expense) or 3 (transfer)").WithField("type"))
+		}
+
+		filter += ` AND e.type = ?`
+		filterArgs = append(filterArgs, entryType)
+	}
+
+	filter, filterArgs, err := sqlx.In(filter, filterArgs...)
 	checkError(err)
 
-	stmtGetEntriesMaxPage, err := tx.Preparex(`
-		SELECT CEIL(COUNT(*) / ?) FROM entry
-		WHERE account_id = ?
-		OR affected_account_id = ?`)
+	// Prepare SQL statement
+	stmtGetAccount, err := tx.Preparex(`SELECT id FROM account WHERE id = ?`)
 	checkError(err)
 
-	stmtSelectEntries, err := tx.Preparex(`
-		SELECT e.id, e.account_id, e.affected_account_id,
-			a1.name account, a2.name affected_account,
-			e.type, e.description, e.amount, e.date
+	// Total is the sum of filtered entries from the view of this
+	// account, i.e. income and received transfer are positive
+	// while expense and sent transfer are negative.
+	stmtCountEntries, err := tx.Preparex(`
+		SELECT COUNT(*) count, COALESCE(SUM(CASE
+			WHEN e.type = 1 THEN e.amount
+			WHEN e.type = 2 THEN -e.amount
+			WHEN e.affected_account_id = ? THEN COALESCE(e.affected_amount, e.amount)
+			ELSE -e.amount END), 0) total
 		FROM entry e
-		LEFT JOIN account a1 ON e.account_id = a1.id
-		LEFT JOIN account a2 ON e.affected_account_id = a2.id
-		WHERE e.account_id = ?
-		OR e.affected_account_id = ?
-		ORDER BY e.date DESC, e.id DESC
-		LIMIT ? OFFSET ?`)
+		WHERE ` + filter)
 	checkError(err)
 
 	// Make sure account exist
@@ -54,37 +181,149 @@ func (h *Handler) SelectEntries(w http.ResponseWriter, r *http.Request, ps httpr
 	checkError(err)
 
 	if err == sql.ErrNoRows {
-		panic(fmt.Errorf("account doesn't exist"))
+		panic(apierr.NotFound("account doesn't exist"))
 	}
 
-	// Get entry count and calculate max page
-	var maxPage int
-	err = stmtGetEntriesMaxPage.Get(&maxPage, pageLength,
-		accountID, accountID)
-	checkError(err)
+	// Fetch entries from database
+	selectQuery := `
+		SELECT e.id, e.account_id, e.affected_account_id, e.category_id,
+			a1.name account, a2.name affected_account, c.name category,
+			e.type, e.description, e.amount, e.affected_amount, e.date,
+			e.recurring_id, e.import_id
+		FROM entry e
+		LEFT JOIN account a1 ON e.account_id = a1.id
+		LEFT JOIN account a2 ON e.affected_account_id = a2.id
+		LEFT JOIN category c ON e.category_id = c.id
+		WHERE ` + filter
 
-	if page == 0 {
-		page = 1
-	} else if page > maxPage {
-		page = maxPage
+	entries := []model.Entry{}
+	selectArgs := append([]interface{}{}, filterArgs...)
+	result := map[string]interface{}{}
+
+	var next, prev null.String
+	nextCursor := func(entry model.Entry) null.String {
+		return null.StringFrom(encodeEntryCursor(entryCursor{
+			Date: entry.Date, ID: entry.ID}))
+	}
+	prevCursor := func(entry model.Entry) null.String {
+		return null.StringFrom(encodeEntryCursor(entryCursor{
+			Date: entry.Date, ID: entry.ID, Backward: true}))
 	}
 
-	offset := (page - 1) * pageLength
+	if strCursor == "" {
+		// Without cursor, use page like before. Entry count
+		// and total are needed to calculate max page.
+		var summary struct {
+			Count int             `db:"count"`
+			Total decimal.Decimal `db:"total"`
+		}
 
-	// Fetch entries from database
-	entries := []model.Entry{}
-	err = stmtSelectEntries.Select(&entries,
-		accountID, accountID,
-		pageLength, offset)
-	checkError(err)
+		countArgs := append([]interface{}{accountID}, filterArgs...)
+		err = stmtCountEntries.Get(&summary, countArgs...)
+		checkError(err)
+
+		maxPage := int(math.Ceil(float64(summary.Count) / float64(limit)))
+		if page > maxPage {
+			page = maxPage
+		}
+
+		if page < 1 {
+			page = 1
+		}
+
+		nBefore := (page - 1) * limit
+		selectArgs = append(selectArgs, limit, nBefore)
+		err = tx.Select(&entries, selectQuery+`
+			ORDER BY e.date DESC, e.id DESC
+			LIMIT ? OFFSET ?`, selectArgs...)
+		checkError(err)
+
+		if n := len(entries); n > 0 {
+			if nBefore+n < summary.Count {
+				next = nextCursor(entries[n-1])
+			}
+
+			if nBefore > 0 {
+				prev = prevCursor(entries[0])
+			}
+		}
+
+		result["page"] = page
+		result["maxPage"] = maxPage
+		result["total"] = summary.Total
+	} else {
+		// With cursor, the filtered entries are not counted since it
+		// needs to scan all of them. Instead, one more entry is fetched
+		// to know whether there are more entries after this page.
+		newer := `(e.date > ? OR (e.date = ? AND e.id > ?))`
+		older := `(e.date < ? OR (e.date = ? AND e.id < ?))`
+
+		// For previous page, fetch entries that newer than
+		// cursor in ascending order, then reverse them
+		condition, order := older, `e.date DESC, e.id DESC`
+		if cursor.Backward {
+			condition, order = newer, `e.date, e.id`
+		}
+
+		selectArgs = append(selectArgs, cursor.Date, cursor.Date, cursor.ID, limit+1)
+		err = tx.Select(&entries, selectQuery+` AND `+condition+`
+			ORDER BY `+order+` LIMIT ?`, selectArgs...)
+		checkError(err)
+
+		hasMore := len(entries) > limit
+		if hasMore {
+			entries = entries[:limit]
+		}
+
+		if cursor.Backward {
+			for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+				entries[i], entries[j] = entries[j], entries[i]
+			}
+		}
 
-	// Return final result
-	result := map[string]interface{}{
-		"page":    page,
-		"maxPage": maxPage,
-		"entries": entries,
+		// For the other direction, it's enough to check
+		// whether there is an entry after the page
+		entryExists := func(condition string, entry model.Entry) bool {
+			args := append([]interface{}{}, filterArgs...)
+			args = append(args, entry.Date, entry.Date, entry.ID)
+
+			var id int64
+			err := tx.Get(&id, `SELECT e.id FROM entry e
+				WHERE `+filter+` AND `+condition+` LIMIT 1`, args...)
+			checkError(err)
+			return err == nil
+		}
+
+		if n := len(entries); n > 0 {
+			hasNext, hasPrev := hasMore, hasMore
+			if cursor.Backward {
+				hasNext = entryExists(older, entries[n-1])
+			} else {
+				hasPrev = entryExists(newer, entries[0])
+			}
+
+			if hasNext {
+				next = nextCursor(entries[n-1])
+			}
+
+			if hasPrev {
+				prev = prevCursor(entries[0])
+			}
+		}
 	}
 
+	err = fetchEntriesTags(tx, entries)
+	checkError(err)
+
+	err = fetchEntriesSplits(tx, entries)
+	checkError(err)
+
+	// Return final result. Page, max page and total are
+	// only returned when cursor is not used.
+	result["next"] = next
+	result["prev"] = prev
+	result["entries"] = entries
+
 	w.Header().Add("Content-Encoding", "gzip")
 	w.Header().Add("Content-Type", "application/json")
 	err = encodeGzippedJSON(w, &result)
@@ -113,35 +352,33 @@ func (h *Handler) InsertEntry(w http.ResponseWriter, r *http.Request, ps httprou
 	}()
 
 	// Prepare statements
-	stmtInsertEntry, err := tx.Preparex(`INSERT INTO entry 
-		(account_id, affected_account_id, type, description, amount, date)
-		VALUES (?, ?, ?, ?, ?, ?)`)
-	checkError(err)
-
 	stmtGetEntry, err := tx.Preparex(`
-		SELECT e.id, e.account_id, e.affected_account_id,
-			a1.name account, a2.name affected_account,
-			e.type, e.description, e.amount, e.date
+		SELECT e.id, e.account_id, e.affected_account_id, e.category_id,
+			a1.name account, a2.name affected_account, c.name category,
+			e.type, e.description, e.amount, e.affected_amount, e.date,
+			e.recurring_id, e.import_id
 		FROM entry e
 		LEFT JOIN account a1 ON e.account_id = a1.id
 		LEFT JOIN account a2 ON e.affected_account_id = a2.id
+		LEFT JOIN category c ON e.category_id = c.id
 		WHERE e.id = ?`)
 	checkError(err)
 
 	// Save to database
-	res := stmtInsertEntry.MustExec(
-		entry.AccountID,
-		entry.AffectedAccountID,
-		entry.Type,
-		entry.Description,
-		entry.Amount,
-		entry.Date)
-	entry.ID, _ = res.LastInsertId()
+	h.insertEntry(tx, &entry)
 
 	// Fetch the inserted data
 	err = stmtGetEntry.Get(&entry, entry.ID)
 	checkError(err)
 
+	entries := []model.Entry{entry}
+	err = fetchEntriesTags(tx, entries)
+	checkError(err)
+
+	err = fetchEntriesSplits(tx, entries)
+	checkError(err)
+	entry = entries[0]
+
 	// Commit transaction
 	err = tx.Commit()
 	checkError(err)
@@ -175,30 +412,81 @@ func (h *Handler) UpdateEntry(w http.ResponseWriter, r *http.Request, ps httprou
 	}()
 
 	// Prepare statements
+	stmtGetOldEntry, err := tx.Preparex(`
+		SELECT account_id, affected_account_id, type, amount, affected_amount
+		FROM entry WHERE id = ?`)
+	checkError(err)
+
 	stmtUpdateEntry, err := tx.Preparex(`UPDATE entry 
-		SET affected_account_id = ?, description = ?, amount = ?, date = ?
+		SET affected_account_id = ?, category_id = ?, description = ?,
+		amount = ?, affected_amount = ?, date = ?
 		WHERE id = ?`)
 	checkError(err)
 
 	stmtGetEntry, err := tx.Preparex(`
-		SELECT e.id, e.account_id, e.affected_account_id,
-			a1.name account, a2.name affected_account,
-			e.type, e.description, e.amount, e.date
+		SELECT e.id, e.account_id, e.affected_account_id, e.category_id,
+			a1.name account, a2.name affected_account, c.name category,
+			e.type, e.description, e.amount, e.affected_amount, e.date,
+			e.recurring_id, e.import_id
 		FROM entry e
 		LEFT JOIN account a1 ON e.account_id = a1.id
 		LEFT JOIN account a2 ON e.affected_account_id = a2.id
+		LEFT JOIN category c ON e.category_id = c.id
 		WHERE e.id = ?`)
 	checkError(err)
 
+	// Account and type can't be changed, so use the old one.
+	// For transfer, if the received amount is not specified and
+	// the transfer is not changed, keep the old received amount.
+	var oldEntry model.Entry
+	err = stmtGetOldEntry.Get(&oldEntry, entry.ID)
+	checkError(err)
+
+	if err == sql.ErrNoRows {
+		panic(apierr.NotFound("entry doesn't exist"))
+	}
+
+	entry.AccountID = oldEntry.AccountID
+	entry.Type = oldEntry.Type
+	if !entry.AffectedAmount.Valid &&
+		entry.AffectedAccountID == oldEntry.AffectedAccountID &&
+		entry.Amount.Equal(oldEntry.Amount) {
+		entry.AffectedAmount = oldEntry.AffectedAmount
+	}
+
 	// Update database
+	mustValidEntry(entry)
+	h.prepareEntryAmounts(tx, &entry)
 	stmtUpdateEntry.MustExec(
-		entry.AffectedAccountID, entry.Description,
-		entry.Amount, entry.Date, entry.ID)
+		entry.AffectedAccountID, entry.CategoryID, entry.Description,
+		entry.Amount, entry.AffectedAmount, entry.Date, entry.ID)
+
+	// If tags is not specified, keep the old tags
+	if entry.Tags != nil {
+		err = saveEntryTags(tx, entry.ID, entry.Tags)
+		checkError(err)
+	}
+
+	// Same with splits. However, the old splits still
+	// must match with the new amount.
+	if entry.Splits != nil {
+		err = saveEntrySplits(tx, entry.ID, entry.Splits)
+		checkError(err)
+	}
+	mustValidSplits(tx, entry.ID)
 
 	// Fetch the updated data
 	err = stmtGetEntry.Get(&entry, entry.ID)
 	checkError(err)
 
+	entries := []model.Entry{entry}
+	err = fetchEntriesTags(tx, entries)
+	checkError(err)
+
+	err = fetchEntriesSplits(tx, entries)
+	checkError(err)
+	entry = entries[0]
+
 	// Commit transaction
 	err = tx.Commit()
 	checkError(err)
@@ -239,7 +527,141 @@ func (h *Handler) DeleteEntries(w http.ResponseWriter, r *http.Request, ps httpr
 		stmt.MustExec(id)
 	}
 
+	err = removeUnusedTags(tx)
+	checkError(err)
+
 	// Commit transaction
 	err = tx.Commit()
 	checkError(err)
 }
+
+// prepareEntryAmounts rounds amounts of the entry following currency of its
+// account. For transfer, if the amount received by the affected account is
+// not specified, it will be converted using exchange rate at entry date.
+func (h *Handler) prepareEntryAmounts(tx *sqlx.Tx, entry *model.Entry) {
+	currency := h.accountCurrency(tx, entry.AccountID)
+	entry.Amount = roundCurrency(entry.Amount, currency)
+	for i := range entry.Splits {
+		entry.Splits[i].Amount = roundCurrency(entry.Splits[i].Amount, currency)
+	}
+
+	if entry.Type != 3 || !entry.AffectedAccountID.Valid {
+		entry.AffectedAmount = decimal.NullDecimal{}
+		return
+	}
+
+	affectedCurrency := h.accountCurrency(tx, entry.AffectedAccountID.Int64)
+	if affectedCurrency == currency {
+		entry.AffectedAmount = decimal.NullDecimal{Decimal: entry.Amount, Valid: true}
+		return
+	}
+
+	if !entry.AffectedAmount.Valid {
+		rates, err := loadExchangeRates(tx)
+		checkError(err)
+
+		amount, err := rates.convert(entry.Amount, currency, affectedCurrency, entry.Date)
+		if err != nil {
+			panic(apierr.Validation("affected amount must be specified: %v", err).WithField("affectedAmount"))
+		}
+
+		entry.AffectedAmount = decimal.NullDecimal{Decimal: amount, Valid: true}
+	}
+
+	entry.AffectedAmount.Decimal = roundCurrency(entry.AffectedAmount.Decimal, affectedCurrency)
+}
+
+// insertEntry saves a new entry along with its tags and splits. Every
+// entry should be saved using this, so they are processed the same way.
+func (h *Handler) insertEntry(tx *sqlx.Tx, entry *model.Entry) {
+	mustValidEntry(*entry)
+	h.prepareEntryAmounts(tx, entry)
+
+	res := tx.MustExec(`INSERT INTO entry
+		(account_id, affected_account_id, category_id, type, description,
+		amount, affected_amount, date, recurring_id, import_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.AccountID,
+		entry.AffectedAccountID,
+		entry.CategoryID,
+		entry.Type,
+		entry.Description,
+		entry.Amount,
+		entry.AffectedAmount,
+		entry.Date,
+		entry.RecurringID,
+		entry.ImportID)
+	entry.ID, _ = res.LastInsertId()
+
+	err := saveEntryTags(tx, entry.ID, entry.Tags)
+	checkError(err)
+
+	err = saveEntrySplits(tx, entry.ID, entry.Splits)
+	checkError(err)
+	mustValidSplits(tx, entry.ID)
+}
+
+// descriptionFilter returns SQL filter for entries whose description contains
+// every word in the search text. The comparison is case insensitive.
+func descriptionFilter(search string) (string, []interface{}) {
+	// Escape wildcard characters, so they are matched literally
+	replacer := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+	filters := []string{}
+	args := []interface{}{}
+	for _, word := range strings.Fields(strings.ToLower(search)) {
+		filters = append(filters, `LOWER(e.description) LIKE ? ESCAPE '!'`)
+		args = append(args, "%"+replacer.Replace(word)+"%")
+	}
+
+	return `(` + strings.Join(filters, ` AND `) + `)`, args
+}
+
+// entryCursor points to an entry in the list of entries. Since entries are
+// sorted by date and ID, the next page contains entries after the cursor,
+// while the previous page contains entries before it.
+type entryCursor struct {
+	Date     string
+	ID       int64
+	Backward bool
+}
+
+// encodeEntryCursor encodes cursor into opaque string, so client
+// doesn't depend on its content.
+func encodeEntryCursor(cursor entryCursor) string {
+	direction := "n"
+	if cursor.Backward {
+		direction = "p"
+	}
+
+	str := fmt.Sprintf("%s|%s|%d", direction, cursor.Date, cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(str))
+}
+
+// decodeEntryCursor decodes cursor that encoded by encodeEntryCursor.
+func decodeEntryCursor(str string) (entryCursor, error) {
+	bt, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return entryCursor{}, err
+	}
+
+	parts := strings.Split(string(bt), "|")
+	if len(parts) != 3 || (parts[0] != "n" && parts[0] != "p") {
+		return entryCursor{}, fmt.Errorf("malformed cursor")
+	}
+
+	if _, err = time.Parse("2006-01-02", parts[1]); err != nil {
+		return entryCursor{}, err
+	}
+
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return entryCursor{}, err
+	}
+
+	return entryCursor{
+		Date:     parts[1],
+		ID:       id,
+		Backward: parts[0] == "p",
+	}, nil
+}
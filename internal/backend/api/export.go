@@ -0,0 +1,172 @@
+package api
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/exporter"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Export is handler for GET /api/export.
+// Format is specified in URL parameter, i.e. csv, json, ledger or beancount.
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Get URL parameter
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = exporter.FormatJSON
+	case exporter.FormatCSV, exporter.FormatJSON,
+		exporter.FormatLedger, exporter.FormatBeancount:
+	default:
+		panic(apierr.Validation("format must be csv, json, ledger or beancount").WithField("format"))
+	}
+
+	// Fetch from database
+	data, err := h.exportData()
+	checkError(err)
+
+	// Return exported data as downloadable file
+	fileName := fmt.Sprintf("duit-%s.%s", time.Now().Format("2006-01-02"), format)
+
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", exporter.ContentType(format))
+	w.Header().Add("Content-Disposition", `attachment; filename="`+fileName+`"`)
+
+	gz := gzip.NewWriter(w)
+	err = exporter.Write(gz, format, data)
+	checkError(err)
+
+	err = gz.Close()
+	checkError(err)
+}
+
+// WriteExport writes all accounts and entries into w using the specified format.
+func (h *Handler) WriteExport(w io.Writer, format string) error {
+	data, err := h.exportData()
+	if err != nil {
+		return err
+	}
+
+	return exporter.Write(w, format, data)
+}
+
+// exportData fetches all accounts, categories and entries. They are fetched
+// in one transaction, so the exported data will be consistent.
+func (h *Handler) exportData() (exporter.Data, error) {
+	// Start transaction
+	// We only use it to fetch the data,
+	// so just rollback it later
+	tx, err := h.db.Beginx()
+	if err != nil {
+		return exporter.Data{}, err
+	}
+	defer tx.Rollback()
+
+	// Fetch from database
+	data := exporter.Data{
+		BaseCurrency: h.baseCurrency,
+		Accounts:     []model.Account{},
+		Categories:   []model.Category{},
+		Entries:      []model.Entry{},
+	}
+
+	err = tx.Select(&data.Accounts, `
+		SELECT id, name, COALESCE(currency, ?) currency, initial_amount, total
+		FROM account_total
+		ORDER BY id`, h.baseCurrency)
+	if err != nil {
+		return exporter.Data{}, err
+	}
+
+	err = tx.Select(&data.Categories,
+		`SELECT id, parent_id, name FROM category ORDER BY id`)
+	if err != nil {
+		return exporter.Data{}, err
+	}
+
+	err = tx.Select(&data.Entries, `
+		SELECT e.id, e.account_id, e.affected_account_id, e.category_id,
+			a1.name account, a2.name affected_account, c.name category,
+			e.type, e.description, e.amount, e.affected_amount, e.date,
+			e.recurring_id, e.import_id
+		FROM entry e
+		LEFT JOIN account a1 ON e.account_id = a1.id
+		LEFT JOIN account a2 ON e.affected_account_id = a2.id
+		LEFT JOIN category c ON e.category_id = c.id
+		ORDER BY e.date, e.id`)
+	if err != nil {
+		return exporter.Data{}, err
+	}
+
+	for i, account := range data.Accounts {
+		data.Accounts[i].Total = roundCurrency(account.Total, account.Currency)
+	}
+
+	// Fill tags and splits. Since all entries are exported, here we
+	// fetch the whole table instead of filtering it by entry ID.
+	err = fetchAllEntriesDetails(tx, data.Entries)
+	if err != nil {
+		return exporter.Data{}, err
+	}
+
+	return data, nil
+}
+
+// fetchAllEntriesDetails fills tags and splits for all of the entries.
+func fetchAllEntriesDetails(tx *sqlx.Tx, entries []model.Entry) error {
+	entryIdx := make(map[int64]int)
+	for i, entry := range entries {
+		entryIdx[entry.ID] = i
+		entries[i].Tags = []string{}
+		entries[i].Splits = []model.EntrySplit{}
+	}
+
+	entryTags := []struct {
+		EntryID int64  `db:"entry_id"`
+		Name    string `db:"name"`
+	}{}
+
+	err := tx.Select(&entryTags, `
+		SELECT et.entry_id, t.name
+		FROM entry_tag et
+		JOIN tag t ON t.id = et.tag_id
+		ORDER BY t.name`)
+	if err != nil {
+		return err
+	}
+
+	for _, et := range entryTags {
+		if idx, exist := entryIdx[et.EntryID]; exist {
+			entries[idx].Tags = append(entries[idx].Tags, et.Name)
+		}
+	}
+
+	splits := []model.EntrySplit{}
+	err = tx.Select(&splits, `
+		SELECT s.id, s.entry_id, s.category_id, s.description, s.amount,
+			c.name category
+		FROM entry_split s
+		LEFT JOIN category c ON s.category_id = c.id
+		ORDER BY s.id`)
+	if err != nil {
+		return err
+	}
+
+	for _, split := range splits {
+		if idx, exist := entryIdx[split.EntryID]; exist {
+			entries[idx].Splits = append(entries[idx].Splits, split)
+		}
+	}
+
+	return nil
+}
@@ -1,23 +1,45 @@
 package api
 
 import (
+	"fmt"
+
 	"github.com/RadhiFadlillah/duit/internal/backend/auth"
+	"github.com/RadhiFadlillah/duit/internal/model"
 	"github.com/jmoiron/sqlx"
 )
 
-const pageLength = 250
+// pageLength is the default number of items in a page, while
+// maxPageLength is the maximum that can be requested by client.
+const (
+	pageLength    = 250
+	maxPageLength = 1000
+)
 
 // Handler represents handler for every API routes.
 type Handler struct {
-	db   *sqlx.DB
-	auth *auth.Authenticator
+	db           *sqlx.DB
+	auth         *auth.Authenticator
+	baseCurrency string
+	backupDir    string
 }
 
 // NewHandler returns new Handler
-func NewHandler(db *sqlx.DB, auth *auth.Authenticator) (*Handler, error) {
+func NewHandler(db *sqlx.DB, auth *auth.Authenticator, config model.Config) (*Handler, error) {
+	// Make sure base currency is valid
+	baseCurrency := defaultCurrency
+	if config.BaseCurrency != "" {
+		var err error
+		baseCurrency, err = normalizeCurrency(config.BaseCurrency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base currency: %w", err)
+		}
+	}
+
 	// Create handler
 	handler := new(Handler)
 	handler.db = db
 	handler.auth = auth
+	handler.baseCurrency = baseCurrency
+	handler.backupDir = config.BackupDir
 	return handler, nil
 }
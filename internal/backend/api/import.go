@@ -0,0 +1,620 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/importer"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/julienschmidt/httprouter"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v3"
+)
+
+// maxImportSize is the maximum size of imported file.
+const maxImportSize = 10 << 20
+
+// Sign conventions for amount in imported CSV.
+const (
+	signNegativeExpense = "negative-expense"
+	signPositiveExpense = "positive-expense"
+	signDebitCredit     = "debit-credit"
+)
+
+// ImportCSV is handler for POST /api/import/csv.
+// The request is a multipart form with following fields :
+// - file: the CSV file.
+// - mapping: JSON of CSV mapping, not required if profile is specified.
+// - profile: ID of the saved import profile.
+// - account: ID of the destination account, not required if profile has it.
+// - preview: if "true", the parsed rows are returned without being saved.
+func (h *Handler) ImportCSV(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Parse form
+	err := r.ParseMultipartForm(maxImportSize)
+	checkError(err)
+
+	file, _, err := r.FormFile("file")
+	checkError(err)
+	defer file.Close()
+
+	// Load the profile if needed
+	var profile model.ImportProfile
+	if strProfile := r.FormValue("profile"); strProfile != "" {
+		err = h.db.Get(&profile, `SELECT id, name, account_id, mapping
+			FROM import_profile WHERE id = ?`, strToInt(strProfile))
+		checkError(err)
+
+		if err == sql.ErrNoRows {
+			panic(apierr.NotFound("import profile doesn't exist"))
+		}
+	}
+
+	if strMapping := r.FormValue("mapping"); strMapping != "" {
+		err = json.Unmarshal([]byte(strMapping), &profile.Mapping)
+		checkError(err)
+	} else if profile.ID == 0 {
+		panic(apierr.Validation("mapping or profile must be specified").WithField("mapping"))
+	}
+
+	if strAccount := r.FormValue("account"); strAccount != "" {
+		profile.AccountID = null.IntFrom(int64(strToInt(strAccount)))
+	}
+
+	// Parse the file
+	rows, err := parseCSV(file, profile.Mapping)
+	checkFileError(err)
+	validateImportRows(rows)
+
+	// Save the entries, unless it's only preview. Transaction is started
+	// after the file parsed, so database is not locked while parsing it.
+	if r.FormValue("preview") != "true" {
+		if !profile.AccountID.Valid {
+			panic(apierr.Validation("account must be specified").WithField("account"))
+		}
+
+		// Make sure to rollback if panic ever happened
+		tx := h.db.MustBegin()
+
+		defer func() {
+			if r := recover(); r != nil {
+				tx.Rollback()
+				panic(r)
+			}
+		}()
+
+		h.importRows(tx, profile.AccountID.Int64, rows)
+
+		err = tx.Commit()
+		checkError(err)
+	}
+
+	// Return the rows
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &rows)
+	checkError(err)
+}
+
+// SelectImportProfiles is handler for GET /api/import/profiles
+func (h *Handler) SelectImportProfiles(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Fetch from database
+	profiles := []model.ImportProfile{}
+	err := h.db.Select(&profiles, `SELECT id, name, account_id, mapping
+		FROM import_profile ORDER BY name`)
+	checkError(err)
+
+	// Return list of profiles
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &profiles)
+	checkError(err)
+}
+
+// InsertImportProfile is handler for POST /api/import/profile
+func (h *Handler) InsertImportProfile(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var profile model.ImportProfile
+	err := json.NewDecoder(r.Body).Decode(&profile)
+	checkError(err)
+
+	// Validate input
+	mustValidImportProfile(profile)
+
+	// Save to database
+	res := h.db.MustExec(`INSERT INTO import_profile
+		(name, account_id, mapping) VALUES (?, ?, ?)`,
+		profile.Name, profile.AccountID, profile.Mapping)
+	profile.ID, _ = res.LastInsertId()
+
+	// Return inserted profile
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &profile)
+	checkError(err)
+}
+
+// UpdateImportProfile is handler for PUT /api/import/profile
+func (h *Handler) UpdateImportProfile(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var profile model.ImportProfile
+	err := json.NewDecoder(r.Body).Decode(&profile)
+	checkError(err)
+
+	// Validate input
+	mustValidImportProfile(profile)
+
+	// Update database
+	h.db.MustExec(`UPDATE import_profile
+		SET name = ?, account_id = ?, mapping = ? WHERE id = ?`,
+		profile.Name, profile.AccountID, profile.Mapping, profile.ID)
+
+	// Return updated profile
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &profile)
+	checkError(err)
+}
+
+// DeleteImportProfiles is handler for DELETE /api/import/profiles
+func (h *Handler) DeleteImportProfiles(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var ids []int64
+	err := json.NewDecoder(r.Body).Decode(&ids)
+	checkError(err)
+
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Delete from database
+	stmt, err := tx.Preparex(`DELETE FROM import_profile WHERE id = ?`)
+	checkError(err)
+
+	for _, id := range ids {
+		stmt.MustExec(id)
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	checkError(err)
+}
+
+// ImportOFX is handler for POST /api/import/ofx.
+// The request is a multipart form with following fields :
+// - file: the OFX or QFX file.
+// - account: ID of the destination account.
+// - preview: if "true", the parsed rows are returned without being saved.
+func (h *Handler) ImportOFX(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.serveStatementImport(w, r, func(f io.Reader) ([]importer.Transaction, error) {
+		return importer.ParseOFX(f)
+	})
+}
+
+// ImportQIF is handler for POST /api/import/qif.
+// The request is a multipart form with following fields :
+// - file: the QIF file.
+// - account: ID of the destination account.
+// - dateFormat: date format in the file, e.g. DD/MM/YYYY. Optional.
+// - decimalSeparator: either . or , which used in amounts. Optional.
+// - preview: if "true", the parsed rows are returned without being saved.
+func (h *Handler) ImportQIF(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.serveStatementImport(w, r, func(f io.Reader) ([]importer.Transaction, error) {
+		layout := ""
+		if dateFormat := r.FormValue("dateFormat"); dateFormat != "" {
+			layout = importer.DateLayout(dateFormat)
+		}
+
+		return importer.ParseQIF(f, layout, r.FormValue("decimalSeparator"))
+	})
+}
+
+// serveStatementImport imports the statement file using the specified
+// parser. Transactions that already imported before are skipped.
+func (h *Handler) serveStatementImport(w http.ResponseWriter, r *http.Request,
+	parse func(io.Reader) ([]importer.Transaction, error)) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Parse form
+	err := r.ParseMultipartForm(maxImportSize)
+	checkError(err)
+
+	file, _, err := r.FormFile("file")
+	checkError(err)
+	defer file.Close()
+
+	accountID := int64(strToInt(r.FormValue("account")))
+	if accountID == 0 {
+		panic(apierr.Validation("account must be specified").WithField("account"))
+	}
+
+	// Parse the file
+	transactions, err := parse(file)
+	checkFileError(err)
+	rows := importer.Rows(transactions)
+	validateImportRows(rows)
+
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Save the entries, unless it's only preview
+	if r.FormValue("preview") == "true" {
+		markDuplicateRows(tx, accountID, rows)
+	} else {
+		h.importRows(tx, accountID, rows)
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	checkError(err)
+
+	// Return the rows
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &rows)
+	checkError(err)
+}
+
+// ImportEntries saves the imported rows into an account in one transaction.
+// It returns the number of saved entries, which might be less than number
+// of rows since the rows that already imported before are skipped.
+func (h *Handler) ImportEntries(accountID int64, rows []model.ImportRow) (n int, err error) {
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			n, err = 0, fmt.Errorf("%v", r)
+		}
+	}()
+
+	h.importRows(tx, accountID, rows)
+
+	err = tx.Commit()
+	checkError(err)
+
+	for _, row := range rows {
+		if !row.Duplicate {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// importRows saves the imported rows into the account. If there are any
+// row that can't be parsed or not valid, none of the rows will be saved.
+// Rows that already imported before are marked as duplicate and skipped.
+func (h *Handler) importRows(tx *sqlx.Tx, accountID int64, rows []model.ImportRow) {
+	validateImportRows(rows)
+	for _, row := range rows {
+		if row.Error != "" {
+			panic(apierr.Validation("line %d: %s", row.Line, row.Error))
+		}
+	}
+
+	markDuplicateRows(tx, accountID, rows)
+	for i := range rows {
+		if rows[i].Duplicate {
+			continue
+		}
+
+		rows[i].Entry.AccountID = accountID
+		h.insertEntry(tx, &rows[i].Entry)
+	}
+}
+
+// markDuplicateRows marks rows whose import ID already used in the account,
+// or already used by the previous rows since some banks repeat the same
+// transaction in their statement file.
+func markDuplicateRows(tx *sqlx.Tx, accountID int64, rows []model.ImportRow) {
+	stmt, err := tx.Preparex(`SELECT id FROM entry
+		WHERE account_id = ? AND import_id = ?`)
+	checkError(err)
+
+	seen := make(map[string]struct{})
+	for i, row := range rows {
+		if !row.Entry.ImportID.Valid {
+			continue
+		}
+
+		importID := row.Entry.ImportID.String
+		if _, exist := seen[importID]; exist {
+			rows[i].Duplicate = true
+			continue
+		}
+		seen[importID] = struct{}{}
+
+		var tmpID int64
+		err = stmt.Get(&tmpID, accountID, importID)
+		checkError(err)
+		rows[i].Duplicate = err == nil
+	}
+}
+
+// mustValidImportProfile panics if the import profile is not valid.
+func mustValidImportProfile(profile model.ImportProfile) {
+	if profile.Name == "" {
+		panic(apierr.Validation("name must not empty").WithField("name"))
+	}
+
+	err := validateCSVMapping(profile.Mapping)
+	checkError(err)
+}
+
+// validateCSVMapping checks whether the mapping usable to parse CSV file.
+func validateCSVMapping(m model.CSVMapping) error {
+	if utf8.RuneCountInString(m.Delimiter) > 1 {
+		return apierr.Validation("delimiter must be a single character")
+	}
+
+	switch m.DecimalSeparator {
+	case "", ".", ",":
+	default:
+		return apierr.Validation("decimal separator must be either . or ,")
+	}
+
+	switch m.SignConvention {
+	case "", signNegativeExpense, signPositiveExpense:
+		if !m.AmountColumn.Valid {
+			return apierr.Validation("amount column must be specified")
+		}
+	case signDebitCredit:
+		if !m.DebitColumn.Valid || !m.CreditColumn.Valid {
+			return apierr.Validation("debit and credit column must be specified")
+		}
+	default:
+		return apierr.Validation("sign convention %s is not valid", m.SignConvention)
+	}
+
+	return nil
+}
+
+// parseCSV reads entries from CSV file using the specified mapping.
+// Empty records are skipped, while records that can't be parsed are
+// returned with their error. Line is the number of CSV record.
+func parseCSV(r io.Reader, m model.CSVMapping) ([]model.ImportRow, error) {
+	if err := validateCSVMapping(m); err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if m.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(m.Delimiter)
+	}
+
+	dateLayout := importer.DateLayout(m.DateFormat)
+	rows := []model.ImportRow{}
+
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if line <= m.SkipRows || isEmptyRecord(record) {
+			continue
+		}
+
+		row := model.ImportRow{Line: line}
+		row.Entry, err = parseCSVRecord(record, m, dateLayout)
+		if err != nil {
+			row.Error = err.Error()
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseCSVRecord converts a CSV record into entry.
+func parseCSVRecord(record []string, m model.CSVMapping, dateLayout string) (model.Entry, error) {
+	column := func(idx int64) (string, error) {
+		if idx < 0 || int(idx) >= len(record) {
+			return "", fmt.Errorf("column %d doesn't exist", idx)
+		}
+
+		return strings.TrimSpace(record[idx]), nil
+	}
+
+	// Parse date
+	var entry model.Entry
+	strDate, err := column(int64(m.DateColumn))
+	if err != nil {
+		return entry, err
+	}
+
+	date, err := time.Parse(dateLayout, strDate)
+	if err != nil {
+		return entry, fmt.Errorf("invalid date %q", strDate)
+	}
+
+	entry.Date = date.Format("2006-01-02")
+
+	// Parse description
+	if m.DescriptionColumn.Valid {
+		description, err := column(m.DescriptionColumn.Int64)
+		if err != nil {
+			return entry, err
+		}
+
+		entry.Description = null.NewString(description, description != "")
+	}
+
+	// Parse amount. Positive amount is income,
+	// unless it's specified the other way around.
+	var amount decimal.Decimal
+	switch m.SignConvention {
+	case signDebitCredit:
+		strDebit, err := column(m.DebitColumn.Int64)
+		if err != nil {
+			return entry, err
+		}
+
+		strCredit, err := column(m.CreditColumn.Int64)
+		if err != nil {
+			return entry, err
+		}
+
+		debit, err := parseAmount(strDebit, m.DecimalSeparator)
+		if err != nil {
+			return entry, err
+		}
+
+		credit, err := parseAmount(strCredit, m.DecimalSeparator)
+		if err != nil {
+			return entry, err
+		}
+
+		amount = credit.Abs().Sub(debit.Abs())
+
+	default:
+		strAmount, err := column(m.AmountColumn.Int64)
+		if err != nil {
+			return entry, err
+		}
+
+		amount, err = parseAmount(strAmount, m.DecimalSeparator)
+		if err != nil {
+			return entry, err
+		}
+
+		if m.SignConvention == signPositiveExpense {
+			amount = amount.Neg()
+		}
+	}
+
+	if amount.IsZero() {
+		return entry, fmt.Errorf("amount must not zero")
+	}
+
+	entry.Type = 1
+	if amount.IsNegative() {
+		entry.Type = 2
+	}
+
+	entry.Amount = amount.Abs()
+	return entry, nil
+}
+
+// parseAmount parses number that might contain thousands separator
+// or negative sign using parentheses, e.g. "(1.234,50)". Empty string
+// will be parsed as zero.
+func parseAmount(s string, decimalSeparator string) (decimal.Decimal, error) {
+	str := strings.TrimSpace(s)
+	if str == "" {
+		return decimal.Zero, nil
+	}
+
+	negative := false
+	if strings.HasPrefix(str, "(") && strings.HasSuffix(str, ")") {
+		negative = true
+		str = str[1 : len(str)-1]
+	}
+
+	if strings.HasSuffix(str, "-") {
+		negative = true
+		str = strings.TrimSuffix(str, "-")
+	}
+
+	// Remove thousands separator
+	thousandsSeparator := ","
+	if decimalSeparator == "," {
+		thousandsSeparator = "."
+	}
+
+	str = strings.NewReplacer(
+		thousandsSeparator, "",
+		" ", "",
+		"\u00a0", "",
+		"'", "",
+	).Replace(str)
+
+	if decimalSeparator == "," {
+		str = strings.Replace(str, ",", ".", 1)
+	}
+
+	amount, err := decimal.NewFromString(str)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid amount %q", s)
+	}
+
+	if negative {
+		amount = amount.Neg()
+	}
+
+	return amount, nil
+}
+
+// isEmptyRecord checks whether all fields in CSV record are empty.
+func isEmptyRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkFileError panics if the uploaded file can't be parsed. Since it's
+// caused by content of the file, it's returned as validation error.
+func checkFileError(err error) {
+	if err == nil {
+		return
+	}
+
+	if _, isAPIError := err.(*apierr.Error); isAPIError {
+		panic(err)
+	}
+
+	panic(apierr.Validation("%v", err).WithField("file"))
+}
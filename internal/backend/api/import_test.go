@@ -0,0 +1,242 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RadhiFadlillah/duit/internal/database"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input            string
+		decimalSeparator string
+		expected         string
+		invalid          bool
+	}{
+		{"", "", "0", false},
+		{"1234.5", "", "1234.5", false},
+		{"1,234.50", ".", "1234.5", false},
+		{"1.234,50", ",", "1234.5", false},
+		{"-25", "", "-25", false},
+		{"(1,234.50)", "", "-1234.5", false},
+		{"100-", "", "-100", false},
+		{"1 234,5", ",", "1234.5", false},
+		{"1'234.5", "", "1234.5", false},
+		{" 1 000", "", "1000", false},
+		{"abc", "", "", true},
+		{"1,2,3", ",", "", true},
+	}
+
+	for _, test := range tests {
+		amount, err := parseAmount(test.input, test.decimalSeparator)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("%q: expected error, got %s", test.input, amount)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", test.input, err)
+			continue
+		}
+
+		if !amount.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("%q: expected %s, got %s", test.input, test.expected, amount)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	type row struct {
+		line        int
+		date        string
+		entryType   int
+		amount      string
+		description string
+		invalid     bool
+	}
+
+	tests := []struct {
+		name    string
+		csv     string
+		mapping model.CSVMapping
+		rows    []row
+		invalid bool
+	}{{
+		name: "negative amount is expense",
+		csv:  "Date,Description,Amount\n2020-01-02,Salary,1000\n2020-01-03,Lunch,-25.5\n",
+		mapping: model.CSVMapping{
+			SkipRows:          1,
+			AmountColumn:      null.IntFrom(2),
+			DescriptionColumn: null.IntFrom(1),
+		},
+		rows: []row{
+			{line: 2, date: "2020-01-02", entryType: 1, amount: "1000", description: "Salary"},
+			{line: 3, date: "2020-01-03", entryType: 2, amount: "25.5", description: "Lunch"},
+		},
+	}, {
+		name: "positive amount is expense",
+		csv:  "25;03/01/2020\n-10;04/01/2020\n",
+		mapping: model.CSVMapping{
+			Delimiter:      ";",
+			DateColumn:     1,
+			DateFormat:     "DD/MM/YYYY",
+			AmountColumn:   null.IntFrom(0),
+			SignConvention: signPositiveExpense,
+		},
+		rows: []row{
+			{line: 1, date: "2020-01-03", entryType: 2, amount: "25"},
+			{line: 2, date: "2020-01-04", entryType: 1, amount: "10"},
+		},
+	}, {
+		name: "separate debit and credit columns, blank line is not a record",
+		csv:  "1/5/2020,Rent,\"1.500,00\",\n\n1/6/2020,Refund,,\"20,5\"\n",
+		mapping: model.CSVMapping{
+			DateFormat:        "M/D/YYYY",
+			DescriptionColumn: null.IntFrom(1),
+			DebitColumn:       null.IntFrom(2),
+			CreditColumn:      null.IntFrom(3),
+			SignConvention:    signDebitCredit,
+			DecimalSeparator:  ",",
+		},
+		rows: []row{
+			{line: 1, date: "2020-01-05", entryType: 2, amount: "1500", description: "Rent"},
+			{line: 2, date: "2020-01-06", entryType: 1, amount: "20.5", description: "Refund"},
+		},
+	}, {
+		name: "invalid records are returned with error",
+		csv:  "2020-13-01,10\n2020-01-01,abc\n2020-01-01,0\n2020-01-01\n",
+		mapping: model.CSVMapping{
+			AmountColumn: null.IntFrom(1),
+		},
+		rows: []row{
+			{line: 1, invalid: true},
+			{line: 2, invalid: true},
+			{line: 3, invalid: true},
+			{line: 4, invalid: true},
+		},
+	}, {
+		name:    "mapping without amount column",
+		csv:     "2020-01-01,10\n",
+		mapping: model.CSVMapping{},
+		invalid: true,
+	}, {
+		name: "debit credit without credit column",
+		csv:  "2020-01-01,10\n",
+		mapping: model.CSVMapping{
+			DebitColumn:    null.IntFrom(1),
+			SignConvention: signDebitCredit,
+		},
+		invalid: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows, err := parseCSV(strings.NewReader(test.csv), test.mapping)
+			if test.invalid {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", rows)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if len(rows) != len(test.rows) {
+				t.Fatalf("expected %d rows, got %d (%+v)", len(test.rows), len(rows), rows)
+			}
+
+			for i, expected := range test.rows {
+				got := rows[i]
+				if got.Line != expected.line {
+					t.Errorf("row %d: expected line %d, got %d", i, expected.line, got.Line)
+				}
+
+				if expected.invalid {
+					if got.Error == "" {
+						t.Errorf("row %d: expected error, got %+v", i, got.Entry)
+					}
+					continue
+				}
+
+				entry := got.Entry
+				if got.Error != "" || entry.Date != expected.date || entry.Type != expected.entryType ||
+					!entry.Amount.Equal(decimal.RequireFromString(expected.amount)) ||
+					entry.Description.String != expected.description {
+					t.Errorf("row %d: expected %+v, got %+v (%s)", i, expected, entry, got.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkDuplicateRows(t *testing.T) {
+	db := openTestDB(t)
+	db.MustExec(`INSERT INTO account (id, name) VALUES (1, 'Cash'), (2, 'Bank')`)
+	db.MustExec(`INSERT INTO entry (account_id, type, amount, date, import_id)
+		VALUES (1, 2, 10, '2020-01-01', 'FIT1'), (2, 2, 10, '2020-01-01', 'FIT2')`)
+
+	row := func(importID string) model.ImportRow {
+		return model.ImportRow{Entry: model.Entry{
+			ImportID: null.NewString(importID, importID != "")}}
+	}
+
+	rows := []model.ImportRow{
+		row("FIT1"), // already imported into this account
+		row("FIT2"), // only imported into other account
+		row("FIT3"),
+		row("FIT3"), // repeated in the same file
+		row(""),
+		row(""),
+	}
+
+	tx := db.MustBegin()
+	defer tx.Rollback()
+
+	markDuplicateRows(tx, 1, rows)
+
+	expected := []bool{true, false, false, true, false, false}
+	for i, row := range rows {
+		if row.Duplicate != expected[i] {
+			t.Errorf("row %d: expected duplicate %v, got %v", i, expected[i], row.Duplicate)
+		}
+	}
+}
+
+// openTestDB opens a new SQLite database with the latest schema.
+func openTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "duit-api")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := database.Open(model.Config{
+		DbDriver: "sqlite",
+		DbPath:   filepath.Join(dir, "duit.db"),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err = database.MigrateUp(db, 0); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	return db
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/importer"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/julienschmidt/httprouter"
+	"gopkg.in/guregu/null.v3"
+)
+
+// ImportJournal is handler for POST /api/import/journal.
+// The request is a multipart form with following fields :
+// - file: the hledger, ledger or beancount journal.
+// - preview: if "true", the parsed journal is returned without being saved.
+// The transactions that can't be imported are returned as rows with error.
+func (h *Handler) ImportJournal(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Parse form
+	err := r.ParseMultipartForm(maxImportSize)
+	checkError(err)
+
+	file, _, err := r.FormFile("file")
+	checkError(err)
+	defer file.Close()
+
+	// Parse the journal
+	journal, err := importer.ParseJournal(file)
+	checkFileError(err)
+	validateImportRows(journal.Rows)
+
+	// Save the journal, unless it's only preview
+	if r.FormValue("preview") != "true" {
+		// Start transaction
+		// Make sure to rollback if panic ever happened
+		tx := h.db.MustBegin()
+
+		defer func() {
+			if r := recover(); r != nil {
+				tx.Rollback()
+				panic(r)
+			}
+		}()
+
+		h.saveJournal(tx, &journal)
+
+		// Commit transaction
+		err = tx.Commit()
+		checkError(err)
+	}
+
+	// Return the journal
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &journal)
+	checkError(err)
+}
+
+// SaveJournal saves accounts and entries from journal in one transaction.
+// It returns the number of saved entries.
+func (h *Handler) SaveJournal(journal *importer.Journal) (n int, err error) {
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			n, err = 0, fmt.Errorf("%v", r)
+		}
+	}()
+
+	n = h.saveJournal(tx, journal)
+
+	err = tx.Commit()
+	checkError(err)
+
+	return n, nil
+}
+
+// saveJournal creates the accounts and categories that don't exist yet, then
+// saves the entries. Initial amount from journal is only used for the new
+// accounts. Rows with error, including the invalid ones, and rows that
+// imported before are skipped.
+func (h *Handler) saveJournal(tx *sqlx.Tx, journal *importer.Journal) int {
+	validateImportRows(journal.Rows)
+
+	// Prepare SQL statements
+	stmtGetAccount, err := tx.Preparex(`
+		SELECT id, COALESCE(currency, ?) currency
+		FROM account WHERE name = ?`)
+	checkError(err)
+
+	stmtInsertAccount, err := tx.Preparex(`INSERT INTO account
+		(name, currency, initial_amount) VALUES (?, ?, ?)`)
+	checkError(err)
+
+	stmtGetEntry, err := tx.Preparex(`SELECT id FROM entry
+		WHERE account_id = ? AND import_id = ?`)
+	checkError(err)
+
+	// Save accounts
+	accountIDs := make(map[string]int64)
+	for i, account := range journal.Accounts {
+		var existing struct {
+			ID       int64  `db:"id"`
+			Currency string `db:"currency"`
+		}
+
+		err = stmtGetAccount.Get(&existing, h.baseCurrency, account.Name)
+		checkError(err)
+
+		if err == nil {
+			currency := existing.Currency
+			if account.Currency != "" {
+				currency, err = normalizeCurrency(account.Currency)
+				checkError(err)
+			}
+
+			if currency != existing.Currency {
+				panic(apierr.Conflict("account %s already exists with currency %s",
+					account.Name, existing.Currency))
+			}
+
+			accountIDs[account.Name] = existing.ID
+			continue
+		}
+
+		newAccount := model.Account{
+			Name:          account.Name,
+			Currency:      account.Currency,
+			InitialAmount: account.InitialAmount,
+		}
+
+		currency := h.prepareAccount(&newAccount)
+		res := stmtInsertAccount.MustExec(newAccount.Name, currency, newAccount.InitialAmount)
+		accountIDs[account.Name], _ = res.LastInsertId()
+		journal.Accounts[i].Currency = newAccount.Currency
+	}
+
+	// Save entries
+	nSaved := 0
+	categoryIDs := make(map[string]int64)
+	for i := range journal.Rows {
+		row := &journal.Rows[i]
+		if row.Error != "" {
+			continue
+		}
+
+		entry := &row.Entry
+		entry.AccountID = accountIDs[entry.Account]
+		if entry.AffectedAccount.Valid {
+			entry.AffectedAccountID = null.IntFrom(accountIDs[entry.AffectedAccount.String])
+		}
+
+		var tmpID int64
+		err = stmtGetEntry.Get(&tmpID, entry.AccountID, entry.ImportID)
+		checkError(err)
+
+		if err == nil {
+			row.Duplicate = true
+			continue
+		}
+
+		if entry.Category.Valid {
+			entry.CategoryID = null.IntFrom(journalCategoryID(tx, categoryIDs, entry.Category.String))
+		}
+
+		for j, split := range entry.Splits {
+			if split.Category.Valid {
+				entry.Splits[j].CategoryID = null.IntFrom(
+					journalCategoryID(tx, categoryIDs, split.Category.String))
+			}
+		}
+
+		h.insertEntry(tx, entry)
+		nSaved++
+	}
+
+	return nSaved
+}
+
+// journalCategoryID returns ID of category from its path, e.g. Food:Snack.
+// The categories will be created if they don't exist yet.
+func journalCategoryID(tx *sqlx.Tx, cache map[string]int64, path string) int64 {
+	if id, exist := cache[path]; exist {
+		return id
+	}
+
+	var parentID null.Int
+	names := strings.Split(path, ":")
+	for i, name := range names {
+		subPath := strings.Join(names[:i+1], ":")
+		if id, exist := cache[subPath]; exist {
+			parentID = null.IntFrom(id)
+			continue
+		}
+
+		var id int64
+		var err error
+		if parentID.Valid {
+			err = tx.Get(&id, `SELECT id FROM category
+				WHERE name = ? AND parent_id = ?`, name, parentID)
+		} else {
+			err = tx.Get(&id, `SELECT id FROM category
+				WHERE name = ? AND parent_id IS NULL`, name)
+		}
+		checkError(err)
+
+		if err != nil {
+			res := tx.MustExec(`INSERT INTO category (parent_id, name) VALUES (?, ?)`,
+				parentID, name)
+			id, _ = res.LastInsertId()
+		}
+
+		cache[subPath] = id
+		parentID = null.IntFrom(id)
+	}
+
+	return parentID.Int64
+}
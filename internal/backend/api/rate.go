@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SelectRates is handler for GET /api/rates
+func (h *Handler) SelectRates(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Get URL parameter
+	currency := r.URL.Query().Get("currency")
+
+	// Fetch from database
+	rates := []model.Rate{}
+	query := `SELECT id, date, from_currency, to_currency, rate FROM currency_rate`
+	args := []interface{}{}
+
+	if currency != "" {
+		code, err := normalizeCurrency(currency)
+		checkError(err)
+
+		query += ` WHERE from_currency = ? OR to_currency = ?`
+		args = append(args, code, code)
+	}
+
+	query += ` ORDER BY date DESC, from_currency, to_currency`
+	err := h.db.Select(&rates, query, args...)
+	checkError(err)
+
+	// Return list of rates
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &rates)
+	checkError(err)
+}
+
+// InsertRate is handler for POST /api/rate
+func (h *Handler) InsertRate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var rate model.Rate
+	err := json.NewDecoder(r.Body).Decode(&rate)
+	checkError(err)
+
+	// Validate input
+	mustValidRate(&rate)
+
+	// Save to database
+	res := h.db.MustExec(`INSERT INTO currency_rate
+		(date, from_currency, to_currency, rate) VALUES (?, ?, ?, ?)`,
+		rate.Date, rate.From, rate.To, rate.Rate)
+	rate.ID, _ = res.LastInsertId()
+
+	// Return inserted rate
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &rate)
+	checkError(err)
+}
+
+// UpdateRate is handler for PUT /api/rate
+func (h *Handler) UpdateRate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var rate model.Rate
+	err := json.NewDecoder(r.Body).Decode(&rate)
+	checkError(err)
+
+	// Validate input
+	mustValidRate(&rate)
+
+	// Update database
+	h.db.MustExec(`UPDATE currency_rate
+		SET date = ?, from_currency = ?, to_currency = ?, rate = ?
+		WHERE id = ?`,
+		rate.Date, rate.From, rate.To, rate.Rate, rate.ID)
+
+	// Return updated rate
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &rate)
+	checkError(err)
+}
+
+// DeleteRates is handler for DELETE /api/rates
+func (h *Handler) DeleteRates(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var ids []int64
+	err := json.NewDecoder(r.Body).Decode(&ids)
+	checkError(err)
+
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Delete from database
+	stmt, err := tx.Preparex(`DELETE FROM currency_rate WHERE id = ?`)
+	checkError(err)
+
+	for _, id := range ids {
+		stmt.MustExec(id)
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	checkError(err)
+}
+
+// mustValidRate panics if the exchange rate is not valid.
+func mustValidRate(rate *model.Rate) {
+	if err := NormalizeRate(rate); err != nil {
+		panic(err)
+	}
+}
+
+// NormalizeRate makes sure the exchange rate is valid and converts its
+// currencies to uppercase. It's also used when importing rates from CLI,
+// so they are checked in the same way as the rates from API.
+func NormalizeRate(rate *model.Rate) error {
+	var err error
+	if _, err = time.Parse("2006-01-02", rate.Date); err != nil {
+		return apierr.Validation("date must be formatted as YYYY-MM-DD").WithField("date")
+	}
+
+	if rate.From, err = normalizeCurrency(rate.From); err != nil {
+		return err
+	}
+
+	if rate.To, err = normalizeCurrency(rate.To); err != nil {
+		return err
+	}
+
+	if rate.From == rate.To {
+		return apierr.Validation("currencies must be different").WithField("to")
+	}
+
+	if !rate.Rate.IsPositive() {
+		return apierr.Validation("rate must be positive").WithField("rate")
+	}
+
+	return nil
+}
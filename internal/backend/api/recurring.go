@@ -0,0 +1,527 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/guregu/null.v3"
+)
+
+const (
+	// maxPreviewDays is the longest period that can be previewed.
+	maxPreviewDays = 366
+
+	// defaultPreviewDays is period that previewed if not specified.
+	defaultPreviewDays = 30
+)
+
+// Rules to adjust the day of recurring entry.
+const (
+	ruleLastDay          = "last-day"
+	ruleFirstBusinessDay = "first-business-day"
+	ruleLastBusinessDay  = "last-business-day"
+	rulePrevBusinessDay  = "previous-business-day"
+	ruleNextBusinessDay  = "next-business-day"
+)
+
+const selectRecurringQuery = `
+	SELECT r.id, r.account_id, r.affected_account_id, r.category_id,
+		a1.name account, a2.name affected_account, c.name category,
+		r.type, r.description, r.amount, r.affected_amount,
+		r.frequency, r.repeat_every, r.day_rule, r.start_date, r.end_date,
+		r.max_count, r.last_seq, r.last_date
+	FROM recurring r
+	LEFT JOIN account a1 ON r.account_id = a1.id
+	LEFT JOIN account a2 ON r.affected_account_id = a2.id
+	LEFT JOIN category c ON r.category_id = c.id`
+
+// SelectRecurring is handler for GET /api/recurring
+func (h *Handler) SelectRecurring(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Fetch from database
+	list := []model.Recurring{}
+	err := h.db.Select(&list, selectRecurringQuery+` ORDER BY r.start_date, r.id`)
+	checkError(err)
+
+	// Return list of recurring entries
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &list)
+	checkError(err)
+}
+
+// InsertRecurring is handler for POST /api/recurring
+func (h *Handler) InsertRecurring(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var rec model.Recurring
+	err := json.NewDecoder(r.Body).Decode(&rec)
+	checkError(err)
+
+	// Validate input
+	mustValidRecurring(&rec)
+
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Save to database
+	res := tx.MustExec(`INSERT INTO recurring
+		(account_id, affected_account_id, category_id, type, description,
+		amount, affected_amount, frequency, repeat_every, day_rule,
+		start_date, end_date, max_count)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.AccountID, rec.AffectedAccountID, rec.CategoryID, rec.Type,
+		rec.Description, rec.Amount, rec.AffectedAmount, rec.Frequency,
+		rec.Every, rec.DayRule, rec.StartDate, rec.EndDate, rec.MaxCount)
+	rec.ID, _ = res.LastInsertId()
+
+	// Fetch the inserted data
+	err = tx.Get(&rec, selectRecurringQuery+` WHERE r.id = ?`, rec.ID)
+	checkError(err)
+
+	// Commit transaction
+	err = tx.Commit()
+	checkError(err)
+
+	// Return inserted recurring entry
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &rec)
+	checkError(err)
+}
+
+// UpdateRecurring is handler for PUT /api/recurring.
+// Entries that already generated are not changed. If the schedule is
+// changed, the next entry will be generated following the new schedule
+// after the date of the last generated entry. The account and type of
+// the template can't be changed.
+func (h *Handler) UpdateRecurring(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var rec model.Recurring
+	err := json.NewDecoder(r.Body).Decode(&rec)
+	checkError(err)
+
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Fetch the old data to find how many entries
+	// already generated using the new schedule
+	var oldRec model.Recurring
+	err = tx.Get(&oldRec, selectRecurringQuery+` WHERE r.id = ?`, rec.ID)
+	checkError(err)
+
+	if err == sql.ErrNoRows {
+		panic(apierr.NotFound("recurring entry doesn't exist"))
+	}
+
+	// Account and type are not updated, so validate
+	// the input using the saved ones
+	rec.AccountID = oldRec.AccountID
+	rec.Type = oldRec.Type
+	mustValidRecurring(&rec)
+
+	rec.LastDate = oldRec.LastDate
+	rec.LastSeq = 0
+	if rec.LastDate.Valid {
+		rec.LastSeq = len(recurringOccurrences(rec, 0, rec.LastDate.String))
+	}
+
+	// Update database
+	tx.MustExec(`UPDATE recurring
+		SET affected_account_id = ?, category_id = ?, description = ?,
+		amount = ?, affected_amount = ?, frequency = ?, repeat_every = ?,
+		day_rule = ?, start_date = ?, end_date = ?, max_count = ?, last_seq = ?
+		WHERE id = ?`,
+		rec.AffectedAccountID, rec.CategoryID, rec.Description,
+		rec.Amount, rec.AffectedAmount, rec.Frequency, rec.Every,
+		rec.DayRule, rec.StartDate, rec.EndDate, rec.MaxCount, rec.LastSeq,
+		rec.ID)
+
+	// Fetch the updated data
+	err = tx.Get(&rec, selectRecurringQuery+` WHERE r.id = ?`, rec.ID)
+	checkError(err)
+
+	// Commit transaction
+	err = tx.Commit()
+	checkError(err)
+
+	// Return updated recurring entry
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &rec)
+	checkError(err)
+}
+
+// DeleteRecurring is handler for DELETE /api/recurring.
+// Entries that already generated are kept.
+func (h *Handler) DeleteRecurring(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var ids []int64
+	err := json.NewDecoder(r.Body).Decode(&ids)
+	checkError(err)
+
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Delete from database
+	stmt, err := tx.Preparex(`DELETE FROM recurring WHERE id = ?`)
+	checkError(err)
+
+	for _, id := range ids {
+		stmt.MustExec(id)
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	checkError(err)
+}
+
+// PreviewRecurring is handler for GET /api/recurring/preview.
+// It returns entries that will be generated in the next N days,
+// including the due entries that not generated yet.
+func (h *Handler) PreviewRecurring(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Get URL parameter
+	days := defaultPreviewDays
+	if strDays := r.URL.Query().Get("days"); strDays != "" {
+		days = strToInt(strDays)
+	}
+
+	if days < 0 || days > maxPreviewDays {
+		panic(apierr.Validation("days must be between 0 and %d", maxPreviewDays).WithField("days"))
+	}
+
+	until := time.Now().AddDate(0, 0, days).Format("2006-01-02")
+
+	// Fetch from database
+	list := []model.Recurring{}
+	err := h.db.Select(&list, selectRecurringQuery)
+	checkError(err)
+
+	// Generate the entries
+	entries := []model.Entry{}
+	for _, rec := range list {
+		for _, occ := range recurringOccurrences(rec, rec.LastSeq, until) {
+			entry := recurringEntry(rec, occ.date)
+			entry.Account = rec.Account
+			entry.AffectedAccount = rec.AffectedAccount
+			entry.Category = rec.Category
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date < entries[j].Date
+	})
+
+	// Return the entries
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &entries)
+	checkError(err)
+}
+
+// ScheduleRecurring generates the due recurring entries, then
+// repeats it periodically following the specified interval.
+func (h *Handler) ScheduleRecurring(interval time.Duration) {
+	for {
+		err := h.GenerateRecurring(time.Now())
+		if err != nil {
+			logrus.Errorln("failed to generate recurring entries:", err)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// GenerateRecurring creates entries from every recurring template that due
+// on or before the specified time. It's safe to be called several times,
+// since each entry will only be generated once.
+func (h *Handler) GenerateRecurring(until time.Time) error {
+	ids := []int64{}
+	err := h.db.Select(&ids, `SELECT id FROM recurring`)
+	if err != nil {
+		return err
+	}
+
+	strUntil := until.Format("2006-01-02")
+	for _, id := range ids {
+		n, err := h.generateRecurring(id, strUntil)
+		if err != nil {
+			logrus.Warnf("failed to generate entries for recurring %d: %v", id, err)
+			continue
+		}
+
+		if n > 0 {
+			logrus.Infof("generated %d entries for recurring %d", n, id)
+		}
+	}
+
+	return nil
+}
+
+// generateRecurring creates the due entries for a recurring template.
+// The template is updated in the same transaction, so if it's already
+// processed by another process, nothing will be generated.
+func (h *Handler) generateRecurring(id int64, until string) (n int, err error) {
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx := h.db.MustBegin()
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			n, err = 0, fmt.Errorf("%v", r)
+		}
+	}()
+
+	// Find the due entries
+	var rec model.Recurring
+	err = tx.Get(&rec, selectRecurringQuery+` WHERE r.id = ?`, id)
+	checkError(err)
+
+	occurrences := recurringOccurrences(rec, rec.LastSeq, until)
+	if err == sql.ErrNoRows || len(occurrences) == 0 {
+		return 0, tx.Rollback()
+	}
+
+	// Save to database
+	for _, occ := range occurrences {
+		entry := recurringEntry(rec, occ.date)
+		h.insertEntry(tx, &entry)
+	}
+
+	last := occurrences[len(occurrences)-1]
+	res := tx.MustExec(`UPDATE recurring SET last_seq = ?, last_date = ?
+		WHERE id = ? AND last_seq = ?`,
+		last.seq, last.date, rec.ID, rec.LastSeq)
+
+	affected, err := res.RowsAffected()
+	checkError(err)
+
+	if affected == 0 {
+		return 0, tx.Rollback()
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	checkError(err)
+
+	return len(occurrences), nil
+}
+
+// recurringEntry creates entry from recurring template at the specified date.
+func recurringEntry(rec model.Recurring, date string) model.Entry {
+	return model.Entry{
+		AccountID:         rec.AccountID,
+		AffectedAccountID: rec.AffectedAccountID,
+		CategoryID:        rec.CategoryID,
+		Type:              rec.Type,
+		Description:       rec.Description,
+		Amount:            rec.Amount,
+		AffectedAmount:    rec.AffectedAmount,
+		Date:              date,
+		RecurringID:       null.IntFrom(rec.ID),
+	}
+}
+
+// mustValidRecurring panics if the recurring template is not valid.
+// If the repeat interval is not specified, it's set to 1.
+func mustValidRecurring(rec *model.Recurring) {
+	if rec.Every == 0 {
+		rec.Every = 1
+	}
+
+	if fields := validateRecurring(*rec); len(fields) > 0 {
+		panic(apierr.InvalidFields(fields))
+	}
+}
+
+// validateRecurring checks the recurring template before it's saved. Since
+// the template is used to generate entries, it's validated as the entry at
+// its start date as well, so it won't be rejected later when the entries are
+// generated. It returns message for each invalid field.
+func validateRecurring(rec model.Recurring) map[string]string {
+	fields := validateEntry(recurringEntry(rec, rec.StartDate))
+
+	startDate, err := time.Parse("2006-01-02", rec.StartDate)
+	if err != nil {
+		delete(fields, "date")
+		fields["startDate"] = "start date must be formatted as YYYY-MM-DD"
+	}
+
+	switch rec.Frequency {
+	case "daily", "weekly", "monthly", "yearly":
+	default:
+		fields["frequency"] = "frequency must be daily, weekly, monthly or yearly"
+	}
+
+	if rec.Every <= 0 {
+		fields["every"] = "every must be positive"
+	}
+
+	// Business day rules would move every weekend of daily
+	// entry into the same date, so they are not usable for it
+	switch rec.DayRule {
+	case "":
+	case rulePrevBusinessDay, ruleNextBusinessDay:
+		if rec.Frequency == "daily" {
+			fields["dayRule"] = fmt.Sprintf("day rule %s is not usable for daily entry", rec.DayRule)
+		}
+	case ruleLastDay, ruleFirstBusinessDay, ruleLastBusinessDay:
+		if rec.Frequency != "monthly" && rec.Frequency != "yearly" {
+			fields["dayRule"] = fmt.Sprintf("day rule %s only usable for monthly or yearly entry", rec.DayRule)
+		}
+	default:
+		fields["dayRule"] = fmt.Sprintf("day rule %s is not valid", rec.DayRule)
+	}
+
+	if rec.EndDate.Valid {
+		endDate, err := time.Parse("2006-01-02", rec.EndDate.String)
+		switch {
+		case err != nil:
+			fields["endDate"] = "end date must be formatted as YYYY-MM-DD"
+		case endDate.Before(startDate):
+			fields["endDate"] = "end date must not before start date"
+		}
+	}
+
+	if rec.MaxCount.Valid && rec.MaxCount.Int64 <= 0 {
+		fields["maxCount"] = "max count must be positive"
+	}
+
+	return fields
+}
+
+// recurringOccurrence is a single occurrence of recurring template.
+// Seq is the sequence number, started from 1.
+type recurringOccurrence struct {
+	seq  int
+	date string
+}
+
+// recurringOccurrences returns occurrences of a recurring template
+// after the specified sequence number until the specified date.
+func recurringOccurrences(rec model.Recurring, afterSeq int, until string) []recurringOccurrence {
+	startDate, err := time.Parse("2006-01-02", rec.StartDate)
+	if err != nil {
+		return nil
+	}
+
+	every := rec.Every
+	if every <= 0 {
+		every = 1
+	}
+
+	result := []recurringOccurrence{}
+	for seq := afterSeq + 1; ; seq++ {
+		if rec.MaxCount.Valid && int64(seq) > rec.MaxCount.Int64 {
+			break
+		}
+
+		date := recurringDate(startDate, rec.Frequency, rec.DayRule, (seq-1)*every)
+		strDate := date.Format("2006-01-02")
+		if strDate > until || (rec.EndDate.Valid && strDate > rec.EndDate.String) {
+			break
+		}
+
+		result = append(result, recurringOccurrence{seq: seq, date: strDate})
+	}
+
+	return result
+}
+
+// recurringDate returns date of the nth period after start date, adjusted
+// following the day rule. If the day doesn't exist in the month, e.g. 31st
+// in February, the last day of the month will be used instead.
+func recurringDate(start time.Time, frequency, rule string, n int) time.Time {
+	var date time.Time
+	switch frequency {
+	case "daily":
+		date = start.AddDate(0, 0, n)
+	case "weekly":
+		date = start.AddDate(0, 0, 7*n)
+	case "monthly", "yearly":
+		nMonth := n
+		if frequency == "yearly" {
+			nMonth = 12 * n
+		}
+
+		firstDay := time.Date(start.Year(), start.Month()+time.Month(nMonth), 1, 0, 0, 0, 0, time.UTC)
+		lastDay := firstDay.AddDate(0, 1, -1)
+
+		date = firstDay.AddDate(0, 0, start.Day()-1)
+		if date.After(lastDay) {
+			date = lastDay
+		}
+
+		switch rule {
+		case ruleLastDay:
+			date = lastDay
+		case ruleLastBusinessDay:
+			date = lastDay
+			rule = rulePrevBusinessDay
+		case ruleFirstBusinessDay:
+			date = firstDay
+			rule = ruleNextBusinessDay
+		}
+	}
+
+	// Move the date if it falls on weekend
+	for date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		switch rule {
+		case rulePrevBusinessDay:
+			date = date.AddDate(0, 0, -1)
+		case ruleNextBusinessDay:
+			date = date.AddDate(0, 0, 1)
+		default:
+			return date
+		}
+	}
+
+	return date
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestRecurringDate(t *testing.T) {
+	tests := []struct {
+		name      string
+		start     string
+		frequency string
+		rule      string
+		n         int
+		expected  string
+	}{
+		{"daily across new year", "2020-12-30", "daily", "", 3, "2021-01-02"},
+		{"weekly", "2021-01-04", "weekly", "", 2, "2021-01-18"},
+		{"weekly on weekend moved to next business day", "2021-01-02", "weekly", ruleNextBusinessDay, 1, "2021-01-11"},
+		{"31 Jan plus one month in leap year", "2020-01-31", "monthly", "", 1, "2020-02-29"},
+		{"31 Jan plus one month", "2021-01-31", "monthly", "", 1, "2021-02-28"},
+		{"31 Jan plus two months keeps the day", "2021-01-31", "monthly", "", 2, "2021-03-31"},
+		{"30 Jan plus one month", "2021-01-30", "monthly", "", 1, "2021-02-28"},
+		{"weekend kept without rule", "2021-01-15", "monthly", "", 4, "2021-05-15"},
+		{"weekend moved to previous business day", "2021-01-15", "monthly", rulePrevBusinessDay, 4, "2021-05-14"},
+		{"weekend moved to next business day", "2021-01-15", "monthly", ruleNextBusinessDay, 4, "2021-05-17"},
+		{"last day of the start month", "2021-01-15", "monthly", ruleLastDay, 0, "2021-01-31"},
+		{"last day of February", "2021-01-15", "monthly", ruleLastDay, 1, "2021-02-28"},
+		{"last business day on Saturday", "2021-07-01", "monthly", ruleLastBusinessDay, 0, "2021-07-30"},
+		{"last business day on weekday", "2021-07-01", "monthly", ruleLastBusinessDay, 1, "2021-08-31"},
+		{"last business day on Sunday", "2021-07-01", "monthly", ruleLastBusinessDay, 3, "2021-10-29"},
+		{"first business day on Sunday", "2021-07-15", "monthly", ruleFirstBusinessDay, 1, "2021-08-02"},
+		{"first business day on Saturday", "2021-04-15", "monthly", ruleFirstBusinessDay, 1, "2021-05-03"},
+		{"yearly on 29 Feb", "2020-02-29", "yearly", "", 1, "2021-02-28"},
+		{"yearly on 29 Feb in the next leap year", "2020-02-29", "yearly", "", 4, "2024-02-29"},
+		{"yearly last day of February", "2019-02-10", "yearly", ruleLastDay, 1, "2020-02-29"},
+		{"yearly last business day of February", "2019-02-01", "yearly", ruleLastBusinessDay, 1, "2020-02-28"},
+	}
+
+	for _, test := range tests {
+		start, _ := time.Parse("2006-01-02", test.start)
+		got := recurringDate(start, test.frequency, test.rule, test.n).Format("2006-01-02")
+		if got != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestRecurringOccurrences(t *testing.T) {
+	monthly := model.Recurring{
+		Frequency: "monthly",
+		Every:     1,
+		StartDate: "2021-01-31",
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(*model.Recurring)
+		afterSeq int
+		until    string
+		expected []recurringOccurrence
+	}{{
+		name:  "until the specified date",
+		until: "2021-04-15",
+		expected: []recurringOccurrence{
+			{1, "2021-01-31"}, {2, "2021-02-28"}, {3, "2021-03-31"},
+		},
+	}, {
+		name:     "after the generated sequence",
+		afterSeq: 2,
+		until:    "2021-05-31",
+		expected: []recurringOccurrence{
+			{3, "2021-03-31"}, {4, "2021-04-30"}, {5, "2021-05-31"},
+		},
+	}, {
+		name:   "every two months",
+		modify: func(r *model.Recurring) { r.Every = 2 },
+		until:  "2021-06-30",
+		expected: []recurringOccurrence{
+			{1, "2021-01-31"}, {2, "2021-03-31"}, {3, "2021-05-31"},
+		},
+	}, {
+		name:   "stopped by end date",
+		modify: func(r *model.Recurring) { r.EndDate = null.StringFrom("2021-03-30") },
+		until:  "2021-12-31",
+		expected: []recurringOccurrence{
+			{1, "2021-01-31"}, {2, "2021-02-28"},
+		},
+	}, {
+		name:   "stopped by max count",
+		modify: func(r *model.Recurring) { r.MaxCount = null.IntFrom(2) },
+		until:  "2021-12-31",
+		expected: []recurringOccurrence{
+			{1, "2021-01-31"}, {2, "2021-02-28"},
+		},
+	}, {
+		name:     "max count already reached",
+		modify:   func(r *model.Recurring) { r.MaxCount = null.IntFrom(2) },
+		afterSeq: 2,
+		until:    "2021-12-31",
+		expected: []recurringOccurrence{},
+	}, {
+		name:     "nothing before start date",
+		until:    "2021-01-30",
+		expected: []recurringOccurrence{},
+	}}
+
+	for _, test := range tests {
+		rec := monthly
+		if test.modify != nil {
+			test.modify(&rec)
+		}
+
+		got := recurringOccurrences(rec, test.afterSeq, test.until)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
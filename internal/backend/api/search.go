@@ -0,0 +1,150 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SearchEntries is handler for GET /api/search.
+// It searches entries from every account whose description contains all of
+// the words in URL parameter "q". The words are matched as prefix, so "coff"
+// will find "coffee". Entries are sorted by their relevance, then their date.
+func (h *Handler) SearchEntries(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Get URL parameter
+	page := strToInt(r.URL.Query().Get("page"))
+	words := searchWords(r.URL.Query().Get("q"))
+	if len(words) == 0 {
+		panic(apierr.Validation("search text must not be empty").WithField("q"))
+	}
+
+	// Start transaction
+	// We only use it to fetch the data,
+	// so just rollback it later
+	tx := h.db.MustBegin()
+	defer tx.Rollback()
+
+	// Prepare SQL statement
+	matchQuery, matchArgs := h.fulltextQuery(words)
+
+	stmtCountEntries, err := tx.Preparex(`
+		SELECT COUNT(*) FROM (` + matchQuery + `) m`)
+	checkError(err)
+
+	stmtSelectEntries, err := tx.Preparex(`
+		SELECT e.id, e.account_id, e.affected_account_id, e.category_id,
+			a1.name account, a2.name affected_account, c.name category,
+			e.type, e.description, e.amount, e.affected_amount, e.date,
+			e.recurring_id, e.import_id
+		FROM entry e
+		JOIN (` + matchQuery + `) m ON m.id = e.id
+		LEFT JOIN account a1 ON e.account_id = a1.id
+		LEFT JOIN account a2 ON e.affected_account_id = a2.id
+		LEFT JOIN category c ON e.category_id = c.id
+		ORDER BY m.relevance DESC, e.date DESC, e.id DESC
+		LIMIT ? OFFSET ?`)
+	checkError(err)
+
+	// Get entry count and calculate max page
+	var nEntries int
+	err = stmtCountEntries.Get(&nEntries, matchArgs...)
+	checkError(err)
+
+	maxPage := int(math.Ceil(float64(nEntries) / pageLength))
+
+	if page > maxPage {
+		page = maxPage
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	offset := (page - 1) * pageLength
+
+	// Fetch entries from database
+	entries := []model.Entry{}
+	selectArgs := append(matchArgs, pageLength, offset)
+	err = stmtSelectEntries.Select(&entries, selectArgs...)
+	checkError(err)
+
+	err = fetchEntriesTags(tx, entries)
+	checkError(err)
+
+	err = fetchEntriesSplits(tx, entries)
+	checkError(err)
+
+	// Return final result
+	result := map[string]interface{}{
+		"page":    page,
+		"maxPage": maxPage,
+		"entries": entries,
+	}
+
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &result)
+	checkError(err)
+}
+
+// fulltextQuery returns query that selects id and relevance of entries
+// whose description contains all of the words. Each database has its own
+// way for full-text search, which is backed by the index created in
+// migration, i.e. FULLTEXT index in MySQL, GIN index in PostgreSQL and
+// FTS4 table in SQLite.
+func (h *Handler) fulltextQuery(words []string) (string, []interface{}) {
+	switch h.db.DriverName() {
+	case "postgres":
+		terms := make([]string, len(words))
+		for i, word := range words {
+			terms[i] = word + ":*"
+		}
+
+		return `SELECT id, ts_rank(to_tsvector('simple', COALESCE(description, '')), q) relevance
+			FROM entry, to_tsquery('simple', ?) q
+			WHERE to_tsvector('simple', COALESCE(description, '')) @@ q`,
+			[]interface{}{strings.Join(terms, " & ")}
+
+	case "sqlite3":
+		// FTS4 doesn't have ranking function, so the relevance is the
+		// number of matched words, counted from result of offsets()
+		// which contains four numbers for each match.
+		terms := make([]string, len(words))
+		for i, word := range words {
+			terms[i] = word + "*"
+		}
+
+		return `SELECT docid id, (LENGTH(offsets(entry_fts)) -
+				LENGTH(REPLACE(offsets(entry_fts), ' ', '')) + 1) / 4 relevance
+			FROM entry_fts WHERE entry_fts MATCH ?`,
+			[]interface{}{strings.Join(terms, " ")}
+
+	default:
+		terms := make([]string, len(words))
+		for i, word := range words {
+			terms[i] = "+" + word + "*"
+		}
+
+		query := strings.Join(terms, " ")
+		return `SELECT id, MATCH (description) AGAINST (? IN BOOLEAN MODE) relevance
+			FROM entry WHERE MATCH (description) AGAINST (? IN BOOLEAN MODE)`,
+			[]interface{}{query, query}
+	}
+}
+
+// searchWords splits the search text into lower case words. Characters other
+// than letters and digits are removed, since they might be treated as
+// operator in full-text search.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/backend/auth"
+	"github.com/julienschmidt/httprouter"
+)
+
+// sessionResult is a session that returned to client.
+// Current marks the session that used by the request.
+type sessionResult struct {
+	auth.Session
+	Current bool `json:"current"`
+}
+
+// SelectSessions is handler for GET /api/sessions.
+// It returns the active sessions of current user.
+func (h *Handler) SelectSessions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Fetch sessions of current user
+	current, err := h.auth.CurrentSession(r)
+	checkError(err)
+
+	sessions, err := h.auth.UserSessions(current.User.ID)
+	checkError(err)
+
+	// Return list of sessions
+	writeSessions(w, sessions, current.ID)
+}
+
+// DeleteSession is handler for DELETE /api/sessions/:id.
+// It invalidates a session of current user, e.g. the one
+// that left open in another device.
+func (h *Handler) DeleteSession(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Make sure the session belongs to current user
+	current, err := h.auth.CurrentSession(r)
+	checkError(err)
+
+	session, found, err := h.auth.GetSession(ps.ByName("id"))
+	checkError(err)
+
+	if !found || session.User.ID != current.User.ID {
+		panic(apierr.NotFound("session doesn't exist"))
+	}
+
+	// Revoke the session
+	err = h.auth.RevokeSession(session.ID)
+	checkError(err)
+}
+
+// SelectUserSessions is handler for GET /api/admin/users/:id/sessions.
+// It returns the active sessions of the specified user.
+func (h *Handler) SelectUserSessions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Fetch sessions of the user
+	current, err := h.auth.CurrentSession(r)
+	checkError(err)
+
+	userID := int64(strToInt(ps.ByName("id")))
+	sessions, err := h.auth.UserSessions(userID)
+	checkError(err)
+
+	// Return list of sessions
+	writeSessions(w, sessions, current.ID)
+}
+
+// DeleteUserSessions is handler for DELETE /api/admin/users/:id/sessions.
+// It invalidates all sessions of the specified user.
+func (h *Handler) DeleteUserSessions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Revoke all sessions of the user
+	userID := int64(strToInt(ps.ByName("id")))
+	err := h.auth.MassLogout(userID)
+	checkError(err)
+}
+
+// DeleteAnySession is handler for DELETE /api/admin/sessions/:id.
+// It invalidates a session of any user.
+func (h *Handler) DeleteAnySession(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Make sure the session exists
+	session, found, err := h.auth.GetSession(ps.ByName("id"))
+	checkError(err)
+
+	if !found {
+		panic(apierr.NotFound("session doesn't exist"))
+	}
+
+	// Revoke the session
+	err = h.auth.RevokeSession(session.ID)
+	checkError(err)
+}
+
+// writeSessions writes the sessions as JSON, marking the current one.
+func writeSessions(w http.ResponseWriter, sessions []auth.Session, currentID string) {
+	results := make([]sessionResult, len(sessions))
+	for i, session := range sessions {
+		results[i] = sessionResult{
+			Session: session,
+			Current: session.ID == currentID,
+		}
+	}
+
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err := encodeGzippedJSON(w, &results)
+	checkError(err)
+}
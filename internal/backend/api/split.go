@@ -0,0 +1,99 @@
+package api
+
+import (
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+)
+
+// saveEntrySplits replaces splits of an entry with the specified splits.
+func saveEntrySplits(tx *sqlx.Tx, entryID int64, splits []model.EntrySplit) error {
+	_, err := tx.Exec(`DELETE FROM entry_split WHERE entry_id = ?`, entryID)
+	if err != nil {
+		return err
+	}
+
+	for _, split := range splits {
+		_, err = tx.Exec(`INSERT INTO entry_split
+			(entry_id, category_id, description, amount)
+			VALUES (?, ?, ?, ?)`,
+			entryID, split.CategoryID, split.Description, split.Amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mustValidSplits panics if the splits of an entry can't be used
+// with the entry, i.e. the entry is a transfer or the amount of its
+// splits doesn't add up to the entry's amount. Entry without any
+// split is always valid.
+func mustValidSplits(tx *sqlx.Tx, entryID int64) {
+	var entry model.Entry
+	err := tx.Get(&entry, `SELECT type, amount FROM entry WHERE id = ?`, entryID)
+	checkError(err)
+
+	var amounts []decimal.Decimal
+	err = tx.Select(&amounts, `SELECT amount FROM entry_split WHERE entry_id = ?`, entryID)
+	checkError(err)
+
+	if len(amounts) == 0 {
+		return
+	}
+
+	if entry.Type == 3 {
+		panic(apierr.Validation("transfer can't be split"))
+	}
+
+	total := decimal.Zero
+	for _, amount := range amounts {
+		total = total.Add(amount)
+	}
+
+	if !total.Equal(entry.Amount) {
+		panic(apierr.Validation("total of splits (%s) must be equal to entry amount (%s)",
+			total.String(), entry.Amount.String()))
+	}
+}
+
+// fetchEntriesSplits fills splits for each of the specified entries.
+func fetchEntriesSplits(tx *sqlx.Tx, entries []model.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(entries))
+	entryIdx := make(map[int64]int)
+	for i, entry := range entries {
+		ids[i] = entry.ID
+		entryIdx[entry.ID] = i
+		entries[i].Splits = []model.EntrySplit{}
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT s.id, s.entry_id, s.category_id, s.description, s.amount,
+			c.name category
+		FROM entry_split s
+		LEFT JOIN category c ON s.category_id = c.id
+		WHERE s.entry_id IN (?)
+		ORDER BY s.id`, ids)
+	if err != nil {
+		return err
+	}
+
+	splits := []model.EntrySplit{}
+	err = tx.Select(&splits, query, args...)
+	if err != nil {
+		return err
+	}
+
+	for _, split := range splits {
+		idx := entryIdx[split.EntryID]
+		entries[idx].Splits = append(entries[idx].Splits, split)
+	}
+
+	return nil
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/julienschmidt/httprouter"
+	"github.com/shopspring/decimal"
+)
+
+// SelectTags is handler for GET /api/tags
+func (h *Handler) SelectTags(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Start transaction
+	// We only use it to fetch the data,
+	// so just rollback it later
+	tx := h.db.MustBegin()
+	defer tx.Rollback()
+
+	// Fetch from database
+	tags := []model.Tag{}
+	err := tx.Select(&tags, `
+		SELECT t.id, t.name, COUNT(et.entry_id) count
+		FROM tag t
+		JOIN entry_tag et ON et.tag_id = t.id
+		GROUP BY t.id, t.name
+		ORDER BY t.name`)
+	checkError(err)
+
+	amounts := []struct {
+		TagID    int64           `db:"tag_id"`
+		Type     int             `db:"type"`
+		Date     string          `db:"date"`
+		Currency string          `db:"currency"`
+		Amount   decimal.Decimal `db:"amount"`
+	}{}
+	err = tx.Select(&amounts, `
+		SELECT et.tag_id, e.type, e.date,
+			COALESCE(a.currency, ?) currency, SUM(e.amount) amount
+		FROM entry_tag et
+		JOIN entry e ON e.id = et.entry_id
+		JOIN account a ON a.id = e.account_id
+		WHERE e.type IN (1, 2)
+		GROUP BY et.tag_id, e.type, e.date, a.currency`, h.baseCurrency)
+	checkError(err)
+
+	rates, err := loadExchangeRates(tx)
+	checkError(err)
+
+	// Entries of a tag may come from accounts with different currency, so
+	// the totals are converted into base currency using exchange rate at
+	// the date of entry. Amounts that don't have exchange rate are skipped,
+	// and their currency is reported in the tag.
+	tagIdx := make(map[int64]int)
+	missing := make(map[int64]missingRates)
+	for i, tag := range tags {
+		tagIdx[tag.ID] = i
+		missing[tag.ID] = missingRates{}
+	}
+
+	for _, ta := range amounts {
+		idx, exist := tagIdx[ta.TagID]
+		if !exist {
+			continue
+		}
+
+		amount, err := rates.convert(ta.Amount, ta.Currency, h.baseCurrency, ta.Date)
+		if err != nil {
+			missing[ta.TagID].add(ta.Currency)
+			continue
+		}
+
+		if ta.Type == 1 {
+			tags[idx].Income = tags[idx].Income.Add(amount)
+		} else {
+			tags[idx].Expense = tags[idx].Expense.Add(amount)
+		}
+	}
+
+	for i, tag := range tags {
+		if len(missing[tag.ID]) > 0 {
+			tags[i].MissingRates = missing[tag.ID].list()
+		}
+	}
+
+	// Return list of tags
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &tags)
+	checkError(err)
+}
+
+// normalizeTags converts tags to lowercase, removes surrounding
+// whitespaces and removes the empty or duplicate tags.
+func normalizeTags(tags []string) []string {
+	result := []string{}
+	exist := make(map[string]struct{})
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if _, found := exist[tag]; found || tag == "" {
+			continue
+		}
+
+		exist[tag] = struct{}{}
+		result = append(result, tag)
+	}
+
+	return result
+}
+
+// saveEntryTags replaces tags of an entry with the specified tags.
+// If a tag doesn't exist yet, it will be created.
+func saveEntryTags(tx *sqlx.Tx, entryID int64, tags []string) error {
+	_, err := tx.Exec(`DELETE FROM entry_tag WHERE entry_id = ?`, entryID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range normalizeTags(tags) {
+		var tagID int64
+		err = tx.Get(&tagID, `SELECT id FROM tag WHERE name = ?`, tag)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		if err == sql.ErrNoRows {
+			res, err := tx.Exec(`INSERT INTO tag (name) VALUES (?)`, tag)
+			if err != nil {
+				return err
+			}
+
+			tagID, err = res.LastInsertId()
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`INSERT INTO entry_tag (entry_id, tag_id) VALUES (?, ?)`,
+			entryID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return removeUnusedTags(tx)
+}
+
+// removeUnusedTags removes tags that not used by any entry.
+func removeUnusedTags(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`DELETE FROM tag
+		WHERE id NOT IN (SELECT tag_id FROM entry_tag)`)
+	return err
+}
+
+// fetchEntriesTags fills tags for each of the specified entries.
+func fetchEntriesTags(tx *sqlx.Tx, entries []model.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(entries))
+	entryIdx := make(map[int64]int)
+	for i, entry := range entries {
+		ids[i] = entry.ID
+		entryIdx[entry.ID] = i
+		entries[i].Tags = []string{}
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT et.entry_id, t.name
+		FROM entry_tag et
+		JOIN tag t ON t.id = et.tag_id
+		WHERE et.entry_id IN (?)
+		ORDER BY t.name`, ids)
+	if err != nil {
+		return err
+	}
+
+	entryTags := []struct {
+		EntryID int64  `db:"entry_id"`
+		Name    string `db:"name"`
+	}{}
+
+	err = tx.Select(&entryTags, query, args...)
+	if err != nil {
+		return err
+	}
+
+	for _, et := range entryTags {
+		idx := entryIdx[et.EntryID]
+		entries[idx].Tags = append(entries[idx].Tags, et.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/auth"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SelectTokens is handler for GET /api/tokens.
+// It returns API tokens of current user.
+func (h *Handler) SelectTokens(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Fetch tokens of current user
+	current, err := h.auth.CurrentSession(r)
+	checkError(err)
+
+	tokens, err := h.auth.ListTokens(current.User.ID)
+	checkError(err)
+
+	// Return list of tokens
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &tokens)
+	checkError(err)
+}
+
+// InsertToken is handler for POST /api/tokens.
+// It creates a new API token for current user. The token is only
+// returned this time, since only its hash is saved in database.
+func (h *Handler) InsertToken(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var request struct {
+		Name  string `json:"name"`
+		Scope string `json:"scope"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	checkError(err)
+
+	// Create token for current user
+	current, err := h.auth.CurrentSession(r)
+	checkError(err)
+
+	apiToken, token, err := h.auth.CreateToken(current.User, request.Name, request.Scope)
+	checkError(err)
+
+	// Return the new token
+	result := struct {
+		auth.APIToken
+		Token string `json:"token"`
+	}{apiToken, token}
+
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &result)
+	checkError(err)
+}
+
+// DeleteToken is handler for DELETE /api/tokens/:id.
+// It revokes API token of current user.
+func (h *Handler) DeleteToken(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Revoke token of current user
+	current, err := h.auth.CurrentSession(r)
+	checkError(err)
+
+	tokenID := int64(strToInt(ps.ByName("id")))
+	err = h.auth.RevokeToken(current.User.ID, tokenID)
+	checkError(err)
+}
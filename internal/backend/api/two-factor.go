@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetTwoFactor is handler for GET /api/2fa.
+// It returns two-factor authentication status of current user.
+func (h *Handler) GetTwoFactor(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Fetch status of current user
+	current, err := h.auth.CurrentSession(r)
+	checkError(err)
+
+	status, err := h.auth.GetTwoFactorStatus(current.User.ID)
+	checkError(err)
+
+	// Return the status
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &status)
+	checkError(err)
+}
+
+// SetupTwoFactor is handler for POST /api/2fa/setup.
+// It generates a new TOTP secret for current user. The secret
+// must be confirmed using POST /api/2fa/enable before used.
+func (h *Handler) SetupTwoFactor(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Generate secret for current user
+	current, err := h.auth.CurrentSession(r)
+	checkError(err)
+
+	secret, uri, err := h.auth.SetupTwoFactor(current.User)
+	checkError(err)
+
+	// Return the secret
+	result := map[string]string{
+		"secret": secret,
+		"uri":    uri,
+	}
+
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &result)
+	checkError(err)
+}
+
+// EnableTwoFactor is handler for POST /api/2fa/enable.
+// It verifies the first code from authenticator app, then returns the
+// recovery codes that can be used when the app is not available.
+func (h *Handler) EnableTwoFactor(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var request struct {
+		Code string `json:"code"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	checkError(err)
+
+	// Enable two-factor for current user
+	current, err := h.auth.CurrentSession(r)
+	checkError(err)
+
+	codes, err := h.auth.EnableTwoFactor(current.User.ID, request.Code)
+	checkError(err)
+
+	// Return recovery codes
+	result := map[string][]string{
+		"recoveryCodes": codes,
+	}
+
+	w.Header().Add("Content-Encoding", "gzip")
+	w.Header().Add("Content-Type", "application/json")
+	err = encodeGzippedJSON(w, &result)
+	checkError(err)
+}
+
+// DisableTwoFactor is handler for POST /api/2fa/disable.
+// It requires password of current user.
+func (h *Handler) DisableTwoFactor(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Decode request
+	var request struct {
+		Password string `json:"password"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&request)
+	checkError(err)
+
+	// Disable two-factor for current user
+	current, err := h.auth.CurrentSession(r)
+	checkError(err)
+
+	err = h.auth.DisableTwoFactor(current.User.ID, request.Password)
+	checkError(err)
+}
+
+// ResetTwoFactor is handler for DELETE /api/admin/users/:id/2fa.
+// It disables two-factor authentication of the specified user,
+// e.g. when the user lost its authenticator app.
+func (h *Handler) ResetTwoFactor(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Make sure session still valid
+	h.auth.MustAuthenticateUser(r)
+
+	// Reset two-factor of the user
+	userID := int64(strToInt(ps.ByName("id")))
+	err := h.auth.ResetTwoFactor(userID)
+	checkError(err)
+}
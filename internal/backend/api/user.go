@@ -3,9 +3,9 @@ package api
 import (
 	"database/sql"
 	"encoding/json"
-	"fmt"
 	"net/http"
 
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
 	"github.com/RadhiFadlillah/duit/internal/model"
 	"github.com/julienschmidt/httprouter"
 	"golang.org/x/crypto/bcrypt"
@@ -19,7 +19,7 @@ func (h *Handler) SelectUsers(w http.ResponseWriter, r *http.Request, ps httprou
 	// Fetch from database
 	users := []model.User{}
 	err := h.db.Select(&users,
-		`SELECT id, username, name, admin FROM user ORDER BY name`)
+		`SELECT id, username, name, admin FROM "user" ORDER BY name`)
 	checkError(err)
 
 	// Return list of users
@@ -38,11 +38,11 @@ func (h *Handler) InsertUser(w http.ResponseWriter, r *http.Request, ps httprout
 
 	// Validate input
 	if user.Name == "" {
-		panic(fmt.Errorf("name must not empty"))
+		panic(apierr.Validation("name must not empty").WithField("name"))
 	}
 
 	if user.Username == "" {
-		panic(fmt.Errorf("username must not empty"))
+		panic(apierr.Validation("username must not empty").WithField("username"))
 	}
 
 	// Generate password if needed
@@ -63,10 +63,10 @@ func (h *Handler) InsertUser(w http.ResponseWriter, r *http.Request, ps httprout
 
 	// Prepare statements
 	stmtCountAdmin, err := tx.Preparex(`SELECT COUNT(id) 
-		FROM user WHERE admin = 1`)
+		FROM "user" WHERE admin = TRUE`)
 	checkError(err)
 
-	stmtInsert, err := tx.Preparex(`INSERT INTO user
+	stmtInsert, err := tx.Preparex(`INSERT INTO "user"
 		(username, name, password, admin) VALUES (?, ?, ?, ?)`)
 	checkError(err)
 
@@ -121,16 +121,17 @@ func (h *Handler) DeleteUsers(w http.ResponseWriter, r *http.Request, ps httprou
 	}()
 
 	// Prepare statements
-	stmtGet, err := tx.Preparex(`SELECT username FROM user WHERE id = ?`)
+	stmtGet, err := tx.Preparex(`SELECT username FROM "user" WHERE id = ?`)
 	checkError(err)
 
-	stmtDelete, err := tx.Preparex(`DELETE FROM user WHERE id = ?`)
+	stmtDelete, err := tx.Preparex(`DELETE FROM "user" WHERE id = ?`)
 	checkError(err)
 
-	stmtCountAdmin, err := tx.Preparex(`SELECT COUNT(id) FROM user WHERE admin = 1`)
+	stmtCountAdmin, err := tx.Preparex(`SELECT COUNT(id) FROM "user" WHERE admin = TRUE`)
 	checkError(err)
 
 	// Delete from database
+	deletedIDs := []int64{}
 	for _, id := range ids {
 		var username string
 		err = stmtGet.Get(&username, id)
@@ -140,7 +141,7 @@ func (h *Handler) DeleteUsers(w http.ResponseWriter, r *http.Request, ps httprou
 		}
 
 		stmtDelete.MustExec(id)
-		h.auth.MassLogout(username)
+		deletedIDs = append(deletedIDs, int64(id))
 	}
 
 	// Make sure at least one admin exists
@@ -149,12 +150,18 @@ func (h *Handler) DeleteUsers(w http.ResponseWriter, r *http.Request, ps httprou
 	checkError(err)
 
 	if nAdmin == 0 {
-		panic(fmt.Errorf("at least one admin must exists"))
+		panic(apierr.Conflict("at least one admin must exists"))
 	}
 
 	// Commit transaction
 	err = tx.Commit()
 	checkError(err)
+
+	// Do mass logout for the deleted users
+	for _, id := range deletedIDs {
+		err = h.auth.MassLogout(id)
+		checkError(err)
+	}
 }
 
 // UpdateUser is handler for PUT /api/user
@@ -169,11 +176,11 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request, ps httprout
 
 	// Validate input
 	if user.Name == "" {
-		panic(fmt.Errorf("name must not empty"))
+		panic(apierr.Validation("name must not empty").WithField("name"))
 	}
 
 	if user.Username == "" {
-		panic(fmt.Errorf("username must not empty"))
+		panic(apierr.Validation("username must not empty").WithField("username"))
 	}
 
 	// Start transaction
@@ -189,15 +196,15 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request, ps httprout
 
 	// Prepare statements
 	stmtGet, err := tx.Preparex(`SELECT id, username, name, admin
-		FROM user WHERE id = ?`)
+		FROM "user" WHERE id = ?`)
 	checkError(err)
 
-	stmtUpdate, err := tx.Preparex(`UPDATE user 
+	stmtUpdate, err := tx.Preparex(`UPDATE "user" 
 		SET username = ?, name = ?, admin = ? 
 		WHERE id = ?`)
 	checkError(err)
 
-	stmtCountAdmin, err := tx.Preparex(`SELECT COUNT(id) FROM user WHERE admin = 1`)
+	stmtCountAdmin, err := tx.Preparex(`SELECT COUNT(id) FROM "user" WHERE admin = TRUE`)
 	checkError(err)
 
 	// Fetch old user data
@@ -206,7 +213,7 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request, ps httprout
 	checkError(err)
 
 	if err == sql.ErrNoRows {
-		panic(fmt.Errorf("user doesn't exist"))
+		panic(apierr.NotFound("user doesn't exist"))
 	}
 
 	// Update user in database
@@ -218,7 +225,7 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request, ps httprout
 	checkError(err)
 
 	if nAdmin == 0 {
-		panic(fmt.Errorf("at least one admin must exists"))
+		panic(apierr.Conflict("at least one admin must exists"))
 	}
 
 	// Commit transaction
@@ -227,7 +234,8 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request, ps httprout
 
 	// If username or admin status changed, do mass logout
 	if oldUser.Username != user.Username || oldUser.Admin != user.Admin {
-		h.auth.MassLogout(oldUser.Username)
+		err = h.auth.MassLogout(oldUser.ID)
+		checkError(err)
 	}
 
 	// Return updated user
@@ -262,10 +270,10 @@ func (h *Handler) ChangeUserPassword(w http.ResponseWriter, r *http.Request, ps
 
 	// Prepare statement
 	stmtGet, err := tx.Preparex(`SELECT id, name, username, password
-		FROM user WHERE id = ?`)
+		FROM "user" WHERE id = ?`)
 	checkError(err)
 
-	stmtUpdate, err := tx.Preparex(`UPDATE user
+	stmtUpdate, err := tx.Preparex(`UPDATE "user"
 		SET password = ? WHERE id = ?`)
 	checkError(err)
 
@@ -277,7 +285,7 @@ func (h *Handler) ChangeUserPassword(w http.ResponseWriter, r *http.Request, ps
 	// Compare old password with database
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.OldPassword))
 	if err != nil {
-		panic(fmt.Errorf("old password for %s doesn't match", user.Username))
+		panic(apierr.Validation("old password for %s doesn't match", user.Username).WithField("oldPassword"))
 	}
 
 	// Hash the new password with bcrypt
@@ -288,12 +296,13 @@ func (h *Handler) ChangeUserPassword(w http.ResponseWriter, r *http.Request, ps
 	// Update password in database
 	stmtUpdate.MustExec(string(hashedPassword), user.ID)
 
-	// Do mass logout for this account
-	h.auth.MassLogout(user.Username)
-
 	// Commit transaction
 	err = tx.Commit()
 	checkError(err)
+
+	// Do mass logout for this account
+	err = h.auth.MassLogout(user.ID)
+	checkError(err)
 }
 
 // ResetUserPassword is handler for PUT /api/user/password/reset
@@ -318,10 +327,10 @@ func (h *Handler) ResetUserPassword(w http.ResponseWriter, r *http.Request, ps h
 	}()
 
 	// Prepare statement
-	stmtGet, err := tx.Preparex(`SELECT username FROM user WHERE id = ?`)
+	stmtGet, err := tx.Preparex(`SELECT username FROM "user" WHERE id = ?`)
 	checkError(err)
 
-	stmtUpdate, err := tx.Preparex(`UPDATE user SET password = ? WHERE id = ?`)
+	stmtUpdate, err := tx.Preparex(`UPDATE "user" SET password = ? WHERE id = ?`)
 	checkError(err)
 
 	// Get username from database
@@ -337,13 +346,14 @@ func (h *Handler) ResetUserPassword(w http.ResponseWriter, r *http.Request, ps h
 	// Update password in database
 	stmtUpdate.MustExec(hashedPassword, id)
 
-	// Do mass logout for this user
-	h.auth.MassLogout(username)
-
 	// Commit transaction
 	err = tx.Commit()
 	checkError(err)
 
+	// Do mass logout for this user
+	err = h.auth.MassLogout(int64(id))
+	checkError(err)
+
 	// Return new passwords
 	result := struct {
 		ID       int    `json:"id"`
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/model"
+)
+
+// Maximum length of text fields, following size of their column.
+const (
+	maxAccountNameLength      = 100
+	maxEntryDescriptionLength = 150
+	maxTagNameLength          = 50
+)
+
+// mustValidAccount panics if the account is not valid.
+func mustValidAccount(account model.Account) {
+	if fields := validateAccount(account); len(fields) > 0 {
+		panic(apierr.InvalidFields(fields))
+	}
+}
+
+// mustValidEntry panics if the entry is not valid.
+func mustValidEntry(entry model.Entry) {
+	if fields := validateEntry(entry); len(fields) > 0 {
+		panic(apierr.InvalidFields(fields))
+	}
+}
+
+// validateAccount checks the account before it's saved.
+// It returns message for each invalid field.
+func validateAccount(account model.Account) map[string]string {
+	fields := map[string]string{}
+
+	name := strings.TrimSpace(account.Name)
+	switch {
+	case name == "":
+		fields["name"] = "name must not empty"
+	case utf8.RuneCountInString(name) > maxAccountNameLength:
+		fields["name"] = fmt.Sprintf("name must not longer than %d characters", maxAccountNameLength)
+	}
+
+	if account.Currency != "" {
+		if _, err := normalizeCurrency(account.Currency); err != nil {
+			fields["currency"] = err.Error()
+		}
+	}
+
+	return fields
+}
+
+// validateEntry checks the entry before it's saved.
+// It returns message for each invalid field.
+func validateEntry(entry model.Entry) map[string]string {
+	fields := map[string]string{}
+
+	if entry.AccountID <= 0 {
+		fields["accountId"] = "account must be specified"
+	}
+
+	if entry.Type < 1 || entry.Type > 3 {
+		fields["type"] = "type must be 1 (income), 2 (expense) or 3 (transfer)"
+	}
+
+	if !entry.Amount.IsPositive() {
+		fields["amount"] = "amount must be positive"
+	}
+
+	if _, err := time.Parse("2006-01-02", entry.Date); err != nil {
+		fields["date"] = "date must be formatted as YYYY-MM-DD"
+	}
+
+	if entry.Description.Valid &&
+		utf8.RuneCountInString(entry.Description.String) > maxEntryDescriptionLength {
+		fields["description"] = fmt.Sprintf("description must not longer than %d characters",
+			maxEntryDescriptionLength)
+	}
+
+	for _, tag := range normalizeTags(entry.Tags) {
+		if utf8.RuneCountInString(tag) > maxTagNameLength {
+			fields["tags"] = fmt.Sprintf("tag must not longer than %d characters", maxTagNameLength)
+			break
+		}
+	}
+
+	// Only transfer has affected account, which must
+	// be different with the source account
+	switch {
+	case entry.Type == 3 && !entry.AffectedAccountID.Valid:
+		fields["affectedAccountId"] = "affected account must be specified for transfer"
+	case entry.Type == 3 && entry.AffectedAccountID.Int64 == entry.AccountID:
+		fields["affectedAccountId"] = "affected account must be different with the account"
+	case entry.Type != 3 && entry.AffectedAccountID.Valid:
+		fields["affectedAccountId"] = "affected account is only for transfer"
+	}
+
+	if entry.Type == 3 && entry.AffectedAmount.Valid && !entry.AffectedAmount.Decimal.IsPositive() {
+		fields["affectedAmount"] = "affected amount must be positive"
+	}
+
+	for i, split := range entry.Splits {
+		if !split.Amount.IsPositive() {
+			fields[fmt.Sprintf("splits[%d].amount", i)] = "amount of split must be positive"
+		}
+	}
+
+	return fields
+}
+
+// validateImportRows checks the entry of each imported row, and marks the
+// invalid ones with the error message, so they can be reported with their
+// line number before any of them saved. Rows that already have error are
+// skipped. Accounts are only known when the rows are saved, so they are not
+// checked here.
+func validateImportRows(rows []model.ImportRow) {
+	for i := range rows {
+		if rows[i].Error != "" {
+			continue
+		}
+
+		fields := validateEntry(rows[i].Entry)
+		delete(fields, "accountId")
+		delete(fields, "affectedAccountId")
+		if len(fields) > 0 {
+			rows[i].Error = apierr.InvalidFields(fields).Message
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package api
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestValidateAccount(t *testing.T) {
+	tests := []struct {
+		name    string
+		account model.Account
+		fields  []string
+	}{{
+		name:    "valid",
+		account: model.Account{Name: "Cash"},
+	}, {
+		name:    "valid with currency",
+		account: model.Account{Name: "Wallet", Currency: "usd"},
+	}, {
+		name:    "negative initial amount is allowed",
+		account: model.Account{Name: "Credit card", InitialAmount: decimal.New(-100, 0)},
+	}, {
+		name:    "empty name",
+		account: model.Account{Name: "  "},
+		fields:  []string{"name"},
+	}, {
+		name:    "name too long",
+		account: model.Account{Name: strings.Repeat("a", maxAccountNameLength+1)},
+		fields:  []string{"name"},
+	}, {
+		name:    "invalid currency",
+		account: model.Account{Name: "Cash", Currency: "US$"},
+		fields:  []string{"currency"},
+	}, {
+		name:    "several invalid fields",
+		account: model.Account{Currency: "dollar"},
+		fields:  []string{"currency", "name"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkInvalidFields(t, validateAccount(test.account), test.fields)
+		})
+	}
+}
+
+func TestValidateEntry(t *testing.T) {
+	income := model.Entry{
+		AccountID: 1,
+		Type:      1,
+		Amount:    decimal.New(100, 0),
+		Date:      "2020-01-31",
+	}
+
+	transfer := income
+	transfer.Type = 3
+	transfer.AffectedAccountID = null.IntFrom(2)
+
+	tests := []struct {
+		name   string
+		base   model.Entry
+		modify func(*model.Entry)
+		fields []string
+	}{{
+		name: "valid income",
+		base: income,
+	}, {
+		name:   "valid expense",
+		base:   income,
+		modify: func(e *model.Entry) { e.Type = 2 },
+	}, {
+		name: "valid transfer",
+		base: transfer,
+	}, {
+		name: "valid transfer with affected amount",
+		base: transfer,
+		modify: func(e *model.Entry) {
+			e.AffectedAmount = decimal.NullDecimal{Decimal: decimal.New(7, 0), Valid: true}
+		},
+	}, {
+		name:   "missing account",
+		base:   income,
+		modify: func(e *model.Entry) { e.AccountID = 0 },
+		fields: []string{"accountId"},
+	}, {
+		name:   "type too small",
+		base:   income,
+		modify: func(e *model.Entry) { e.Type = 0 },
+		fields: []string{"type"},
+	}, {
+		name:   "type too big",
+		base:   income,
+		modify: func(e *model.Entry) { e.Type = 4 },
+		fields: []string{"type"},
+	}, {
+		name:   "negative amount",
+		base:   income,
+		modify: func(e *model.Entry) { e.Amount = decimal.New(-5, 0) },
+		fields: []string{"amount"},
+	}, {
+		name:   "zero amount",
+		base:   income,
+		modify: func(e *model.Entry) { e.Amount = decimal.Zero },
+		fields: []string{"amount"},
+	}, {
+		name:   "unparseable date",
+		base:   income,
+		modify: func(e *model.Entry) { e.Date = "31/01/2020" },
+		fields: []string{"date"},
+	}, {
+		name:   "impossible date",
+		base:   income,
+		modify: func(e *model.Entry) { e.Date = "2020-02-30" },
+		fields: []string{"date"},
+	}, {
+		name:   "empty date",
+		base:   income,
+		modify: func(e *model.Entry) { e.Date = "" },
+		fields: []string{"date"},
+	}, {
+		name: "description too long",
+		base: income,
+		modify: func(e *model.Entry) {
+			e.Description = null.StringFrom(strings.Repeat("a", maxEntryDescriptionLength+1))
+		},
+		fields: []string{"description"},
+	}, {
+		name: "description at max length",
+		base: income,
+		modify: func(e *model.Entry) {
+			e.Description = null.StringFrom(strings.Repeat("é", maxEntryDescriptionLength))
+		},
+	}, {
+		name: "tag too long",
+		base: income,
+		modify: func(e *model.Entry) {
+			e.Tags = []string{"food", strings.Repeat("a", maxTagNameLength+1)}
+		},
+		fields: []string{"tags"},
+	}, {
+		name: "tag at max length",
+		base: income,
+		modify: func(e *model.Entry) {
+			e.Tags = []string{" " + strings.Repeat("é", maxTagNameLength) + " "}
+		},
+	}, {
+		name:   "transfer without affected account",
+		base:   transfer,
+		modify: func(e *model.Entry) { e.AffectedAccountID = null.Int{} },
+		fields: []string{"affectedAccountId"},
+	}, {
+		name:   "transfer into the same account",
+		base:   transfer,
+		modify: func(e *model.Entry) { e.AffectedAccountID = null.IntFrom(1) },
+		fields: []string{"affectedAccountId"},
+	}, {
+		name:   "income with affected account",
+		base:   income,
+		modify: func(e *model.Entry) { e.AffectedAccountID = null.IntFrom(2) },
+		fields: []string{"affectedAccountId"},
+	}, {
+		name: "transfer with negative affected amount",
+		base: transfer,
+		modify: func(e *model.Entry) {
+			e.AffectedAmount = decimal.NullDecimal{Decimal: decimal.New(-7, 0), Valid: true}
+		},
+		fields: []string{"affectedAmount"},
+	}, {
+		name: "split with negative amount",
+		base: income,
+		modify: func(e *model.Entry) {
+			e.Splits = []model.EntrySplit{
+				{Amount: decimal.New(150, 0)},
+				{Amount: decimal.New(-50, 0)},
+			}
+		},
+		fields: []string{"splits[1].amount"},
+	}, {
+		name:   "several invalid fields",
+		base:   model.Entry{Type: 9, Amount: decimal.New(-1, 0)},
+		fields: []string{"accountId", "amount", "date", "type"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry := test.base
+			if test.modify != nil {
+				test.modify(&entry)
+			}
+
+			checkInvalidFields(t, validateEntry(entry), test.fields)
+		})
+	}
+}
+
+func TestValidateRecurring(t *testing.T) {
+	monthly := model.Recurring{
+		AccountID: 1,
+		Type:      2,
+		Amount:    decimal.New(100, 0),
+		Frequency: "monthly",
+		Every:     1,
+		StartDate: "2020-01-31",
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*model.Recurring)
+		fields []string
+	}{{
+		name: "valid",
+	}, {
+		name:   "valid with business day rule",
+		modify: func(r *model.Recurring) { r.DayRule = ruleLastBusinessDay },
+	}, {
+		name:   "expense with affected account",
+		modify: func(r *model.Recurring) { r.AffectedAccountID = null.IntFrom(2) },
+		fields: []string{"affectedAccountId"},
+	}, {
+		name: "transfer into the same account",
+		modify: func(r *model.Recurring) {
+			r.Type = 3
+			r.AffectedAccountID = null.IntFrom(1)
+		},
+		fields: []string{"affectedAccountId"},
+	}, {
+		name: "description too long",
+		modify: func(r *model.Recurring) {
+			r.Description = null.StringFrom(strings.Repeat("a", maxEntryDescriptionLength+1))
+		},
+		fields: []string{"description"},
+	}, {
+		name:   "invalid start date",
+		modify: func(r *model.Recurring) { r.StartDate = "31/01/2020" },
+		fields: []string{"startDate"},
+	}, {
+		name:   "end date before start date",
+		modify: func(r *model.Recurring) { r.EndDate = null.StringFrom("2020-01-01") },
+		fields: []string{"endDate"},
+	}, {
+		name: "business day rule for daily entry",
+		modify: func(r *model.Recurring) {
+			r.Frequency = "daily"
+			r.DayRule = ruleNextBusinessDay
+		},
+		fields: []string{"dayRule"},
+	}, {
+		name: "last day rule for weekly entry",
+		modify: func(r *model.Recurring) {
+			r.Frequency = "weekly"
+			r.DayRule = ruleLastDay
+		},
+		fields: []string{"dayRule"},
+	}, {
+		name: "invalid schedule",
+		modify: func(r *model.Recurring) {
+			r.Frequency = "hourly"
+			r.Every = -1
+			r.MaxCount = null.IntFrom(0)
+		},
+		fields: []string{"every", "frequency", "maxCount"},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := monthly
+			if test.modify != nil {
+				test.modify(&rec)
+			}
+
+			checkInvalidFields(t, validateRecurring(rec), test.fields)
+		})
+	}
+}
+
+func TestMustValidEntry(t *testing.T) {
+	defer func() {
+		err, ok := recover().(*apierr.Error)
+		if !ok {
+			t.Fatalf("expected panic with *apierr.Error")
+		}
+
+		if err.Code != apierr.CodeValidation || err.Status() != 400 {
+			t.Errorf("expected validation error with status 400, got %s (%d)", err.Code, err.Status())
+		}
+
+		if _, exist := err.Fields["amount"]; !exist {
+			t.Errorf("expected message for amount, got %v", err.Fields)
+		}
+	}()
+
+	mustValidEntry(model.Entry{AccountID: 1, Type: 1, Date: "2020-01-01"})
+}
+
+// checkInvalidFields makes sure fields contain message
+// only for the expected invalid fields.
+func checkInvalidFields(t *testing.T, fields map[string]string, expected []string) {
+	t.Helper()
+
+	names := []string{}
+	for name, message := range fields {
+		names = append(names, name)
+		if message == "" {
+			t.Errorf("field %s doesn't have message", name)
+		}
+	}
+	sort.Strings(names)
+
+	if expected == nil {
+		expected = []string{}
+	}
+
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected invalid fields %v, got %v (%v)", expected, names, fields)
+	}
+}
+
+func TestValidateImportRows(t *testing.T) {
+	entry := model.Entry{Type: 2, Amount: decimal.New(100, 0), Date: "2020-01-31"}
+	long := entry
+	long.Description = null.StringFrom(strings.Repeat("a", maxEntryDescriptionLength+1))
+	transfer := entry
+	transfer.Type = 3
+
+	rows := []model.ImportRow{
+		{Line: 2, Entry: entry},
+		{Line: 3, Entry: long},
+		{Line: 4, Entry: transfer},
+		{Line: 5, Error: "amount is not valid"},
+	}
+
+	validateImportRows(rows)
+
+	expected := []string{"", "description must not longer than 150 characters", "", "amount is not valid"}
+	for i, row := range rows {
+		if row.Error != expected[i] {
+			t.Errorf("line %d: expected error %q, got %q", row.Line, expected[i], row.Error)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Package apierr contains errors that returned to client of the API. Each
+// error has a code which decides the HTTP status of the response, so client
+// can tell apart a missing data, an invalid request and a server failure.
+package apierr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Code is the kind of error.
+type Code string
+
+// List of error codes.
+const (
+	CodeValidation   Code = "validation"
+	CodeUnauthorized Code = "unauthorized"
+	CodeForbidden    Code = "forbidden"
+	CodeNotFound     Code = "not_found"
+	CodeConflict     Code = "conflict"
+	CodeRateLimited  Code = "rate_limited"
+	CodeInternal     Code = "internal"
+)
+
+// Error is error that can be returned to client. Fields contains message
+// for each invalid field in request, which is used by validation error.
+type Error struct {
+	Code    Code              `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Status returns HTTP status code for the error.
+func (e *Error) Status() int {
+	switch e.Code {
+	case CodeValidation:
+		return http.StatusBadRequest
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeForbidden:
+		return http.StatusForbidden
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeConflict:
+		return http.StatusConflict
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// WithField marks the field as the cause of error.
+func (e *Error) WithField(name string) *Error {
+	if e.Fields == nil {
+		e.Fields = make(map[string]string)
+	}
+
+	e.Fields[name] = e.Message
+	return e
+}
+
+// Validation returns error for request that has invalid data.
+func Validation(format string, args ...interface{}) *Error {
+	return newError(CodeValidation, format, args...)
+}
+
+// InvalidFields returns validation error for several invalid fields,
+// where fields maps name of the field to its message.
+func InvalidFields(fields map[string]string) *Error {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	messages := make([]string, len(names))
+	for i, name := range names {
+		messages[i] = fields[name]
+	}
+
+	return &Error{
+		Code:    CodeValidation,
+		Message: strings.Join(messages, ", "),
+		Fields:  fields,
+	}
+}
+
+// Unauthorized returns error for user that hasn't logged in.
+func Unauthorized(format string, args ...interface{}) *Error {
+	return newError(CodeUnauthorized, format, args...)
+}
+
+// Forbidden returns error for user that doesn't have permission.
+func Forbidden(format string, args ...interface{}) *Error {
+	return newError(CodeForbidden, format, args...)
+}
+
+// NotFound returns error for data that doesn't exist.
+func NotFound(format string, args ...interface{}) *Error {
+	return newError(CodeNotFound, format, args...)
+}
+
+// Conflict returns error for request that conflicts with the existing data.
+func Conflict(format string, args ...interface{}) *Error {
+	return newError(CodeConflict, format, args...)
+}
+
+// RateLimited returns error for client that sent too many requests.
+func RateLimited(format string, args ...interface{}) *Error {
+	return newError(CodeRateLimited, format, args...)
+}
+
+func newError(code Code, format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// From converts err into Error. Beside Error, it also recognizes errors
+// that caused by client, e.g. malformed JSON and duplicate data. The other
+// errors are treated as internal error. Errors from database might contain
+// details about the schema and query, so their message is not sent to
+// client and the original error should be logged instead.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr),
+		errors.As(err, &typeErr),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return Validation("request is not valid: %v", err)
+	case errors.Is(err, http.ErrNotMultipart):
+		return Validation("request must be a multipart form")
+	case errors.Is(err, http.ErrMissingFile):
+		return Validation("%v", err).WithField("file")
+	case isDuplicateError(err):
+		return Conflict("data already exists")
+	}
+
+	return newError(CodeInternal, "internal server error")
+}
+
+// isDuplicateError checks if err is caused by violation of unique constraint.
+// The drivers have their own error type, so here we just check the message.
+func isDuplicateError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "Error 1062") || // MySQL
+		strings.Contains(msg, "UNIQUE constraint failed") || // SQLite
+		strings.Contains(msg, "duplicate key value violates unique constraint") // PostgreSQL
+}
+
+// Write writes the error as JSON with its HTTP status.
+func Write(w http.ResponseWriter, err *Error) {
+	// Remove headers that might be set before error happened
+	w.Header().Del("Content-Encoding")
+	w.Header().Del("Content-Disposition")
+
+	if err.Fields == nil {
+		err.Fields = map[string]string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.Status())
+	json.NewEncoder(w).Encode(err)
+}
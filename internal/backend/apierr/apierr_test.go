@@ -0,0 +1,44 @@
+package apierr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    Code
+		message string
+	}{{
+		name:    "api error is kept",
+		err:     fmt.Errorf("wrapped: %w", NotFound("account doesn't exist")),
+		code:    CodeNotFound,
+		message: "account doesn't exist",
+	}, {
+		name:    "duplicate in SQLite",
+		err:     errors.New("UNIQUE constraint failed: user.username"),
+		code:    CodeConflict,
+		message: "data already exists",
+	}, {
+		name:    "duplicate in PostgreSQL",
+		err:     errors.New(`pq: duplicate key value violates unique constraint "entry_import_id_UNIQUE"`),
+		code:    CodeConflict,
+		message: "data already exists",
+	}, {
+		name:    "database error is hidden",
+		err:     errors.New(`no such column: e.secret in "SELECT e.secret FROM entry e"`),
+		code:    CodeInternal,
+		message: "internal server error",
+	}}
+
+	for _, test := range tests {
+		got := From(test.err)
+		if got.Code != test.code || got.Message != test.message {
+			t.Errorf("%s: expected %s %q, got %s %q",
+				test.name, test.code, test.message, got.Code, got.Message)
+		}
+	}
+}
@@ -1,16 +1,32 @@
 package auth
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
 	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/gofrs/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Durations that used to manage the sessions. Last seen time is only updated
+// once in a while, so database store doesn't need to be written on every request.
+const (
+	sessionDuration        = 3 * time.Hour
+	sessionCleanupInterval = 10 * time.Minute
+	lastSeenInterval       = time.Minute
+)
+
 // AuthenticationRules is function to check whether
 // an user allowed to access an URL.
 type AuthenticationRules func(user model.User, method, url string) bool
@@ -18,26 +34,66 @@ type AuthenticationRules func(user model.User, method, url string) bool
 // Authenticator is object to authenticate a http request.
 // It also handles login and logout.
 type Authenticator struct {
-	db             *sqlx.DB
-	sessionManager *SessionManager
-	rules          AuthenticationRules
+	db       *sqlx.DB
+	sessions SessionStore
+	rules    AuthenticationRules
+	totp     TOTP
+
+	// Pending logins are logins whose password already
+	// verified, but still waiting for the TOTP code.
+	pendingLock   sync.Mutex
+	pendingLogins map[string]pendingLogin
+
+	// Limiter for failed logins, to slow down password guessing
+	limiter *loginLimiter
 }
 
-// NewAuthenticator returns new Authenticator
-func NewAuthenticator(db *sqlx.DB, rules AuthenticationRules) (*Authenticator, error) {
+// dummyPasswordHash is compared with the password when user doesn't exist,
+// so the response time doesn't tell whether the username is registered.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("duit"), 10)
+
+// NewAuthenticator returns new Authenticator which saves its sessions
+// in the specified store, using login limits from the config.
+func NewAuthenticator(db *sqlx.DB, sessions SessionStore, rules AuthenticationRules, config model.Config) (*Authenticator, error) {
 	// Create authenticator
 	auth := new(Authenticator)
 	auth.db = db
-	auth.sessionManager = NewSessionManager(3*time.Hour, 10*time.Minute)
+	auth.sessions = sessions
 	auth.rules = rules
+	auth.totp = DefaultTOTP
+	auth.pendingLogins = make(map[string]pendingLogin)
+	auth.limiter = newLoginLimiter(
+		config.LoginMaxAttempts,
+		config.LoginMaxAttemptsPerIP,
+		time.Duration(config.LoginLockoutMinutes)*time.Minute)
+
+	go auth.cleanUpExpiredSessions()
 
 	return auth, nil
 }
 
+// LoginResult is the result of login. If user has enabled two-factor
+// authentication, the login is not finished yet. In that case Session
+// will be empty, and PendingToken must be sent with the TOTP code to
+// LoginTwoFactor to get the session.
+type LoginResult struct {
+	Session      string
+	User         model.User
+	PendingToken string
+}
+
 // Login verify that username and password match,
-// generate session ID then save it to cache.
-func (auth *Authenticator) Login(username, password string) (string, model.User, error) {
-	emptyUser := model.User{}
+// generate session ID then save it to session store.
+// Failed logins are limited for each username and IP
+// address, and they all return the same error.
+func (auth *Authenticator) Login(r *http.Request, username, password string) (LoginResult, error) {
+	// Make sure login is not limited. Until it's finished,
+	// this login is counted as failure by the limiter.
+	attempt, wait := auth.limiter.reserve(username, clientIP(r))
+	if wait > 0 {
+		return LoginResult{}, loginLimitedError(wait)
+	}
+	defer attempt.release()
 
 	// Start transaction
 	// We only use it to fetch the data,
@@ -48,80 +104,260 @@ func (auth *Authenticator) Login(username, password string) (string, model.User,
 	// Prepare statements
 	stmtGetUser, err := tx.Preparex(`
 		SELECT id, username, name, password, admin
-		FROM user WHERE username = ?`)
+		FROM "user" WHERE username = ?`)
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("failed to prepare query: %w", err)
+	}
+
+	stmtGetTwoFactor, err := tx.Preparex(`
+		SELECT COUNT(*) FROM user_totp
+		WHERE user_id = ? AND enabled = TRUE`)
 	if err != nil {
-		return "", emptyUser, fmt.Errorf("failed to prepare query: %w", err)
+		return LoginResult{}, fmt.Errorf("failed to prepare query: %w", err)
 	}
 
 	// Fetch user from database
 	var user model.User
 	err = stmtGetUser.Get(&user, username)
 	if err != nil && err != sql.ErrNoRows {
-		return "", emptyUser, fmt.Errorf("failed to get user: %w", err)
+		return LoginResult{}, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	if err == sql.ErrNoRows {
-		return "", emptyUser, fmt.Errorf("user doesn't exist")
+		user.Password = string(dummyPasswordHash)
 	}
 
 	// Make sure its password matched.
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
-	if err != nil {
-		return "", emptyUser, fmt.Errorf("username and password don't match")
+	if err != nil || user.ID == 0 {
+		attempt.fail()
+		return LoginResult{}, apierr.Unauthorized("username or password is not valid")
 	}
 
-	// Save user to session manager
-	expTime := time.Duration(0)
-	session, err := auth.sessionManager.RegisterUser(user, expTime)
+	// Check whether user has enabled two-factor authentication
+	var nTwoFactor int
+	err = stmtGetTwoFactor.Get(&nTwoFactor, user.ID)
 	if err != nil {
-		return "", emptyUser, fmt.Errorf("failed to register user: %w", err)
+		return LoginResult{}, fmt.Errorf("failed to get two-factor status: %w", err)
 	}
 
+	// The transaction is only used to fetch user, so end it now. In SQLite
+	// every transaction is a writer, so saving session to database store
+	// while it's still open will fail because database is locked.
+	tx.Rollback()
 	user.Password = ""
-	return session, user, nil
+
+	// If two-factor is enabled, wait for the TOTP code first
+	if nTwoFactor > 0 {
+		token, err := auth.startPendingLogin(user)
+		if err != nil {
+			return LoginResult{}, err
+		}
+
+		return LoginResult{PendingToken: token}, nil
+	}
+
+	// Save user to session store
+	session, err := auth.createSession(r, user)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	attempt.succeed()
+	return LoginResult{Session: session, User: user}, nil
+}
+
+// UnlockUser removes the lock caused by failed logins of the user.
+func (auth *Authenticator) UnlockUser(userID int64) error {
+	var username string
+	err := auth.db.Get(&username, `SELECT username FROM "user" WHERE id = ?`, userID)
+	if err == sql.ErrNoRows {
+		return apierr.NotFound("user doesn't exist")
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	auth.limiter.unlock(username)
+	return nil
+}
+
+// loginLimitedError returns error for login that must wait before retried.
+func loginLimitedError(wait time.Duration) error {
+	seconds := int64((wait + time.Second - 1) / time.Second)
+	return apierr.RateLimited("too many failed logins, please try again in %d seconds", seconds)
+}
+
+// createSession creates a new session for user and saves it to session store.
+func (auth *Authenticator) createSession(r *http.Request, user model.User) (string, error) {
+	sessionID, err := uuid.NewV4()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	now := time.Now()
+	session := sessionID.String()
+	err = auth.sessions.Save(Session{
+		ID:        hashSessionID(session),
+		User:      user,
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpTime:   now.Add(sessionDuration),
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return session, nil
 }
 
 // Logout invalidates session
 func (auth *Authenticator) Logout(r *http.Request) error {
 	session := auth.GetSessionFromRequest(r)
 	if session == "" {
-		return fmt.Errorf("session has been expired")
+		return apierr.Unauthorized("session has been expired")
 	}
 
-	auth.sessionManager.RemoveUserSession(session)
-	return nil
+	if isAPIToken(session) {
+		return apierr.Validation("API token can't be logged out, revoke it instead")
+	}
+
+	return auth.RevokeSession(hashSessionID(session))
 }
 
 // MassLogout invalidates all sessions for an user.
-func (auth *Authenticator) MassLogout(username string) {
-	auth.sessionManager.RemoveUsername(username)
+func (auth *Authenticator) MassLogout(userID int64) error {
+	err := auth.sessions.RemoveUser(userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove sessions: %w", err)
+	}
+
+	return nil
 }
 
-// AuthenticateUser checks whether the session is still valid.
-// If yes, prolong its expiration time as well.
-func (auth *Authenticator) AuthenticateUser(r *http.Request) error {
+// CurrentSession returns the session that used by the request.
+func (auth *Authenticator) CurrentSession(r *http.Request) (Session, error) {
 	// Get session from request
 	session := auth.GetSessionFromRequest(r)
 	if session == "" {
-		return fmt.Errorf("session has been expired")
+		return Session{}, apierr.Unauthorized("session has been expired")
+	}
+
+	// API token is saved in its own table instead of session store
+	if isAPIToken(session) {
+		data, found, err := auth.getTokenSession(session)
+		if err != nil {
+			return Session{}, err
+		}
+
+		if !found {
+			return Session{}, apierr.Unauthorized("token is not valid")
+		}
+
+		return data, nil
+	}
+
+	// Get data from session store
+	data, found, err := auth.GetSession(hashSessionID(session))
+	if err != nil {
+		return Session{}, err
 	}
 
-	// Get data from session manager
-	user, expTime, found := auth.sessionManager.GetUser(session)
 	if !found {
-		return fmt.Errorf("session has been expired")
+		return Session{}, apierr.Unauthorized("session has been expired")
+	}
+
+	return data, nil
+}
+
+// GetSession returns the active session with specified ID.
+func (auth *Authenticator) GetSession(id string) (Session, bool, error) {
+	session, found, err := auth.sessions.Get(id)
+	if err != nil {
+		return Session{}, false, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if !found || time.Now().After(session.ExpTime) {
+		return Session{}, false, nil
+	}
+
+	return session, true, nil
+}
+
+// UserSessions returns the active sessions of an user.
+func (auth *Authenticator) UserSessions(userID int64) ([]Session, error) {
+	sessions, err := auth.sessions.List(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	now := time.Now()
+	activeSessions := []Session{}
+	for _, session := range sessions {
+		if !now.After(session.ExpTime) {
+			activeSessions = append(activeSessions, session)
+		}
+	}
+
+	return activeSessions, nil
+}
+
+// RevokeSession invalidates the session with specified ID.
+func (auth *Authenticator) RevokeSession(id string) error {
+	err := auth.sessions.Remove(id)
+	if err != nil {
+		return fmt.Errorf("failed to remove session: %w", err)
 	}
 
+	return nil
+}
+
+// AuthenticateUser checks whether the session is still valid.
+// If yes, prolong its expiration time as well.
+func (auth *Authenticator) AuthenticateUser(r *http.Request) error {
+	data, err := auth.CurrentSession(r)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user := data.User
+
 	// Check whether this user has permission to access the URL
 	if auth.rules != nil {
 		if allowed := auth.rules(user, r.Method, r.URL.Path); !allowed {
-			return fmt.Errorf("user doesn't have permission to access")
+			return apierr.Forbidden("user doesn't have permission to access")
+		}
+	}
+
+	// API token is limited by its scope as well. Since it doesn't
+	// expire, only its last used time need to be updated.
+	if data.TokenID != 0 {
+		if !scopeAllows(data.Scope, r.Method, r.URL.Path) {
+			return apierr.Forbidden("token doesn't have permission to access")
+		}
+
+		if now.Sub(data.LastSeen) >= lastSeenInterval {
+			return auth.touchToken(data.TokenID, now)
 		}
+
+		return nil
 	}
 
-	// If session almost expired, prolong it
-	if expTime.Sub(time.Now()).Hours() < 1 && user.ID != 0 {
-		auth.sessionManager.ProlongUserSession(session, 0)
+	// If session almost expired, prolong it. While at it, update its last seen time.
+	expTime := data.ExpTime
+	if expTime.Sub(now).Hours() < 1 && user.ID != 0 {
+		expTime = expTime.Add(sessionDuration)
+	}
+
+	if expTime != data.ExpTime || now.Sub(data.LastSeen) >= lastSeenInterval {
+		err = auth.sessions.Touch(data.ID, now, expTime)
+		if err != nil {
+			return fmt.Errorf("failed to update session: %w", err)
+		}
 	}
 
 	return nil
@@ -136,7 +372,8 @@ func (auth *Authenticator) MustAuthenticateUser(r *http.Request) {
 }
 
 // GetSessionFromRequest as its name implies, will get the
-// session ID from http request.
+// session ID from http request. Scripts can also send the
+// session or API token using "Authorization: Bearer" header.
 func (auth *Authenticator) GetSessionFromRequest(r *http.Request) string {
 	// Get session from header and cookie
 	headerSession := r.Header.Get("X-Session-Duit")
@@ -154,5 +391,49 @@ func (auth *Authenticator) GetSessionFromRequest(r *http.Request) string {
 		session = cookieSession
 	}
 
+	// Bearer token is only used when there are no session
+	authorization := r.Header.Get("Authorization")
+	if session == "" && strings.HasPrefix(authorization, "Bearer ") {
+		session = strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+	}
+
 	return session
 }
+
+// cleanUpExpiredSessions periodically removes the expired sessions from
+// session store. It never returns, so it should be run in its own goroutine.
+func (auth *Authenticator) cleanUpExpiredSessions() {
+	for {
+		time.Sleep(sessionCleanupInterval)
+
+		err := auth.sessions.RemoveExpired(time.Now())
+		if err != nil {
+			logrus.Errorln("failed to remove expired sessions:", err)
+		}
+
+		auth.removeExpiredPendingLogins()
+		auth.limiter.removeExpired()
+	}
+}
+
+// hashSessionID returns the hex encoded SHA-256 hash of session token, which
+// used as the ID of session. Session token is a random UUID, so unlike
+// password it doesn't need slow hash like bcrypt.
+func hashSessionID(session string) string {
+	return sha256Hex(session)
+}
+
+// sha256Hex returns the hex encoded SHA-256 hash of str.
+func sha256Hex(str string) string {
+	hash := sha256.Sum256([]byte(str))
+	return hex.EncodeToString(hash[:])
+}
+
+// clientIP returns IP address of the client that sent the request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default settings for login limiter, used when they are not set in config.
+const (
+	DefaultLoginMaxAttempts      = 5
+	DefaultLoginMaxAttemptsPerIP = 20
+	DefaultLoginLockout          = 15 * time.Minute
+)
+
+// maxLoginBackoff is the longest delay between failed logins
+// before the threshold is reached and the login is locked.
+const maxLoginBackoff = time.Minute
+
+// loginFailures is the failed logins of an username or IP address.
+// Logins that are still being verified are counted as pending, so
+// concurrent guesses can't pass the limiter before any of them failed.
+type loginFailures struct {
+	count       int
+	pending     int
+	lastFailure time.Time
+	lastAttempt time.Time
+}
+
+// loginLimiter tracks failed logins for each username and IP address. After
+// each failure, the next login must wait for a delay that doubled every
+// time, and once the failures reached the threshold the login is locked
+// until lockout duration passed. Failures are forgotten after the lockout
+// duration passed since the last one.
+type loginLimiter struct {
+	sync.Mutex
+
+	maxAttempts      int
+	maxAttemptsPerIP int
+	lockout          time.Duration
+	failures         map[string]loginFailures
+
+	// Now returns the current time. It can be
+	// replaced with fake clock in test.
+	now func() time.Time
+}
+
+// loginAttempt is a login allowed by the limiter. Until it's finished,
+// it's counted as pending failure for its username and IP address.
+type loginAttempt struct {
+	limiter  *loginLimiter
+	userKey  string
+	ipKey    string
+	finished bool
+}
+
+func newLoginLimiter(maxAttempts, maxAttemptsPerIP int, lockout time.Duration) *loginLimiter {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultLoginMaxAttempts
+	}
+
+	if maxAttemptsPerIP <= 0 {
+		maxAttemptsPerIP = DefaultLoginMaxAttemptsPerIP
+	}
+
+	if lockout <= 0 {
+		lockout = DefaultLoginLockout
+	}
+
+	return &loginLimiter{
+		maxAttempts:      maxAttempts,
+		maxAttemptsPerIP: maxAttemptsPerIP,
+		lockout:          lockout,
+		failures:         make(map[string]loginFailures),
+		now:              time.Now,
+	}
+}
+
+// reserve checks whether login for username from the IP address is allowed.
+// If it is, the login is counted as pending failure in the same lock and
+// returned, so it must be finished by calling fail, succeed or release.
+// If it's not, it returns how long the login must wait before retried.
+func (l *loginLimiter) reserve(username, ip string) (*loginAttempt, time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	attempt := &loginAttempt{
+		limiter: l,
+		userKey: usernameKey(username),
+		ipKey:   ipKey(ip),
+	}
+
+	now := l.now()
+	userWait := l.waitKey(attempt.userKey, l.maxAttempts, now)
+	ipWait := l.waitKey(attempt.ipKey, l.maxAttemptsPerIP, now)
+	if ipWait > userWait {
+		userWait = ipWait
+	}
+
+	if userWait > 0 {
+		return nil, userWait
+	}
+
+	for _, key := range []string{attempt.userKey, attempt.ipKey} {
+		failures := l.failures[key]
+		if now.Sub(failures.lastFailure) >= l.lockout {
+			failures.count = 0
+		}
+
+		failures.pending++
+		failures.lastAttempt = now
+		l.failures[key] = failures
+	}
+
+	return attempt, 0
+}
+
+// unlock forgets the failed logins for username. It's used when admin
+// unlocks the user. The failures from IP address are kept, so an attacker
+// can't reset it by logging in to its own account between the guesses.
+func (l *loginLimiter) unlock(username string) {
+	l.Lock()
+	defer l.Unlock()
+
+	delete(l.failures, usernameKey(username))
+}
+
+// removeExpired forgets the failures that already passed the lockout
+// duration. Pending logins are never that long, so they are removed
+// as well in case one of them is never finished.
+func (l *loginLimiter) removeExpired() {
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	for key, failures := range l.failures {
+		if now.Sub(failures.lastAttempt) >= l.lockout {
+			delete(l.failures, key)
+		}
+	}
+}
+
+func (l *loginLimiter) waitKey(key string, maxAttempts int, now time.Time) time.Duration {
+	failures, exist := l.failures[key]
+	if !exist || now.Sub(failures.lastAttempt) >= l.lockout {
+		return 0
+	}
+
+	count := failures.pending
+	if now.Sub(failures.lastFailure) < l.lockout {
+		count += failures.count
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	// Once threshold reached, lock until lockout duration passed.
+	// Before that, delay is doubled for every failure.
+	delay := l.lockout
+	if count < maxAttempts {
+		delay = time.Second
+		for i := 1; i < count && delay < maxLoginBackoff; i++ {
+			delay *= 2
+		}
+
+		if delay > maxLoginBackoff {
+			delay = maxLoginBackoff
+		}
+	}
+
+	if wait := failures.lastAttempt.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// fail finishes the login attempt as failure.
+func (a *loginAttempt) fail() {
+	a.finish(func(l *loginLimiter, key string, failures loginFailures) {
+		failures.count++
+		failures.lastFailure = l.now()
+		failures.lastAttempt = failures.lastFailure
+		l.failures[key] = failures
+	})
+}
+
+// succeed finishes the login attempt as success, which forgets the failed
+// logins of the username. Like unlock, the failures of IP address are kept.
+func (a *loginAttempt) succeed() {
+	a.finish(func(l *loginLimiter, key string, failures loginFailures) {
+		if key == a.userKey {
+			delete(l.failures, key)
+		} else {
+			l.failures[key] = failures
+		}
+	})
+}
+
+// release finishes the login attempt without counting it as failure, e.g.
+// when the password is correct but two-factor code is still needed, or
+// when it can't be verified because of server error. It does nothing if
+// the attempt is already finished, so it's safe to be deferred.
+func (a *loginAttempt) release() {
+	a.finish(func(l *loginLimiter, key string, failures loginFailures) {
+		l.failures[key] = failures
+	})
+}
+
+// finish removes the pending state of the login attempt from its username
+// and IP address, then let fn decide how the failures are saved.
+func (a *loginAttempt) finish(fn func(l *loginLimiter, key string, failures loginFailures)) {
+	l := a.limiter
+	l.Lock()
+	defer l.Unlock()
+
+	if a.finished {
+		return
+	}
+	a.finished = true
+
+	for _, key := range []string{a.userKey, a.ipKey} {
+		failures := l.failures[key]
+		if failures.pending > 0 {
+			failures.pending--
+		}
+
+		if failures.pending == 0 {
+			failures.lastAttempt = failures.lastFailure
+		}
+
+		fn(l, key, failures)
+		if failures, exist := l.failures[key]; exist && failures.count == 0 && failures.pending == 0 {
+			delete(l.failures, key)
+		}
+	}
+}
+
+func usernameKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+)
+
+func TestLoginLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	limiter := newLoginLimiter(3, 10, 15*time.Minute)
+	limiter.now = clock.Now
+
+	// Delay is doubled for every failure
+	for i, expected := range []time.Duration{time.Second, 2 * time.Second} {
+		attempt, wait := limiter.reserve("John", "192.0.2.1")
+		if wait != 0 {
+			t.Fatalf("failure %d: expected no wait before login, got %s", i+1, wait)
+		}
+		attempt.fail()
+
+		if _, wait = limiter.reserve("john", "192.0.2.2"); wait != expected {
+			t.Errorf("failure %d: expected wait %s, got %s", i+1, expected, wait)
+		}
+		clock.Add(expected)
+	}
+
+	// After threshold reached, user is locked
+	attempt, _ := limiter.reserve("john", "192.0.2.1")
+	attempt.fail()
+	if _, wait := limiter.reserve("john", "192.0.2.2"); wait != 15*time.Minute {
+		t.Errorf("expected user to be locked, got wait %s", wait)
+	}
+
+	// Unlock only forgets the user, not the IP address
+	limiter.unlock("john")
+	attempt, wait := limiter.reserve("john", "192.0.2.2")
+	if wait != 0 {
+		t.Fatalf("expected unlocked user to not wait, got %s", wait)
+	}
+	attempt.succeed()
+
+	if _, wait := limiter.reserve("jane", "192.0.2.1"); wait != 4*time.Second {
+		t.Errorf("expected IP address to wait 4s, got %s", wait)
+	}
+
+	// Failures are forgotten after lockout duration passed
+	clock.Add(15 * time.Minute)
+	limiter.removeExpired()
+	if len(limiter.failures) != 0 {
+		t.Errorf("expected expired failures to be removed, got %v", limiter.failures)
+	}
+}
+
+func TestLoginLimiterConcurrent(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	limiter := newLoginLimiter(5, 20, 15*time.Minute)
+	limiter.now = clock.Now
+
+	// While a login is being verified, the other ones must wait
+	// as if it already failed, so only one of them is allowed.
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var attempts []*loginAttempt
+	start := make(chan struct{})
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if attempt, wait := limiter.reserve("john", "192.0.2.1"); wait == 0 {
+				mutex.Lock()
+				attempts = append(attempts, attempt)
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	if len(attempts) != 1 {
+		t.Fatalf("expected only one concurrent login to be allowed, got %d", len(attempts))
+	}
+
+	// Once released, e.g. because two-factor code is still needed,
+	// login is allowed right away again.
+	attempts[0].release()
+	attempt, wait := limiter.reserve("john", "192.0.2.1")
+	if wait != 0 {
+		t.Fatalf("expected released login to not wait, got %s", wait)
+	}
+
+	// Finishing the attempt more than once doesn't count it twice
+	attempt.fail()
+	attempt.fail()
+	attempt.release()
+	if failures := limiter.failures[usernameKey("john")]; failures.count != 1 || failures.pending != 0 {
+		t.Errorf("expected one failure and no pending login, got %+v", failures)
+	}
+}
+
+func TestLoginConcurrent(t *testing.T) {
+	auth, _ := newTestAuthenticator(t)
+
+	// Concurrent wrong passwords can't skip the backoff
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var nUnauthorized, nLimited int
+	start := make(chan struct{})
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := auth.Login(httptest.NewRequest("POST", "/api/login", nil), "john", "wrong")
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			switch {
+			case isAPIError(err, apierr.CodeUnauthorized):
+				nUnauthorized++
+			case isAPIError(err, apierr.CodeRateLimited):
+				nLimited++
+			default:
+				t.Errorf("unexpected error %v", err)
+			}
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	if nUnauthorized != 1 || nLimited != 19 {
+		t.Errorf("expected 1 password check and 19 limited logins, got %d and %d",
+			nUnauthorized, nLimited)
+	}
+}
+
+func TestLoginFailure(t *testing.T) {
+	auth, clock := newTestAuthenticator(t)
+	r := httptest.NewRequest("POST", "/api/login", nil)
+
+	// Unknown user and wrong password return the same error
+	_, errUnknown := auth.Login(r, "jane", "secret")
+	clock.Add(maxLoginBackoff)
+	_, errPassword := auth.Login(r, "john", "wrong")
+	if !isAPIError(errUnknown, apierr.CodeUnauthorized) ||
+		!isAPIError(errPassword, apierr.CodeUnauthorized) ||
+		errUnknown.Error() != errPassword.Error() {
+		t.Fatalf("expected same unauthorized error, got %v and %v", errUnknown, errPassword)
+	}
+
+	// Login is rejected while waiting for backoff, even with correct password
+	if _, err := auth.Login(r, "john", "secret"); !isAPIError(err, apierr.CodeRateLimited) {
+		t.Fatalf("expected rate limited login, got %v", err)
+	}
+
+	// Admin can unlock the user
+	for i := 0; i < DefaultLoginMaxAttempts; i++ {
+		clock.Add(maxLoginBackoff)
+		auth.Login(r, "john", "wrong")
+	}
+
+	clock.Add(maxLoginBackoff)
+	if _, err := auth.Login(r, "john", "secret"); !isAPIError(err, apierr.CodeRateLimited) {
+		t.Fatalf("expected locked user, got %v", err)
+	}
+
+	err := auth.UnlockUser(1)
+	if err != nil {
+		t.Fatalf("failed to unlock user: %v", err)
+	}
+
+	if result, err := auth.Login(r, "john", "secret"); err != nil || result.Session == "" {
+		t.Fatalf("expected session after unlock, got %+v (%v)", result, err)
+	}
+
+	if err = auth.UnlockUser(100); !isAPIError(err, apierr.CodeNotFound) {
+		t.Errorf("expected unknown user to be not found, got %v", err)
+	}
+}
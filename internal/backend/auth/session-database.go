@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Max length of client data that saved in database.
+const (
+	maxIPAddressLength = 45
+	maxUserAgentLength = 255
+)
+
+// DatabaseSessionStore is SessionStore that keeps the sessions in table
+// "session", so they persist when the server restarted. The times are
+// saved as Unix time to avoid the difference of date type between the
+// databases.
+type DatabaseSessionStore struct {
+	db *sqlx.DB
+}
+
+// sessionRow is a row of session joined with its user.
+type sessionRow struct {
+	ID        string `db:"id"`
+	UserID    int64  `db:"user_id"`
+	Username  string `db:"username"`
+	Name      string `db:"name"`
+	Admin     bool   `db:"admin"`
+	CreatedAt int64  `db:"created_at"`
+	LastSeen  int64  `db:"last_seen"`
+	ExpTime   int64  `db:"expires_at"`
+	IPAddress string `db:"ip_address"`
+	UserAgent string `db:"user_agent"`
+}
+
+const selectSessionQuery = `
+	SELECT s.id, s.user_id, u.username, u.name, u.admin, s.created_at,
+		s.last_seen, s.expires_at, s.ip_address, s.user_agent
+	FROM session s
+	JOIN "user" u ON u.id = s.user_id`
+
+// NewDatabaseSessionStore returns new DatabaseSessionStore.
+func NewDatabaseSessionStore(db *sqlx.DB) *DatabaseSessionStore {
+	return &DatabaseSessionStore{db: db}
+}
+
+// Save saves a new session.
+func (ds *DatabaseSessionStore) Save(session Session) error {
+	_, err := ds.db.Exec(`INSERT INTO session
+		(id, user_id, created_at, last_seen, expires_at, ip_address, user_agent)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		session.ID, session.User.ID,
+		session.CreatedAt.Unix(), session.LastSeen.Unix(), session.ExpTime.Unix(),
+		truncate(session.IPAddress, maxIPAddressLength),
+		truncate(session.UserAgent, maxUserAgentLength))
+	return err
+}
+
+// Get returns the session with specified ID.
+func (ds *DatabaseSessionStore) Get(id string) (Session, bool, error) {
+	var row sessionRow
+	err := ds.db.Get(&row, selectSessionQuery+` WHERE s.id = ?`, id)
+	if err == sql.ErrNoRows {
+		return Session{}, false, nil
+	}
+
+	if err != nil {
+		return Session{}, false, err
+	}
+
+	return row.session(), true, nil
+}
+
+// List returns all sessions of the user, sorted from the last seen.
+func (ds *DatabaseSessionStore) List(userID int64) ([]Session, error) {
+	rows := []sessionRow{}
+	err := ds.db.Select(&rows, selectSessionQuery+`
+		WHERE s.user_id = ?
+		ORDER BY s.last_seen DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, len(rows))
+	for i, row := range rows {
+		sessions[i] = row.session()
+	}
+
+	return sessions, nil
+}
+
+// Touch updates the last seen and expiration time of the session.
+func (ds *DatabaseSessionStore) Touch(id string, lastSeen, expTime time.Time) error {
+	_, err := ds.db.Exec(`UPDATE session
+		SET last_seen = ?, expires_at = ? WHERE id = ?`,
+		lastSeen.Unix(), expTime.Unix(), id)
+	return err
+}
+
+// Remove removes the session with specified ID.
+func (ds *DatabaseSessionStore) Remove(id string) error {
+	_, err := ds.db.Exec(`DELETE FROM session WHERE id = ?`, id)
+	return err
+}
+
+// RemoveUser removes all sessions of the user.
+func (ds *DatabaseSessionStore) RemoveUser(userID int64) error {
+	_, err := ds.db.Exec(`DELETE FROM session WHERE user_id = ?`, userID)
+	return err
+}
+
+// RemoveExpired removes sessions that already expired at the specified time.
+func (ds *DatabaseSessionStore) RemoveExpired(now time.Time) error {
+	_, err := ds.db.Exec(`DELETE FROM session WHERE expires_at < ?`, now.Unix())
+	return err
+}
+
+func (row sessionRow) session() Session {
+	session := Session{
+		ID:        row.ID,
+		CreatedAt: time.Unix(row.CreatedAt, 0),
+		LastSeen:  time.Unix(row.LastSeen, 0),
+		ExpTime:   time.Unix(row.ExpTime, 0),
+		IPAddress: row.IPAddress,
+		UserAgent: row.UserAgent,
+	}
+
+	session.User.ID = row.UserID
+	session.User.Username = row.Username
+	session.User.Name = row.Name
+	session.User.Admin = row.Admin
+	return session
+}
+
+// truncate cuts str so its length is not more than max characters,
+// which is how VARCHAR length is counted. Invalid UTF-8 is replaced
+// since some databases refuse to save it.
+func truncate(str string, max int) string {
+	str = strings.ToValidUTF8(str, "\uFFFD")
+
+	count := 0
+	for i := range str {
+		if count == max {
+			return str[:i]
+		}
+		count++
+	}
+	return str
+}
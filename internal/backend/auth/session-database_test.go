@@ -0,0 +1,24 @@
+package auth
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		str  string
+		max  int
+		want string
+	}{
+		{"127.0.0.1", 45, "127.0.0.1"},
+		{"Mozilla/5.0", 7, "Mozilla"},
+		{"ブラウザ/1.0", 3, "ブラウ"},
+		{"ブラウザ", 4, "ブラウザ"},
+		{"abc\xffdef", 5, "abc�d"},
+		{"", 10, ""},
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.str, tt.max); got != tt.want {
+			t.Errorf("truncate(%q, %d): want %q, got %q", tt.str, tt.max, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemorySessionStore is SessionStore that keeps the sessions in memory,
+// so all sessions will be lost when the server restarted.
+type MemorySessionStore struct {
+	sync.RWMutex
+
+	// Sessions is map of session ID and its data. Special mention for
+	// user session list, which is list of sessions that used by an user.
+	// Useful for mass logout.
+	sessions        map[string]Session
+	userSessionList map[int64][]string
+}
+
+// NewMemorySessionStore returns new MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions:        make(map[string]Session),
+		userSessionList: make(map[int64][]string),
+	}
+}
+
+// Save saves a new session.
+func (ms *MemorySessionStore) Save(session Session) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	userID := session.User.ID
+	ms.sessions[session.ID] = session
+	ms.userSessionList[userID] = append(ms.userSessionList[userID], session.ID)
+	return nil
+}
+
+// Get returns the session with specified ID.
+func (ms *MemorySessionStore) Get(id string) (Session, bool, error) {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	session, found := ms.sessions[id]
+	return session, found, nil
+}
+
+// List returns all sessions of the user, sorted from the last seen.
+func (ms *MemorySessionStore) List(userID int64) ([]Session, error) {
+	ms.RLock()
+	defer ms.RUnlock()
+
+	sessions := []Session{}
+	for _, id := range ms.userSessionList[userID] {
+		if session, found := ms.sessions[id]; found {
+			sessions = append(sessions, session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+
+	return sessions, nil
+}
+
+// Touch updates the last seen and expiration time of the session.
+func (ms *MemorySessionStore) Touch(id string, lastSeen, expTime time.Time) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	if session, found := ms.sessions[id]; found {
+		session.LastSeen = lastSeen
+		session.ExpTime = expTime
+		ms.sessions[id] = session
+	}
+
+	return nil
+}
+
+// Remove removes the session with specified ID.
+func (ms *MemorySessionStore) Remove(id string) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	delete(ms.sessions, id)
+	return nil
+}
+
+// RemoveUser removes all sessions of the user.
+func (ms *MemorySessionStore) RemoveUser(userID int64) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	for _, id := range ms.userSessionList[userID] {
+		delete(ms.sessions, id)
+	}
+
+	delete(ms.userSessionList, userID)
+	return nil
+}
+
+// RemoveExpired removes sessions that already expired at the specified time.
+func (ms *MemorySessionStore) RemoveExpired(now time.Time) error {
+	ms.Lock()
+	defer ms.Unlock()
+
+	for id, session := range ms.sessions {
+		if now.After(session.ExpTime) {
+			delete(ms.sessions, id)
+		}
+	}
+
+	// Remove the deleted sessions from user session list as well
+	for userID, ids := range ms.userSessionList {
+		activeIDs := []string{}
+		for _, id := range ids {
+			if _, found := ms.sessions[id]; found {
+				activeIDs = append(activeIDs, id)
+			}
+		}
+
+		if len(activeIDs) == 0 {
+			delete(ms.userSessionList, userID)
+		} else {
+			ms.userSessionList[userID] = activeIDs
+		}
+	}
+
+	return nil
+}
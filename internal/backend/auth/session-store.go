@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// Session is the data of a login session. ID is the hash of session token
+// that sent by client, so it can be shown to user without letting anyone
+// use it to login. For request that uses API token, the session is not
+// saved in store and only has the user, token ID and its scope.
+type Session struct {
+	ID        string     `json:"id"`
+	User      model.User `json:"-"`
+	CreatedAt time.Time  `json:"createdAt"`
+	LastSeen  time.Time  `json:"lastSeen"`
+	ExpTime   time.Time  `json:"expTime"`
+	IPAddress string     `json:"ipAddress"`
+	UserAgent string     `json:"userAgent"`
+	TokenID   int64      `json:"-"`
+	Scope     string     `json:"-"`
+}
+
+// SessionStore is storage for login sessions.
+type SessionStore interface {
+	// Save saves a new session.
+	Save(session Session) error
+
+	// Get returns the session with specified ID. If it doesn't exist,
+	// the returned bool will be false.
+	Get(id string) (Session, bool, error)
+
+	// List returns all sessions of the user, sorted from the last seen.
+	List(userID int64) ([]Session, error)
+
+	// Touch updates the last seen and expiration time of the session.
+	Touch(id string, lastSeen, expTime time.Time) error
+
+	// Remove removes the session with specified ID.
+	Remove(id string) error
+
+	// RemoveUser removes all sessions of the user. Used for mass logout.
+	RemoveUser(userID int64) error
+
+	// RemoveExpired removes sessions that already expired at the specified time.
+	RemoveExpired(now time.Time) error
+}
+
+// NewSessionStore returns SessionStore of the specified kind, which is
+// either "memory" or "database". Empty kind will use the memory store.
+func NewSessionStore(db *sqlx.DB, kind string) (SessionStore, error) {
+	switch kind {
+	case "", "memory":
+		return NewMemorySessionStore(), nil
+	case "database":
+		return NewDatabaseSessionStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown session store %q", kind)
+	}
+}
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/model"
+)
+
+// Scopes of API token.
+const (
+	ScopeReadOnly     = "read-only"
+	ScopeWriteEntries = "write-entries"
+	ScopeAdmin        = "admin"
+)
+
+// apiTokenPrefix is prefix of every API token, which
+// used to tell it apart from the session of login.
+const apiTokenPrefix = "duit_"
+
+// apiTokenSize is the size of random part of API token in bytes.
+const apiTokenSize = 32
+
+// maxTokenNameLength is the max length of API token's name.
+const maxTokenNameLength = 100
+
+// APIToken is a long-lived token that used by scripts to access the API.
+// Only the hash of the token is saved, so it can't be shown again later.
+type APIToken struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Scope     string     `json:"scope"`
+	CreatedAt time.Time  `json:"createdAt"`
+	LastUsed  *time.Time `json:"lastUsed"`
+}
+
+// apiTokenRow is a row of table api_token.
+type apiTokenRow struct {
+	ID        int64  `db:"id"`
+	Name      string `db:"name"`
+	Scope     string `db:"scope"`
+	CreatedAt int64  `db:"created_at"`
+	LastUsed  int64  `db:"last_used"`
+}
+
+// CreateToken creates a new API token for user. It returns the token data,
+// and the token itself which is only available this time.
+func (auth *Authenticator) CreateToken(user model.User, name, scope string) (APIToken, string, error) {
+	// Validate input
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return APIToken{}, "", apierr.Validation("name must not empty").WithField("name")
+	case len([]rune(name)) > maxTokenNameLength:
+		return APIToken{}, "", apierr.Validation("name must not be longer than %d characters", maxTokenNameLength).WithField("name")
+	}
+
+	switch scope {
+	case ScopeReadOnly, ScopeWriteEntries:
+	case ScopeAdmin:
+		if !user.Admin {
+			return APIToken{}, "", apierr.Forbidden("only admin can create token with admin scope")
+		}
+	default:
+		return APIToken{}, "", apierr.Validation("scope must be %s, %s or %s",
+			ScopeReadOnly, ScopeWriteEntries, ScopeAdmin).WithField("scope")
+	}
+
+	// Generate random token
+	buffer := make([]byte, apiTokenSize)
+	if _, err := rand.Read(buffer); err != nil {
+		return APIToken{}, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	token := apiTokenPrefix + hex.EncodeToString(buffer)
+	createdAt := time.Now()
+
+	// Save its hash to database
+	res, err := auth.db.Exec(`INSERT INTO api_token
+		(user_id, name, scope, token_hash, created_at, last_used)
+		VALUES (?, ?, ?, ?, ?, 0)`,
+		user.ID, name, scope, sha256Hex(token), createdAt.Unix())
+	if err != nil {
+		return APIToken{}, "", fmt.Errorf("failed to save token: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return APIToken{}, "", fmt.Errorf("failed to get token ID: %w", err)
+	}
+
+	return APIToken{
+		ID:        id,
+		Name:      name,
+		Scope:     scope,
+		CreatedAt: time.Unix(createdAt.Unix(), 0),
+	}, token, nil
+}
+
+// ListTokens returns API tokens of the user, sorted from the newest.
+func (auth *Authenticator) ListTokens(userID int64) ([]APIToken, error) {
+	rows := []apiTokenRow{}
+	err := auth.db.Select(&rows, `SELECT id, name, scope, created_at, last_used
+		FROM api_token WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tokens: %w", err)
+	}
+
+	tokens := make([]APIToken, len(rows))
+	for i, row := range rows {
+		tokens[i] = row.apiToken()
+	}
+
+	return tokens, nil
+}
+
+// RevokeToken removes API token of the user, so it can't be used anymore.
+func (auth *Authenticator) RevokeToken(userID, tokenID int64) error {
+	res, err := auth.db.Exec(`DELETE FROM api_token
+		WHERE id = ? AND user_id = ?`, tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove token: %w", err)
+	}
+
+	nDeleted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if nDeleted == 0 {
+		return apierr.NotFound("token doesn't exist")
+	}
+
+	return nil
+}
+
+// getTokenSession returns session for the API token. The session doesn't
+// have ID since it's not saved in session store.
+func (auth *Authenticator) getTokenSession(token string) (Session, bool, error) {
+	var row struct {
+		ID       int64  `db:"id"`
+		Scope    string `db:"scope"`
+		LastUsed int64  `db:"last_used"`
+		UserID   int64  `db:"user_id"`
+		Username string `db:"username"`
+		Name     string `db:"name"`
+		Admin    bool   `db:"admin"`
+	}
+
+	err := auth.db.Get(&row, `
+		SELECT t.id, t.scope, t.last_used, t.user_id, u.username, u.name, u.admin
+		FROM api_token t
+		JOIN "user" u ON u.id = t.user_id
+		WHERE t.token_hash = ?`,
+		sha256Hex(token))
+	if err == sql.ErrNoRows {
+		return Session{}, false, nil
+	}
+
+	if err != nil {
+		return Session{}, false, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	session := Session{
+		LastSeen: time.Unix(row.LastUsed, 0),
+		TokenID:  row.ID,
+		Scope:    row.Scope,
+	}
+
+	session.User.ID = row.UserID
+	session.User.Username = row.Username
+	session.User.Name = row.Name
+	session.User.Admin = row.Admin
+	return session, true, nil
+}
+
+// touchToken updates the last used time of API token.
+func (auth *Authenticator) touchToken(tokenID int64, lastUsed time.Time) error {
+	_, err := auth.db.Exec(`UPDATE api_token SET last_used = ? WHERE id = ?`,
+		lastUsed.Unix(), tokenID)
+	if err != nil {
+		return fmt.Errorf("failed to update token: %w", err)
+	}
+	return nil
+}
+
+// scopeAllows checks whether API token with the scope can access the URL.
+// Read-only token can only fetch data, including the export of entries,
+// while write-entries token can also save entries and import them from
+// bank statements.
+func scopeAllows(scope, method, url string) bool {
+	if scope == ScopeAdmin {
+		return true
+	}
+
+	// Admin API serves backups which contain password hashes and other
+	// credentials, so it needs admin token whatever the method is.
+	if strings.HasPrefix(url, "/api/admin/") {
+		return false
+	}
+
+	if method == "GET" || method == "HEAD" {
+		return scope == ScopeReadOnly || scope == ScopeWriteEntries
+	}
+
+	// Journal import is not allowed since it also creates
+	// accounts and categories, and so are import profiles.
+	if scope == ScopeWriteEntries {
+		switch url {
+		case "/api/entry",
+			"/api/entries",
+			"/api/import/csv",
+			"/api/import/ofx",
+			"/api/import/qif":
+			return true
+		}
+	}
+
+	return false
+}
+
+// isAPIToken checks whether the credential sent by client is API token.
+func isAPIToken(credential string) bool {
+	return strings.HasPrefix(credential, apiTokenPrefix)
+}
+
+func (row apiTokenRow) apiToken() APIToken {
+	token := APIToken{
+		ID:        row.ID,
+		Name:      row.Name,
+		Scope:     row.Scope,
+		CreatedAt: time.Unix(row.CreatedAt, 0),
+	}
+
+	if row.LastUsed > 0 {
+		lastUsed := time.Unix(row.LastUsed, 0)
+		token.LastUsed = &lastUsed
+	}
+
+	return token
+}
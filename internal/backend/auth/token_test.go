@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+)
+
+func TestScopeAllows(t *testing.T) {
+	tests := []struct {
+		scope   string
+		method  string
+		url     string
+		allowed bool
+	}{
+		{ScopeReadOnly, "GET", "/api/entries", true},
+		{ScopeReadOnly, "POST", "/api/entry", false},
+		{ScopeReadOnly, "POST", "/api/tokens", false},
+		{ScopeReadOnly, "GET", "/api/export", true},
+		{ScopeReadOnly, "GET", "/api/admin/backups/x", false},
+		{ScopeWriteEntries, "GET", "/api/accounts", true},
+		{ScopeWriteEntries, "POST", "/api/entry", true},
+		{ScopeWriteEntries, "DELETE", "/api/entries", true},
+		{ScopeWriteEntries, "POST", "/api/import/csv", true},
+		{ScopeWriteEntries, "POST", "/api/import/journal", false},
+		{ScopeWriteEntries, "POST", "/api/import/profile", false},
+		{ScopeWriteEntries, "PUT", "/api/import/profile", false},
+		{ScopeWriteEntries, "GET", "/api/admin/backups/x", false},
+		{ScopeWriteEntries, "POST", "/api/account", false},
+		{ScopeWriteEntries, "POST", "/api/tokens", false},
+		{ScopeAdmin, "DELETE", "/api/users", true},
+		{ScopeAdmin, "GET", "/api/admin/backups/x", true},
+		{"unknown", "GET", "/api/entries", false},
+	}
+
+	for _, test := range tests {
+		allowed := scopeAllows(test.scope, test.method, test.url)
+		if allowed != test.allowed {
+			t.Errorf("%s %s %s: expected allowed %v, got %v",
+				test.scope, test.method, test.url, test.allowed, allowed)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	auth, _ := newTestAuthenticator(t)
+
+	login, err := auth.Login(httptest.NewRequest("POST", "/api/login", nil), "john", "secret")
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+
+	apiToken, token, err := auth.CreateToken(login.User, "script", ScopeReadOnly)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	request := func(method, url, credential string) error {
+		r := httptest.NewRequest(method, url, nil)
+		r.Header.Set("Authorization", "Bearer "+credential)
+		return auth.AuthenticateUser(r)
+	}
+
+	// Both API token and session can be sent as bearer token
+	if err = request("GET", "/api/entries", token); err != nil {
+		t.Errorf("expected token to be accepted, got %v", err)
+	}
+
+	if err = request("POST", "/api/entry", login.Session); err != nil {
+		t.Errorf("expected session to be accepted, got %v", err)
+	}
+
+	// Token is limited by its scope
+	if err = request("POST", "/api/entry", token); !isAPIError(err, apierr.CodeForbidden) {
+		t.Errorf("expected read-only token to be forbidden, got %v", err)
+	}
+
+	// Revoked token can't be used anymore
+	err = auth.RevokeToken(login.User.ID, apiToken.ID)
+	if err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+
+	if err = request("GET", "/api/entries", token); !isAPIError(err, apierr.CodeUnauthorized) {
+		t.Errorf("expected revoked token to be rejected, got %v", err)
+	}
+
+	tokens, err := auth.ListTokens(login.User.ID)
+	if err != nil || len(tokens) != 0 {
+		t.Errorf("expected no tokens, got %v (%v)", tokens, err)
+	}
+}
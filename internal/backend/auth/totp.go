@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// totpSecretSize is the size of TOTP secret in bytes. RFC 4226
+// recommends 160 bits, which is the block size of HMAC-SHA1.
+const totpSecretSize = 20
+
+// base32NoPadding is encoding for TOTP secret. Authenticator apps
+// expect the secret as upper case base32 without padding.
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTP generates and verifies time-based one-time password as described
+// in RFC 6238, using HMAC-SHA1 like most authenticator apps do.
+type TOTP struct {
+	// Period is the duration of each time step.
+	Period time.Duration
+
+	// Digits is the length of the code.
+	Digits int
+
+	// Skew is the number of time steps before and after the current
+	// one that still accepted, to tolerate clock drift in client.
+	Skew int
+
+	// Now returns the current time. It can be replaced with
+	// fake clock, so codes can be tested without waiting.
+	Now func() time.Time
+}
+
+// DefaultTOTP is TOTP with the settings used by common authenticator apps.
+var DefaultTOTP = TOTP{
+	Period: 30 * time.Second,
+	Digits: 6,
+	Skew:   1,
+	Now:    time.Now,
+}
+
+// GenerateTOTPSecret returns a new random secret encoded in base32.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return base32NoPadding.EncodeToString(secret), nil
+}
+
+// Step returns the time step that contains t.
+func (t TOTP) Step(at time.Time) int64 {
+	return at.Unix() / int64(t.Period/time.Second)
+}
+
+// Code returns the code for secret at the specified time step.
+func (t TOTP) Code(secret string, step int64) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	// Compute HMAC of the step counter, then take four bytes
+	// from offset that specified by the last byte of the hash
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	hash := mac.Sum(nil)
+
+	offset := hash[len(hash)-1] & 0x0F
+	value := binary.BigEndian.Uint32(hash[offset:offset+4]) & 0x7FFFFFFF
+
+	modulo := uint32(1)
+	for i := 0; i < t.Digits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", t.Digits, value%modulo), nil
+}
+
+// Verify checks whether code is valid for secret at the current time. If it's
+// valid, it returns the matched time step, which can be used to make sure the
+// same code is not used twice.
+func (t TOTP) Verify(secret, code string) (int64, bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) != t.Digits {
+		return 0, false, nil
+	}
+
+	current := t.Step(t.Now())
+	for step := current - int64(t.Skew); step <= current+int64(t.Skew); step++ {
+		expected, err := t.Code(secret, step)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// URI returns otpauth URI for secret, which usually shown as QR code
+// so it can be scanned by authenticator app.
+func (t TOTP) URI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(t.Digits))
+	params.Set("period", fmt.Sprint(int64(t.Period/time.Second)))
+
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: params.Encode(),
+	}
+
+	return uri.String()
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.TrimRight(secret, "="))
+	key, err := base32NoPadding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 secret used in test vectors of RFC 6238,
+// i.e. "12345678901234567890" encoded in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// fakeClock is clock whose time only changes when it's told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestTOTPCode(t *testing.T) {
+	totp := TOTP{Period: 30 * time.Second, Digits: 8}
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, test := range tests {
+		step := totp.Step(time.Unix(test.unix, 0))
+		code, err := totp.Code(rfcSecret, step)
+		if err != nil {
+			t.Fatalf("failed to generate code at %d: %v", test.unix, err)
+		}
+
+		if code != test.code {
+			t.Errorf("code at %d: expected %s, got %s", test.unix, test.code, code)
+		}
+	}
+}
+
+func TestTOTPVerify(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1111111111, 0)}
+	totp := DefaultTOTP
+	totp.Now = clock.Now
+
+	code, err := totp.Code(rfcSecret, totp.Step(clock.Now()))
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+
+	if code != "050471" {
+		t.Fatalf("expected 6 digits code 050471, got %s", code)
+	}
+
+	tests := []struct {
+		name  string
+		delay time.Duration
+		valid bool
+	}{
+		{"same step", 0, true},
+		{"next step is within skew", 30 * time.Second, true},
+		{"two steps later is rejected", 30 * time.Second, false},
+	}
+
+	for _, test := range tests {
+		clock.Add(test.delay)
+		_, valid, err := totp.Verify(rfcSecret, code)
+		if err != nil {
+			t.Fatalf("%s: failed to verify: %v", test.name, err)
+		}
+
+		if valid != test.valid {
+			t.Errorf("%s: expected valid %v, got %v", test.name, test.valid, valid)
+		}
+	}
+
+	for _, invalid := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, valid, _ := totp.Verify(rfcSecret, invalid); valid {
+			t.Errorf("code %q should be invalid", invalid)
+		}
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(DefaultTOTP.URI("Duit", "john doe", rfcSecret))
+	if err != nil {
+		t.Fatalf("URI is not valid: %v", err)
+	}
+
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Duit:john doe" {
+		t.Errorf("unexpected URI %s", uri)
+	}
+
+	query := uri.Query()
+	if query.Get("secret") != rfcSecret || query.Get("issuer") != "Duit" ||
+		query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("unexpected URI parameters %s", uri.RawQuery)
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		t.Fatalf("secret %s is not valid base32: %v", secret, err)
+	}
+
+	if len(key) != totpSecretSize {
+		t.Errorf("expected %d bytes secret, got %d", totpSecretSize, len(key))
+	}
+}
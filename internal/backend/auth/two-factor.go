@@ -0,0 +1,394 @@
+package auth
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/gofrs/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Settings for two-factor authentication.
+const (
+	totpIssuer              = "Duit"
+	pendingLoginDuration    = 5 * time.Minute
+	maxPendingLoginAttempts = 5
+	nRecoveryCodes          = 10
+	recoveryCodeLength      = 10
+)
+
+// recoveryCodeAlphabet is the characters used in recovery code. It's
+// lower case base32 alphabet, which doesn't have easily confused
+// characters like 0 and O.
+const recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+// pendingLogin is login that waiting for TOTP code.
+type pendingLogin struct {
+	user     model.User
+	expTime  time.Time
+	attempts int
+}
+
+// TwoFactorStatus is the state of user's two-factor authentication.
+type TwoFactorStatus struct {
+	Enabled       bool `json:"enabled"`
+	RecoveryCodes int  `json:"recoveryCodes"`
+}
+
+// LoginTwoFactor finishes the login of user that has enabled two-factor
+// authentication. Code is either TOTP code from authenticator app, or one
+// of the recovery codes which can only be used once.
+func (auth *Authenticator) LoginTwoFactor(r *http.Request, token, code string) (LoginResult, error) {
+	// Take the pending login, so it can't be used by
+	// other request while the code is being verified
+	auth.pendingLock.Lock()
+	pending, found := auth.pendingLogins[token]
+	delete(auth.pendingLogins, token)
+	auth.pendingLock.Unlock()
+
+	if !found || time.Now().After(pending.expTime) {
+		return LoginResult{}, apierr.Unauthorized("login has been expired, please login again")
+	}
+
+	// Wrong codes are counted as failed logins as well, so make sure
+	// it's not limited. If it is, put back the pending login for later.
+	attempt, wait := auth.limiter.reserve(pending.user.Username, clientIP(r))
+	if wait > 0 {
+		auth.pendingLock.Lock()
+		auth.pendingLogins[token] = pending
+		auth.pendingLock.Unlock()
+
+		return LoginResult{}, loginLimitedError(wait)
+	}
+	defer attempt.release()
+
+	// Verify the code. If it's wrong, put back the pending
+	// login until it runs out of attempts.
+	valid, err := auth.verifyTwoFactor(pending.user.ID, code)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	if !valid {
+		attempt.fail()
+
+		pending.attempts++
+		if pending.attempts < maxPendingLoginAttempts {
+			auth.pendingLock.Lock()
+			auth.pendingLogins[token] = pending
+			auth.pendingLock.Unlock()
+		}
+
+		return LoginResult{}, apierr.Unauthorized("code is not valid").WithField("code")
+	}
+
+	// Save user to session store
+	session, err := auth.createSession(r, pending.user)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	attempt.succeed()
+	return LoginResult{Session: session, User: pending.user}, nil
+}
+
+// GetTwoFactorStatus returns the two-factor authentication state of user.
+func (auth *Authenticator) GetTwoFactorStatus(userID int64) (TwoFactorStatus, error) {
+	var status TwoFactorStatus
+
+	var nEnabled int
+	err := auth.db.Get(&nEnabled, `SELECT COUNT(*) FROM user_totp
+		WHERE user_id = ? AND enabled = TRUE`, userID)
+	if err != nil {
+		return status, fmt.Errorf("failed to get two-factor status: %w", err)
+	}
+
+	err = auth.db.Get(&status.RecoveryCodes, `SELECT COUNT(*) FROM recovery_code
+		WHERE user_id = ?`, userID)
+	if err != nil {
+		return status, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	status.Enabled = nEnabled > 0
+	return status, nil
+}
+
+// SetupTwoFactor generates a new TOTP secret for user. It returns the secret
+// and its otpauth URI to be entered into authenticator app. The two-factor
+// authentication is not enabled until it's confirmed by EnableTwoFactor.
+func (auth *Authenticator) SetupTwoFactor(user model.User) (string, string, error) {
+	// Start transaction
+	// Make sure to rollback if error ever happened
+	tx := auth.db.MustBegin()
+	defer tx.Rollback()
+
+	// Make sure two-factor is not enabled yet
+	var nEnabled int
+	err := tx.Get(&nEnabled, `SELECT COUNT(*) FROM user_totp
+		WHERE user_id = ? AND enabled = TRUE`, user.ID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get two-factor status: %w", err)
+	}
+
+	if nEnabled > 0 {
+		return "", "", apierr.Conflict("two-factor authentication already enabled")
+	}
+
+	// Replace the unconfirmed secret, if any
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM user_totp WHERE user_id = ?`, user.ID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to remove old secret: %w", err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO user_totp (user_id, secret, enabled, last_step)
+		VALUES (?, ?, ?, 0)`, user.ID, secret, false)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to save secret: %w", err)
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, auth.totp.URI(totpIssuer, user.Username, secret), nil
+}
+
+// EnableTwoFactor enables two-factor authentication for user, after making
+// sure the first code from authenticator app is valid. It returns the new
+// recovery codes, which only shown this time since only their hashes saved.
+func (auth *Authenticator) EnableTwoFactor(userID int64, code string) ([]string, error) {
+	// Start transaction
+	// Make sure to rollback if error ever happened
+	tx := auth.db.MustBegin()
+	defer tx.Rollback()
+
+	// Fetch the unconfirmed secret
+	var secret string
+	err := tx.Get(&secret, `SELECT secret FROM user_totp
+		WHERE user_id = ? AND enabled = FALSE`, userID)
+	if err == sql.ErrNoRows {
+		return nil, apierr.Conflict("two-factor authentication is not set up or already enabled")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	// Verify the code
+	step, valid, err := auth.totp.Verify(secret, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		return nil, apierr.Validation("code is not valid").WithField("code")
+	}
+
+	// Enable two-factor, marking the code as used
+	_, err = tx.Exec(`UPDATE user_totp SET enabled = ?, last_step = ?
+		WHERE user_id = ?`, true, step, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor: %w", err)
+	}
+
+	// Replace recovery codes
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM recovery_code WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove old recovery codes: %w", err)
+	}
+
+	for _, code := range codes {
+		_, err = tx.Exec(`INSERT INTO recovery_code (user_id, code_hash)
+			VALUES (?, ?)`, userID, hashRecoveryCode(code))
+		if err != nil {
+			return nil, fmt.Errorf("failed to save recovery code: %w", err)
+		}
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor disables two-factor authentication for user,
+// after making sure the password is correct.
+func (auth *Authenticator) DisableTwoFactor(userID int64, password string) error {
+	var hashedPassword string
+	err := auth.db.Get(&hashedPassword, `SELECT password FROM "user" WHERE id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	if err != nil {
+		return apierr.Validation("password doesn't match").WithField("password")
+	}
+
+	return auth.ResetTwoFactor(userID)
+}
+
+// ResetTwoFactor removes TOTP secret and recovery codes of user, so it can
+// login using password only. Used by admin when user lost its device.
+func (auth *Authenticator) ResetTwoFactor(userID int64) error {
+	// Start transaction
+	// Make sure to rollback if error ever happened
+	tx := auth.db.MustBegin()
+	defer tx.Rollback()
+
+	_, err := tx.Exec(`DELETE FROM recovery_code WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove recovery codes: %w", err)
+	}
+
+	_, err = tx.Exec(`DELETE FROM user_totp WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove secret: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// verifyTwoFactor checks whether code is the valid TOTP code
+// or one of the unused recovery codes of the user.
+func (auth *Authenticator) verifyTwoFactor(userID int64, code string) (bool, error) {
+	// Code that has the length of TOTP code is treated as
+	// TOTP code, while the rest is treated as recovery code
+	code = strings.TrimSpace(code)
+	if len(code) != auth.totp.Digits {
+		res, err := auth.db.Exec(`DELETE FROM recovery_code
+			WHERE user_id = ? AND code_hash = ?`,
+			userID, hashRecoveryCode(code))
+		if err != nil {
+			return false, fmt.Errorf("failed to use recovery code: %w", err)
+		}
+
+		nUsed, err := res.RowsAffected()
+		if err != nil {
+			return false, err
+		}
+
+		return nUsed > 0, nil
+	}
+
+	// Fetch the secret
+	var secret string
+	err := auth.db.Get(&secret, `SELECT secret FROM user_totp
+		WHERE user_id = ? AND enabled = TRUE`, userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	// Verify the code
+	step, valid, err := auth.totp.Verify(secret, code)
+	if err != nil || !valid {
+		return false, err
+	}
+
+	// Make sure the code hasn't been used before. The last step is only
+	// updated if it's older, so the same code can't be used twice even
+	// when it's sent by two requests at the same time.
+	res, err := auth.db.Exec(`UPDATE user_totp SET last_step = ?
+		WHERE user_id = ? AND last_step < ?`, step, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to update last step: %w", err)
+	}
+
+	nUpdated, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return nUpdated > 0, nil
+}
+
+// startPendingLogin saves user as pending login, and
+// returns the token to finish it in LoginTwoFactor.
+func (auth *Authenticator) startPendingLogin(user model.User) (string, error) {
+	token, err := uuid.NewV4()
+	if err != nil {
+		return "", fmt.Errorf("failed to create login token: %w", err)
+	}
+
+	auth.pendingLock.Lock()
+	defer auth.pendingLock.Unlock()
+
+	auth.pendingLogins[token.String()] = pendingLogin{
+		user:    user,
+		expTime: time.Now().Add(pendingLoginDuration),
+	}
+
+	return token.String(), nil
+}
+
+func (auth *Authenticator) removeExpiredPendingLogins() {
+	auth.pendingLock.Lock()
+	defer auth.pendingLock.Unlock()
+
+	now := time.Now()
+	for token, pending := range auth.pendingLogins {
+		if now.After(pending.expTime) {
+			delete(auth.pendingLogins, token)
+		}
+	}
+}
+
+// generateRecoveryCodes returns random recovery codes,
+// formatted as two groups of characters like "abcde-fghij".
+func generateRecoveryCodes() ([]string, error) {
+	codes := make([]string, nRecoveryCodes)
+	for i := range codes {
+		buffer := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(buffer); err != nil {
+			return nil, err
+		}
+
+		for j, b := range buffer {
+			buffer[j] = recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)]
+		}
+
+		half := recoveryCodeLength / 2
+		codes[i] = string(buffer[:half]) + "-" + string(buffer[half:])
+	}
+
+	return codes, nil
+}
+
+// hashRecoveryCode returns hash of recovery code. The code is normalized
+// first, so user can enter it without dash or in upper case.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+
+	return sha256Hex(code)
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
+	"github.com/RadhiFadlillah/duit/internal/database"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"golang.org/x/crypto/bcrypt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestTwoFactorLogin(t *testing.T) {
+	auth, clock := newTestAuthenticator(t)
+	r := httptest.NewRequest("POST", "/api/login", nil)
+
+	// Without two-factor, login returns session right away
+	result, err := auth.Login(r, "john", "secret")
+	if err != nil || result.Session == "" {
+		t.Fatalf("expected session, got %+v (%v)", result, err)
+	}
+
+	// Enable two-factor using code from the fake clock
+	user := result.User
+	secret, _, err := auth.SetupTwoFactor(user)
+	if err != nil {
+		t.Fatalf("failed to setup two-factor: %v", err)
+	}
+
+	if _, err = auth.EnableTwoFactor(user.ID, "000000"); !isAPIError(err, apierr.CodeValidation) {
+		t.Fatalf("expected validation error for wrong code, got %v", err)
+	}
+
+	recoveryCodes, err := auth.EnableTwoFactor(user.ID, testCode(t, auth, secret))
+	if err != nil {
+		t.Fatalf("failed to enable two-factor: %v", err)
+	}
+
+	if len(recoveryCodes) != nRecoveryCodes {
+		t.Fatalf("expected %d recovery codes, got %d", nRecoveryCodes, len(recoveryCodes))
+	}
+
+	// Now login needs the second step
+	result, err = auth.Login(r, "john", "secret")
+	if err != nil || result.Session != "" || result.PendingToken == "" {
+		t.Fatalf("expected pending login, got %+v (%v)", result, err)
+	}
+
+	// The code used for enrolment can't be used again
+	token := result.PendingToken
+	_, err = auth.LoginTwoFactor(r, token, testCode(t, auth, secret))
+	if !isAPIError(err, apierr.CodeUnauthorized) {
+		t.Fatalf("expected reused code to be rejected, got %v", err)
+	}
+
+	// Code from the next time step is accepted
+	clock.Add(30 * time.Second)
+	result, err = auth.LoginTwoFactor(r, token, testCode(t, auth, secret))
+	if err != nil || result.Session == "" || result.User.ID != user.ID {
+		t.Fatalf("expected session, got %+v (%v)", result, err)
+	}
+
+	// The pending token is finished and can't be used anymore
+	_, err = auth.LoginTwoFactor(r, token, recoveryCodes[0])
+	if !isAPIError(err, apierr.CodeUnauthorized) {
+		t.Fatalf("expected finished token to be rejected, got %v", err)
+	}
+
+	// Recovery code can be used once, without dash or in upper case
+	result, _ = auth.Login(r, "john", "secret")
+	_, err = auth.LoginTwoFactor(r, result.PendingToken, "  "+upperNoDash(recoveryCodes[0]))
+	if err != nil {
+		t.Fatalf("failed to login with recovery code: %v", err)
+	}
+
+	result, _ = auth.Login(r, "john", "secret")
+	_, err = auth.LoginTwoFactor(r, result.PendingToken, recoveryCodes[0])
+	if !isAPIError(err, apierr.CodeUnauthorized) {
+		t.Fatalf("expected used recovery code to be rejected, got %v", err)
+	}
+
+	status, err := auth.GetTwoFactorStatus(user.ID)
+	if err != nil || !status.Enabled || status.RecoveryCodes != nRecoveryCodes-1 {
+		t.Fatalf("unexpected status %+v (%v)", status, err)
+	}
+
+	// Pending login is dropped after too many wrong codes. Wrong codes
+	// are failed logins, so forget the earlier ones to keep the user from
+	// being locked, and wait for their backoff before each retry.
+	auth.limiter.unlock(user.Username)
+	clock.Add(maxLoginBackoff)
+	result, _ = auth.Login(r, "john", "secret")
+	for i := 0; i < maxPendingLoginAttempts; i++ {
+		clock.Add(maxLoginBackoff)
+		auth.LoginTwoFactor(r, result.PendingToken, "000000")
+	}
+
+	clock.Add(30 * time.Second)
+	_, err = auth.LoginTwoFactor(r, result.PendingToken, testCode(t, auth, secret))
+	if !isAPIError(err, apierr.CodeUnauthorized) {
+		t.Fatalf("expected token to be dropped after too many attempts, got %v", err)
+	}
+
+	// After reset, login returns session right away again
+	err = auth.ResetTwoFactor(user.ID)
+	if err != nil {
+		t.Fatalf("failed to reset two-factor: %v", err)
+	}
+
+	err = auth.UnlockUser(user.ID)
+	if err != nil {
+		t.Fatalf("failed to unlock user: %v", err)
+	}
+
+	clock.Add(maxLoginBackoff)
+
+	result, err = auth.Login(r, "john", "secret")
+	if err != nil || result.Session == "" {
+		t.Fatalf("expected session after reset, got %+v (%v)", result, err)
+	}
+}
+
+// newTestAuthenticator returns Authenticator that uses a new SQLite database
+// with user "john", and TOTP and login limiter whose time is controlled
+// by the fake clock.
+func newTestAuthenticator(t *testing.T) (*Authenticator, *fakeClock) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "duit-auth")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := database.Open(model.Config{
+		DbDriver: "sqlite",
+		DbPath:   filepath.Join(dir, "duit.db"),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	err = database.MigrateUp(db, 0)
+	if err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	password, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	db.MustExec(`INSERT INTO "user" (username, name, password, admin)
+		VALUES (?, ?, ?, ?)`, "john", "John", password, true)
+
+	auth, err := NewAuthenticator(db, NewMemorySessionStore(), nil, model.Config{})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+
+	clock := &fakeClock{now: time.Unix(1600000000, 0)}
+	auth.totp.Now = clock.Now
+	auth.limiter.now = clock.Now
+	return auth, clock
+}
+
+// testCode returns TOTP code for secret at the current time of fake clock.
+func testCode(t *testing.T, auth *Authenticator, secret string) string {
+	t.Helper()
+
+	code, err := auth.totp.Code(secret, auth.totp.Step(auth.totp.Now()))
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	return code
+}
+
+func isAPIError(err error, code apierr.Code) bool {
+	apiErr, ok := err.(*apierr.Error)
+	return ok && apiErr.Code == code
+}
+
+func upperNoDash(code string) string {
+	return strings.ToUpper(strings.Replace(code, "-", "", -1))
+}
@@ -3,12 +3,15 @@ package backend
 import (
 	"fmt"
 	"net/http"
+	"runtime/debug"
 	"strings"
 	"time"
 
 	"github.com/RadhiFadlillah/duit/internal/backend/api"
+	"github.com/RadhiFadlillah/duit/internal/backend/apierr"
 	"github.com/RadhiFadlillah/duit/internal/backend/auth"
 	"github.com/RadhiFadlillah/duit/internal/backend/ui"
+	"github.com/RadhiFadlillah/duit/internal/backup"
 	"github.com/RadhiFadlillah/duit/internal/model"
 	"github.com/jmoiron/sqlx"
 	"github.com/julienschmidt/httprouter"
@@ -17,6 +20,9 @@ import (
 
 var developmentMode = true
 
+// recurringInterval is interval for checking the due recurring entries.
+const recurringInterval = time.Hour
+
 // SlowDown is middleware to throttle response speed.
 // Used to emulate low connection speed.
 type SlowDown struct {
@@ -32,9 +38,14 @@ func (sd SlowDown) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 // ServeApp serves web app in specified port
-func ServeApp(db *sqlx.DB, port int) error {
+func ServeApp(db *sqlx.DB, config model.Config, port int) error {
 	// Prepare authenticator and handler
-	auth, err := auth.NewAuthenticator(db, authenticationRules)
+	sessions, err := auth.NewSessionStore(db, config.SessionStore)
+	if err != nil {
+		return fmt.Errorf("failed to create session store: %w", err)
+	}
+
+	auth, err := auth.NewAuthenticator(db, sessions, authenticationRules, config)
 	if err != nil {
 		return fmt.Errorf("failed to create authenticator: %w", err)
 	}
@@ -44,7 +55,7 @@ func ServeApp(db *sqlx.DB, port int) error {
 		return fmt.Errorf("failed to create UI handler: %w", err)
 	}
 
-	apiHdl, err := api.NewHandler(db, auth)
+	apiHdl, err := api.NewHandler(db, auth, config)
 	if err != nil {
 		return fmt.Errorf("failed to create API handler: %w", err)
 	}
@@ -60,8 +71,21 @@ func ServeApp(db *sqlx.DB, port int) error {
 	router.GET("/css/*filepath", uiHdl.ServeFile)
 
 	router.POST("/api/login", apiHdl.Login)
+	router.POST("/api/login/2fa", apiHdl.LoginTwoFactor)
 	router.POST("/api/logout", apiHdl.Logout)
 
+	router.GET("/api/2fa", apiHdl.GetTwoFactor)
+	router.POST("/api/2fa/setup", apiHdl.SetupTwoFactor)
+	router.POST("/api/2fa/enable", apiHdl.EnableTwoFactor)
+	router.POST("/api/2fa/disable", apiHdl.DisableTwoFactor)
+
+	router.GET("/api/tokens", apiHdl.SelectTokens)
+	router.POST("/api/tokens", apiHdl.InsertToken)
+	router.DELETE("/api/tokens/:id", apiHdl.DeleteToken)
+
+	router.GET("/api/sessions", apiHdl.SelectSessions)
+	router.DELETE("/api/sessions/:id", apiHdl.DeleteSession)
+
 	router.GET("/api/users", apiHdl.SelectUsers)
 	router.POST("/api/user", apiHdl.InsertUser)
 	router.DELETE("/api/users", apiHdl.DeleteUsers)
@@ -74,16 +98,79 @@ func ServeApp(db *sqlx.DB, port int) error {
 	router.PUT("/api/account", apiHdl.UpdateAccount)
 	router.DELETE("/api/accounts", apiHdl.DeleteAccounts)
 
+	router.GET("/api/categories", apiHdl.SelectCategories)
+	router.POST("/api/category", apiHdl.InsertCategory)
+	router.PUT("/api/category", apiHdl.UpdateCategory)
+	router.DELETE("/api/categories", apiHdl.DeleteCategories)
+
+	router.GET("/api/tags", apiHdl.SelectTags)
+
 	router.GET("/api/entries", apiHdl.SelectEntries)
 	router.POST("/api/entry", apiHdl.InsertEntry)
 	router.PUT("/api/entry", apiHdl.UpdateEntry)
 	router.DELETE("/api/entries", apiHdl.DeleteEntries)
 
+	router.GET("/api/search", apiHdl.SearchEntries)
+
+	router.GET("/api/recurring", apiHdl.SelectRecurring)
+	router.GET("/api/recurring/preview", apiHdl.PreviewRecurring)
+	router.POST("/api/recurring", apiHdl.InsertRecurring)
+	router.PUT("/api/recurring", apiHdl.UpdateRecurring)
+	router.DELETE("/api/recurring", apiHdl.DeleteRecurring)
+
+	router.GET("/api/budgets", apiHdl.SelectBudgets)
+	router.GET("/api/budgets/status", apiHdl.GetBudgetsStatus)
+	router.POST("/api/budget", apiHdl.InsertBudget)
+	router.PUT("/api/budget", apiHdl.UpdateBudget)
+	router.DELETE("/api/budgets", apiHdl.DeleteBudgets)
+
+	router.POST("/api/import/csv", apiHdl.ImportCSV)
+	router.POST("/api/import/ofx", apiHdl.ImportOFX)
+	router.POST("/api/import/qif", apiHdl.ImportQIF)
+	router.POST("/api/import/journal", apiHdl.ImportJournal)
+	router.GET("/api/import/profiles", apiHdl.SelectImportProfiles)
+	router.POST("/api/import/profile", apiHdl.InsertImportProfile)
+	router.PUT("/api/import/profile", apiHdl.UpdateImportProfile)
+	router.DELETE("/api/import/profiles", apiHdl.DeleteImportProfiles)
+
+	router.GET("/api/export", apiHdl.Export)
+
+	router.GET("/api/admin/backups", apiHdl.SelectBackups)
+	router.GET("/api/admin/backups/:name", apiHdl.DownloadBackup)
+	router.GET("/api/admin/users/:id/sessions", apiHdl.SelectUserSessions)
+	router.DELETE("/api/admin/users/:id/sessions", apiHdl.DeleteUserSessions)
+	router.DELETE("/api/admin/sessions/:id", apiHdl.DeleteAnySession)
+	router.DELETE("/api/admin/users/:id/2fa", apiHdl.ResetTwoFactor)
+	router.DELETE("/api/admin/users/:id/lockout", apiHdl.UnlockUser)
+
+	router.GET("/api/rates", apiHdl.SelectRates)
+	router.POST("/api/rate", apiHdl.InsertRate)
+	router.PUT("/api/rate", apiHdl.UpdateRate)
+	router.DELETE("/api/rates", apiHdl.DeleteRates)
+
 	router.GET("/api/charts", apiHdl.GetChartsData)
 
-	// Route for panic
+	// Route for panic. Stack trace is only logged for internal
+	// error, since the other errors are caused by client.
 	router.PanicHandler = func(w http.ResponseWriter, r *http.Request, arg interface{}) {
-		http.Error(w, fmt.Sprint(arg), 500)
+		err, isError := arg.(error)
+		if !isError {
+			err = fmt.Errorf("%v", arg)
+		}
+
+		// Message of internal and duplicate error is replaced with
+		// a generic one, so the original error is logged here
+		apiErr := apierr.From(err)
+		switch apiErr.Code {
+		case apierr.CodeInternal:
+			logrus.Errorf("%s %s: %v\n%s", r.Method, r.URL.Path, err, debug.Stack())
+		case apierr.CodeConflict:
+			if _, isAPIError := err.(*apierr.Error); !isAPIError {
+				logrus.Warnf("%s %s: %v", r.Method, r.URL.Path, err)
+			}
+		}
+
+		apierr.Write(w, apiErr)
 	}
 
 	// Set handler
@@ -103,6 +190,19 @@ func ServeApp(db *sqlx.DB, port int) error {
 		WriteTimeout: time.Minute,
 	}
 
+	// Generate recurring entries in background
+	go apiHdl.ScheduleRecurring(recurringInterval)
+
+	// Create scheduled backups in background
+	if config.BackupDir != "" {
+		scheduler, err := backup.NewScheduler(db, config)
+		if err != nil {
+			return fmt.Errorf("failed to create backup scheduler: %w", err)
+		}
+
+		go scheduler.Start()
+	}
+
 	// Serve app
 	logrus.Infoln("Serve app in", url)
 	return svr.ListenAndServe()
@@ -118,5 +218,10 @@ func authenticationRules(user model.User, method, url string) bool {
 		return user.Admin
 	}
 
+	// Admin API is only for admin as well
+	if strings.HasPrefix(url, "/api/admin/") {
+		return user.Admin
+	}
+
 	return true
 }
@@ -113,6 +113,6 @@ func (h *Handler) ServeRegister(w http.ResponseWriter, r *http.Request, ps httpr
 // isFirstRun check if there are no admin registered
 func (h *Handler) isFirstRun() bool {
 	var nAdmin int
-	h.db.Get(&nAdmin, `SELECT COUNT(id) FROM user WHERE admin = 1`)
+	h.db.Get(&nAdmin, `SELECT COUNT(id) FROM "user" WHERE admin = TRUE`)
 	return nAdmin == 0
 }
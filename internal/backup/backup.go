@@ -0,0 +1,436 @@
+// Package backup creates and restores archive that contains all data in
+// database. The archive is independent from the database driver, so it
+// can also be used to move data from a database to another.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/database"
+	"github.com/jmoiron/sqlx"
+)
+
+// Format and version of the archive. Increase the version when the
+// structure of archive is changed, including when the list of tables
+// below is changed.
+const (
+	archiveFormat  = "duit-backup"
+	archiveVersion = 2
+)
+
+// tables is list of tables saved in archive. Table must be listed after
+// the tables that referred by it, so they can be restored in order.
+var tables = []string{
+	"user",
+	"user_totp",
+	"recovery_code",
+	"api_token",
+	"account",
+	"category",
+	"tag",
+	"currency_rate",
+	"recurring",
+	"entry",
+	"entry_tag",
+	"entry_split",
+	"budget",
+	"import_profile",
+}
+
+var rxIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Archive is the content of backup file. SchemaVersion is version of
+// database schema when the archive created, which must be matched when
+// the archive restored.
+type Archive struct {
+	Format        string  `json:"format"`
+	Version       int     `json:"version"`
+	SchemaVersion int     `json:"schemaVersion"`
+	CreatedAt     string  `json:"createdAt"`
+	Tables        []Table `json:"tables"`
+}
+
+// Table is the content of a table in archive.
+type Table struct {
+	Name    string          `json:"name"`
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// Create reads all data from database. The data is read in one transaction,
+// so the archive is consistent even if the database is being modified.
+func Create(db *sqlx.DB) (*Archive, error) {
+	// Start transaction. Repeatable read is needed, so every
+	// query see the same snapshot of database.
+	tx, err := db.BeginTxx(context.Background(), &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	archive := &Archive{
+		Format:    archiveFormat,
+		Version:   archiveVersion,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Tables:    []Table{},
+	}
+
+	err = tx.Get(&archive.SchemaVersion, `SELECT MAX(version) FROM schema_version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	// Fetch content of each table
+	for _, name := range tables {
+		table, err := readTable(tx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read table %s: %w", name, err)
+		}
+
+		archive.Tables = append(archive.Tables, table)
+	}
+
+	return archive, nil
+}
+
+// Restore writes the archive into database in one transaction. If database
+// schema is older than the archive, it will be migrated first. Restore will
+// fail if database is not empty, unless it's forced which means the existing
+// data will be replaced.
+func Restore(db *sqlx.DB, archive *Archive, force bool) (err error) {
+	// Make sure the archive can be restored
+	if archive.Format != archiveFormat {
+		return fmt.Errorf("file is not a duit backup")
+	}
+
+	if archive.Version > archiveVersion {
+		return fmt.Errorf("backup version %d is not supported, please upgrade duit", archive.Version)
+	}
+
+	knownTables := make(map[string]struct{})
+	for _, name := range tables {
+		knownTables[name] = struct{}{}
+	}
+
+	archiveTables := make(map[string]Table)
+	for _, table := range archive.Tables {
+		if _, known := knownTables[table.Name]; !known {
+			return fmt.Errorf("backup contains unknown table %q", table.Name)
+		}
+		archiveTables[table.Name] = table
+	}
+
+	err = checkSchemaVersion(db, archive.SchemaVersion)
+	if err != nil {
+		return err
+	}
+
+	// Start transaction
+	// Make sure to rollback if panic ever happened
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+
+			var ok bool
+			if err, ok = r.(error); !ok {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	// Make sure database is empty, or clear it if forced. Archive only
+	// contains the tables that exist in its schema version, so the newer
+	// tables are skipped since they don't exist yet in database.
+	for i := len(tables) - 1; i >= 0; i-- {
+		if _, exist := archiveTables[tables[i]]; !exist {
+			continue
+		}
+
+		if force {
+			tx.MustExec(`DELETE FROM "` + tables[i] + `"`)
+			continue
+		}
+
+		var count int
+		err = tx.Get(&count, `SELECT COUNT(*) FROM "`+tables[i]+`"`)
+		checkError(err)
+
+		if count > 0 {
+			panic(fmt.Errorf("database is not empty, use force to replace the existing data"))
+		}
+	}
+
+	// Restore each table in order
+	for _, name := range tables {
+		table, exist := archiveTables[name]
+		if !exist {
+			continue
+		}
+
+		err = writeTable(tx, table)
+		if err != nil {
+			panic(fmt.Errorf("failed to restore table %s: %w", name, err))
+		}
+	}
+
+	err = tx.Commit()
+	checkError(err)
+
+	return nil
+}
+
+// WriteFile writes archive into file as JSON. If the file name ends
+// with .gz, it will be compressed. Since the archive contains password
+// of users, the file is only readable by its owner.
+func WriteFile(path string, archive *Archive) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+
+	err = json.NewEncoder(w).Encode(archive)
+	if err != nil {
+		return err
+	}
+
+	if gz != nil {
+		if err = gz.Close(); err != nil {
+			return err
+		}
+	}
+
+	return f.Close()
+}
+
+// ReadFile reads archive from file. The file is
+// decompressed if its name ends with .gz.
+func ReadFile(path string) (*Archive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	// Numbers are kept as it is, so big number
+	// and decimal won't lose their precision
+	var archive Archive
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	err = decoder.Decode(&archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	return &archive, nil
+}
+
+// checkSchemaVersion makes sure the database schema has the same version
+// as the archive. If the database schema is older, it will be migrated.
+func checkSchemaVersion(db *sqlx.DB, version int) error {
+	latest, err := database.LatestSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	if version > latest {
+		return fmt.Errorf("backup schema version %d is newer than "+
+			"the latest version known by duit (%d), please upgrade duit",
+			version, latest)
+	}
+
+	current, err := database.SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	if current > version {
+		return fmt.Errorf("database schema version %d is newer than backup "+
+			"schema version %d, please restore it into a new database", current, version)
+	}
+
+	if current < version {
+		err = database.MigrateUp(db, version)
+		if err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// readTable reads all rows in the table.
+func readTable(tx *sqlx.Tx, name string) (Table, error) {
+	rows, err := tx.Query(`SELECT * FROM "` + name + `"`)
+	if err != nil {
+		return Table{}, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return Table{}, err
+	}
+
+	table := Table{
+		Name:    name,
+		Columns: columns,
+		Rows:    [][]interface{}{},
+	}
+
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err = rows.Scan(pointers...); err != nil {
+			return Table{}, err
+		}
+
+		// MySQL returns most of the value as bytes, while
+		// SQLite might returns time for date column
+		for i, value := range values {
+			switch v := value.(type) {
+			case []byte:
+				values[i] = string(v)
+			case time.Time:
+				values[i] = v.UTC().Format("2006-01-02 15:04:05")
+			}
+		}
+
+		table.Rows = append(table.Rows, values)
+	}
+
+	return table, rows.Err()
+}
+
+// writeTable inserts all rows of the table. For PostgreSQL, the sequence
+// for ID is updated since it's not changed when ID is inserted manually.
+func writeTable(tx *sqlx.Tx, table Table) error {
+	// Make sure the columns are valid, since
+	// they will be put directly into query
+	hasID := false
+	for _, column := range table.Columns {
+		if !rxIdentifier.MatchString(column) {
+			return fmt.Errorf("invalid column %q", column)
+		}
+		hasID = hasID || column == "id"
+	}
+
+	if len(table.Rows) == 0 {
+		return nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(table.Columns)), ", ")
+	stmt, err := tx.Preparex(fmt.Sprintf(`INSERT INTO "%s" ("%s") VALUES (%s)`,
+		table.Name, strings.Join(table.Columns, `", "`), placeholders))
+	if err != nil {
+		return err
+	}
+
+	for _, row := range sortByParent(table) {
+		if len(row) != len(table.Columns) {
+			return fmt.Errorf("row has %d values while table has %d columns",
+				len(row), len(table.Columns))
+		}
+
+		if _, err = stmt.Exec(row...); err != nil {
+			return err
+		}
+	}
+
+	if hasID && tx.DriverName() == "postgres" {
+		_, err = tx.Exec(fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('"%s"', 'id'),
+			(SELECT MAX(id) FROM "%s"))`, table.Name, table.Name))
+	}
+
+	return err
+}
+
+// sortByParent sorts rows in table that refer to itself using parent_id,
+// e.g. category, so parent is always inserted before its children.
+func sortByParent(table Table) [][]interface{} {
+	idIdx, parentIdx := -1, -1
+	for i, column := range table.Columns {
+		switch column {
+		case "id":
+			idIdx = i
+		case "parent_id":
+			parentIdx = i
+		}
+	}
+
+	if idIdx < 0 || parentIdx < 0 {
+		return table.Rows
+	}
+
+	result := [][]interface{}{}
+	inserted := make(map[string]bool)
+	remaining := table.Rows
+
+	for len(remaining) > 0 {
+		next := [][]interface{}{}
+		for _, row := range remaining {
+			parent := row[parentIdx]
+			if parent == nil || inserted[fmt.Sprint(parent)] {
+				inserted[fmt.Sprint(row[idIdx])] = true
+				result = append(result, row)
+			} else {
+				next = append(next, row)
+			}
+		}
+
+		// If nothing inserted in this round, the parents don't exist.
+		// Just insert them, so database can decide what to do.
+		if len(next) == len(remaining) {
+			return append(result, next...)
+		}
+
+		remaining = next
+	}
+
+	return result
+}
+
+func checkError(err error) {
+	if err != nil && err != sql.ErrNoRows {
+		panic(err)
+	}
+}
@@ -0,0 +1,179 @@
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RadhiFadlillah/duit/internal/database"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestRestoreRejectsArchive(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive Archive
+		err     string
+	}{{
+		name:    "wrong format",
+		archive: Archive{Format: "other", Version: archiveVersion},
+		err:     "not a duit backup",
+	}, {
+		name:    "newer version",
+		archive: Archive{Format: archiveFormat, Version: archiveVersion + 1},
+		err:     "not supported",
+	}, {
+		name: "unknown table",
+		archive: Archive{
+			Format:  archiveFormat,
+			Version: archiveVersion,
+			Tables:  []Table{{Name: "user"}, {Name: "session"}},
+		},
+		err: `unknown table "session"`,
+	}}
+
+	// The archive is checked before database is touched,
+	// so these restores never need a real database.
+	for _, tt := range tests {
+		err := Restore(nil, &tt.archive, false)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: want error containing %q, got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestRestoreOldArchive(t *testing.T) {
+	// Archive created at schema 9, before tables for
+	// two-factor authentication and API token exist
+	source := openTestDB(t, 9)
+	source.MustExec(`INSERT INTO user (id, username, name, password, admin)
+		VALUES (1, 'admin', 'Admin', 'secret', 1)`)
+	source.MustExec(`INSERT INTO account (id, name, initial_amount, currency)
+		VALUES (1, 'Cash', 100, 'USD')`)
+	source.MustExec(`INSERT INTO entry (id, account_id, type, amount, date, import_id)
+		VALUES (1, 1, 2, 25.5, '2021-03-04', 'FIT1')`)
+
+	archive := &Archive{
+		Format:        archiveFormat,
+		Version:       1,
+		SchemaVersion: 9,
+	}
+
+	tx := source.MustBegin()
+	for _, name := range []string{"user", "account", "category", "tag",
+		"currency_rate", "recurring", "entry", "entry_tag", "entry_split",
+		"budget", "import_profile"} {
+		table, err := readTable(tx, name)
+		if err != nil {
+			t.Fatalf("failed to read table %s: %v", name, err)
+		}
+		archive.Tables = append(archive.Tables, table)
+	}
+	tx.Rollback()
+
+	// Restore into a new database, which will be migrated to schema 9
+	target := openTestDB(t, -1)
+	if err := Restore(target, archive, false); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+
+	version, err := database.SchemaVersion(target)
+	if err != nil || version != 9 {
+		t.Errorf("want schema version 9, got %d (%v)", version, err)
+	}
+
+	var entry struct {
+		Amount   string `db:"amount"`
+		ImportID string `db:"import_id"`
+		Currency string `db:"currency"`
+	}
+	err = target.Get(&entry, `SELECT e.amount, e.import_id, a.currency
+		FROM entry e JOIN account a ON a.id = e.account_id`)
+	if err != nil {
+		t.Fatalf("failed to get restored entry: %v", err)
+	}
+
+	if entry.Amount != "25.5" || entry.ImportID != "FIT1" || entry.Currency != "USD" {
+		t.Errorf("restored entry is wrong: %+v", entry)
+	}
+
+	// Database is not empty anymore, so it must be forced
+	err = Restore(target, archive, false)
+	if err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("want error for non empty database, got %v", err)
+	}
+
+	if err = Restore(target, archive, true); err != nil {
+		t.Errorf("failed to force restore: %v", err)
+	}
+
+	// Restored database can be migrated to the latest schema
+	if err = database.MigrateUp(target, 0); err != nil {
+		t.Errorf("failed to migrate restored database: %v", err)
+	}
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	source := openTestDB(t, 0)
+	source.MustExec(`INSERT INTO user (id, username, name, password, admin)
+		VALUES (1, 'admin', 'Admin', 'secret', 1)`)
+	source.MustExec(`INSERT INTO api_token (user_id, name, token_hash, scope, created_at)
+		VALUES (1, 'script', 'hash', 'read-only', 1600000000)`)
+
+	archive, err := Create(source)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+
+	if len(archive.Tables) != len(tables) {
+		t.Errorf("want %d tables, got %d", len(tables), len(archive.Tables))
+	}
+
+	target := openTestDB(t, -1)
+	if err = Restore(target, archive, false); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+
+	var count int
+	err = target.Get(&count, `SELECT COUNT(*) FROM api_token`)
+	if err != nil || count != 1 {
+		t.Errorf("want 1 restored API token, got %d (%v)", count, err)
+	}
+}
+
+// openTestDB opens a new SQLite database which migrated to the specified
+// schema version. Use 0 for the latest version, or -1 to keep it empty.
+func openTestDB(t *testing.T, version int) *sqlx.DB {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "duit-backup")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+
+	db, err := database.Open(model.Config{
+		DbDriver: "sqlite",
+		DbPath:   filepath.Join(dir, "duit.db"),
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+
+	if version >= 0 {
+		if err = database.MigrateUp(db, version); err != nil {
+			t.Fatalf("failed to migrate database: %v", err)
+		}
+	}
+
+	return db
+}
@@ -0,0 +1,166 @@
+package backup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron-like schedule with five fields, i.e. minute, hour,
+// day of month, month and day of week. Each field may contain a list of
+// numbers, ranges and steps, e.g. "0,30", "1-5" or "*/15".
+type Schedule struct {
+	minute, hour, day, month, weekday uint64
+
+	// In cron, if both day of month and day of week are
+	// restricted, the time matches when either one of them
+	// matches. So here we need to know whether it's "*".
+	anyDay, anyWeekday bool
+}
+
+// scheduleAliases is the shortcuts for common schedules.
+var scheduleAliases = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// ParseSchedule parses cron expression, e.g. "0 2 * * *" for every day at
+// 02:00. Shortcuts @hourly, @daily, @weekly and @monthly are supported too.
+func ParseSchedule(expr string) (*Schedule, error) {
+	if alias, exist := scheduleAliases[strings.TrimSpace(expr)]; exist {
+		expr = alias
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule must have 5 fields: minute, hour, day, month and weekday")
+	}
+
+	bounds := []struct {
+		name     string
+		min, max int
+	}{
+		{"minute", 0, 59},
+		{"hour", 0, 23},
+		{"day", 1, 31},
+		{"month", 1, 12},
+		{"weekday", 0, 7},
+	}
+
+	bits := make([]uint64, 5)
+	for i, field := range fields {
+		var err error
+		bits[i], err = parseScheduleField(field, bounds[i].min, bounds[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", bounds[i].name, field, err)
+		}
+	}
+
+	// Both 0 and 7 are Sunday
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &Schedule{
+		minute:     bits[0],
+		hour:       bits[1],
+		day:        bits[2],
+		month:      bits[3],
+		weekday:    bits[4],
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}, nil
+}
+
+// Next returns the first time after t that matches the schedule.
+// If there is no such time in the next five years, zero time is returned.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) matchDay(t time.Time) bool {
+	dayMatch := s.day&(1<<uint(t.Day())) != 0
+	weekdayMatch := s.weekday&(1<<uint(t.Weekday())) != 0
+
+	if s.anyDay || s.anyWeekday {
+		return dayMatch && weekdayMatch
+	}
+
+	return dayMatch || weekdayMatch
+}
+
+// parseScheduleField parses a field of cron expression into bits,
+// where each bit marks the allowed value.
+func parseScheduleField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		// Parse step
+		step := 1
+		hasStep := false
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			hasStep = true
+			var err error
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step")
+			}
+			part = part[:idx]
+		}
+
+		// Parse range
+		start, end := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			rangeParts := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			start, err1 = strconv.Atoi(rangeParts[0])
+			end, err2 = strconv.Atoi(rangeParts[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range")
+			}
+		default:
+			var err error
+			start, err = strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid number")
+			}
+
+			// Single number only means range if step is specified
+			if !hasStep {
+				end = start
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("value must be between %d and %d", min, max)
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
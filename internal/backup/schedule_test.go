@@ -0,0 +1,90 @@
+package backup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	valid := []string{
+		"0 2 * * *",
+		"*/15 * * * *",
+		"0,30 8-17 * * 1-5",
+		"0 0 1 */3 *",
+		"5/10 * * * 7",
+		"@daily",
+		" @weekly ",
+	}
+
+	for _, expr := range valid {
+		if _, err := ParseSchedule(expr); err != nil {
+			t.Errorf("%q: unexpected error %v", expr, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"0 2 * *",
+		"0 2 * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-b * * * *",
+		"@yearly",
+	}
+
+	for _, expr := range invalid {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// 2020-01-15 is Wednesday
+	now := time.Date(2020, 1, 15, 10, 20, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		next string
+	}{
+		{"0 2 * * *", "2020-01-16 02:00"},
+		{"30 10 * * *", "2020-01-15 10:30"},
+		{"20 10 * * *", "2020-01-16 10:20"},
+		{"*/15 * * * *", "2020-01-15 10:30"},
+		{"5/10 * * * *", "2020-01-15 10:25"},
+		{"5,50/10 * * * *", "2020-01-15 10:50"},
+		{"0 0 1 * *", "2020-02-01 00:00"},
+		{"0 0 * * 7", "2020-01-19 00:00"},
+		{"0 0 * * 0", "2020-01-19 00:00"},
+		{"0 9 * * 1-5", "2020-01-16 09:00"},
+		{"0 0 29 2 *", "2020-02-29 00:00"},
+		{"0 0 31 * *", "2020-01-31 00:00"},
+
+		// If both day and weekday are restricted, either one matches
+		{"0 0 20 * 5", "2020-01-17 00:00"},
+		{"0 0 16 * 6", "2020-01-16 00:00"},
+	}
+
+	for _, test := range tests {
+		schedule, err := ParseSchedule(test.expr)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", test.expr, err)
+			continue
+		}
+
+		if next := schedule.Next(now).Format("2006-01-02 15:04"); next != test.next {
+			t.Errorf("%q: expected next %s, got %s", test.expr, test.next, next)
+		}
+	}
+
+	// Date that never exists returns zero time
+	schedule, _ := ParseSchedule("0 0 30 2 *")
+	if next := schedule.Next(now); !next.IsZero() {
+		t.Errorf("expected zero time for February 30th, got %s", next)
+	}
+}
@@ -0,0 +1,269 @@
+package backup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+// Default values for scheduled backup, used when they are not set in config.
+const (
+	DefaultSchedule    = "0 2 * * *"
+	DefaultKeepDaily   = 7
+	DefaultKeepWeekly  = 4
+	DefaultKeepMonthly = 12
+)
+
+// fileLayout is the time layout used in name of scheduled backup file.
+const fileLayout = "20060102-150405"
+
+var rxFileName = regexp.MustCompile(`^duit-(\d{8}-\d{6})\.json\.gz$`)
+
+// File is a backup file inside the backup directory.
+type File struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Retention is the number of backups to keep. For each day, week and month,
+// only the latest backup is kept, up to the specified number of each.
+type Retention struct {
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+// Scheduler creates backups periodically into a directory, then removes
+// the old backups that are not needed anymore.
+type Scheduler struct {
+	db        *sqlx.DB
+	dir       string
+	schedule  *Schedule
+	retention Retention
+}
+
+// NewScheduler returns new Scheduler using the backup settings in config.
+func NewScheduler(db *sqlx.DB, config model.Config) (*Scheduler, error) {
+	if config.BackupDir == "" {
+		return nil, fmt.Errorf("backup directory is not specified")
+	}
+
+	expr := config.BackupSchedule
+	if expr == "" {
+		expr = DefaultSchedule
+	}
+
+	schedule, err := ParseSchedule(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup schedule: %w", err)
+	}
+
+	err = os.MkdirAll(config.BackupDir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	retention := Retention{
+		Daily:   config.BackupKeepDaily,
+		Weekly:  config.BackupKeepWeekly,
+		Monthly: config.BackupKeepMonthly,
+	}
+
+	if retention.Daily <= 0 {
+		retention.Daily = DefaultKeepDaily
+	}
+
+	if retention.Weekly <= 0 {
+		retention.Weekly = DefaultKeepWeekly
+	}
+
+	if retention.Monthly <= 0 {
+		retention.Monthly = DefaultKeepMonthly
+	}
+
+	return &Scheduler{
+		db:        db,
+		dir:       config.BackupDir,
+		schedule:  schedule,
+		retention: retention,
+	}, nil
+}
+
+// Start creates backup every time the schedule is due. It never returns,
+// so it should be run in its own goroutine.
+func (s *Scheduler) Start() {
+	var last time.Time
+	for {
+		// Sleep might wake up a bit early, so make sure
+		// the same schedule is not used twice
+		from := time.Now()
+		if from.Before(last) {
+			from = last
+		}
+
+		next := s.schedule.Next(from)
+		if next.IsZero() {
+			logrus.Errorln("backup schedule will never be due")
+			return
+		}
+
+		time.Sleep(time.Until(next))
+		last = next
+
+		name, err := s.Backup()
+		if err != nil {
+			logrus.Errorln("failed to create scheduled backup:", err)
+			continue
+		}
+
+		logrus.Infoln("scheduled backup saved to", name)
+	}
+}
+
+// Backup creates a new backup file, then removes the old backups
+// that are outside retention. It returns name of the new file.
+func (s *Scheduler) Backup() (string, error) {
+	archive, err := Create(s.db)
+	if err != nil {
+		return "", err
+	}
+
+	// Write into hidden temporary file first, so a
+	// failed backup won't be listed as the valid one
+	name := FileName(time.Now())
+	tmpPath := filepath.Join(s.dir, "."+name)
+	err = WriteFile(tmpPath, archive)
+	if err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+
+	err = os.Rename(tmpPath, filepath.Join(s.dir, name))
+	if err != nil {
+		return "", err
+	}
+
+	removed, err := Prune(s.dir, s.retention)
+	if err != nil {
+		return name, fmt.Errorf("failed to remove old backups: %w", err)
+	}
+
+	for _, old := range removed {
+		logrus.Infoln("removed old backup", old)
+	}
+
+	return name, nil
+}
+
+// FileName returns name of scheduled backup file that created at t.
+func FileName(t time.Time) string {
+	return "duit-" + t.Format(fileLayout) + ".json.gz"
+}
+
+// ValidFileName checks whether name is the name of scheduled backup file.
+// It's used to make sure the name doesn't point outside backup directory.
+func ValidFileName(name string) bool {
+	return rxFileName.MatchString(name)
+}
+
+// ListFiles returns scheduled backup files inside dir, sorted from the
+// newest. Files whose name is not the scheduled backup's are ignored.
+func ListFiles(dir string) ([]File, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []File{}
+	for _, info := range infos {
+		match := rxFileName.FindStringSubmatch(info.Name())
+		if match == nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		createdAt, err := time.ParseInLocation(fileLayout, match[1], time.Local)
+		if err != nil {
+			continue
+		}
+
+		files = append(files, File{
+			Name:      info.Name(),
+			Size:      info.Size(),
+			CreatedAt: createdAt,
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].CreatedAt.After(files[j].CreatedAt)
+	})
+
+	return files, nil
+}
+
+// Prune removes the backup files in dir that are outside retention.
+// It returns names of the removed files.
+func Prune(dir string, retention Retention) ([]string, error) {
+	files, err := ListFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := make(map[string]bool)
+	periods := []struct {
+		limit int
+		key   func(time.Time) string
+	}{
+		{retention.Daily, func(t time.Time) string {
+			return t.Format("2006-01-02")
+		}},
+		{retention.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%d", year, week)
+		}},
+		{retention.Monthly, func(t time.Time) string {
+			return t.Format("2006-01")
+		}},
+	}
+
+	// Since files sorted from the newest, the first
+	// file in each period is the latest backup in it
+	for _, period := range periods {
+		seen := make(map[string]bool)
+		for _, file := range files {
+			if len(seen) >= period.limit {
+				break
+			}
+
+			key := period.key(file.CreatedAt)
+			if !seen[key] {
+				seen[key] = true
+				keep[file.Name] = true
+			}
+		}
+	}
+
+	removed := []string{}
+	for _, file := range files {
+		if keep[file.Name] {
+			continue
+		}
+
+		err = os.Remove(filepath.Join(dir, file.Name))
+		if err != nil {
+			return removed, err
+		}
+
+		removed = append(removed, file.Name)
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,102 @@
+package backup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "duit-backup")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Around new year, where 2020-12-28 until 2021-01-03 is the
+	// 53rd ISO week of 2020 although it spans two calendar years
+	at := func(date string, hour int) string {
+		t, _ := time.ParseInLocation("2006-01-02", date, time.Local)
+		return FileName(t.Add(time.Duration(hour) * time.Hour))
+	}
+
+	names := map[string]string{
+		"a": at("2021-01-04", 10), // Monday, week 1 of 2021
+		"b": at("2021-01-04", 2),  // older backup in the same day
+		"c": at("2021-01-03", 2),  // Sunday, week 53 of 2020
+		"d": at("2021-01-01", 2),  // week 53 of 2020
+		"e": at("2020-12-31", 2),  // week 53 of 2020, latest of December
+		"f": at("2020-12-28", 2),  // week 53 of 2020
+		"g": at("2020-12-27", 2),  // week 52 of 2020
+		"h": at("2020-11-30", 2),  // November
+	}
+
+	for _, name := range append(mapValues(names), "notes.txt") {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte("{}"), os.ModePerm)
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	// Daily keeps a and c, weekly keeps a, c and g,
+	// while monthly keeps a and e.
+	removed, err := Prune(dir, Retention{Daily: 2, Weekly: 3, Monthly: 2})
+	if err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+
+	expected := []string{names["b"], names["d"], names["f"], names["h"]}
+	sort.Strings(expected)
+	sort.Strings(removed)
+	if !reflect.DeepEqual(removed, expected) {
+		t.Errorf("expected removed %v, got %v", expected, removed)
+	}
+
+	files, err := ListFiles(dir)
+	if err != nil {
+		t.Fatalf("failed to list files: %v", err)
+	}
+
+	kept := []string{}
+	for _, file := range files {
+		kept = append(kept, file.Name)
+	}
+
+	expected = []string{names["a"], names["c"], names["e"], names["g"]}
+	if !reflect.DeepEqual(kept, expected) {
+		t.Errorf("expected kept %v from the newest, got %v", expected, kept)
+	}
+
+	// File that is not a backup is never touched
+	if _, err = os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Errorf("expected other file to be kept: %v", err)
+	}
+}
+
+func TestValidFileName(t *testing.T) {
+	tests := map[string]bool{
+		FileName(time.Now()):              true,
+		"duit-20200101-020000.json.gz":    true,
+		"duit-20200101.json.gz":           false,
+		"../duit-20200101-020000.json.gz": false,
+		"duit-20200101-020000.json":       false,
+	}
+
+	for name, valid := range tests {
+		if ValidFileName(name) != valid {
+			t.Errorf("%q: expected valid %v", name, valid)
+		}
+	}
+}
+
+func mapValues(m map[string]string) []string {
+	values := []string{}
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,431 @@
+package database
+
+const ddlPostgresCreateUser = `
+CREATE TABLE IF NOT EXISTS "user" (
+	id       SERIAL      NOT NULL,
+	username VARCHAR(40) NOT NULL,
+	name     VARCHAR(80) NOT NULL,
+	password BYTEA       NOT NULL,
+	admin    BOOLEAN     NOT NULL DEFAULT TRUE,
+	PRIMARY KEY (id),
+	CONSTRAINT user_username_UNIQUE UNIQUE (username))
+`
+
+const ddlPostgresCreateAccount = `
+CREATE TABLE IF NOT EXISTS account (
+	id             SERIAL        NOT NULL,
+	name           VARCHAR(100)  NOT NULL,
+	initial_amount DECIMAL(20,4) NOT NULL DEFAULT 0,
+	PRIMARY KEY (id))
+`
+
+const ddlPostgresCreateEntry = `
+CREATE TABLE IF NOT EXISTS entry (
+	id                  SERIAL        NOT NULL,
+	account_id          INTEGER       NOT NULL,
+	affected_account_id INTEGER       DEFAULT NULL,
+	type                INTEGER       NOT NULL,
+	description         VARCHAR(150)  DEFAULT NULL,
+	amount              DECIMAL(20,4) NOT NULL,
+	date                DATE          NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT entry_account_id_FK FOREIGN KEY (account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT entry_affected_account_id_FK FOREIGN KEY (affected_account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CHECK (affected_account_id <> account_id),
+	CHECK (type >= 1 AND type <= 3))
+`
+
+const ddlPostgresCreateViewAccountTotal = `
+CREATE OR REPLACE VIEW account_total AS
+	WITH income AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 1
+		GROUP BY account_id),
+	expense AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 2
+		GROUP BY account_id),
+	moved AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 3
+		GROUP BY account_id),
+	received AS (
+		SELECT affected_account_id id, SUM(amount) amount FROM entry
+		WHERE type = 3
+		GROUP BY affected_account_id)
+	SELECT a.id, a.name, a.initial_amount,
+		a.initial_amount +
+		COALESCE(i.amount, 0) -
+		COALESCE(e.amount, 0) -
+		COALESCE(m.amount, 0) +
+		COALESCE(r.amount, 0) total
+	FROM account a
+	LEFT JOIN income i ON i.id = a.id
+	LEFT JOIN expense e ON e.id = a.id
+	LEFT JOIN moved m ON m.id = a.id
+	LEFT JOIN received r ON r.id = a.id
+`
+
+const ddlPostgresCreateViewCumulativeAmount = `
+CREATE OR REPLACE VIEW cumulative_amount AS
+	WITH entry_list AS (
+		SELECT id, account_id, affected_account_id, type,
+			description, amount, TO_CHAR(date, 'YYYY-MM') month
+		FROM entry),
+	account_list AS (
+		SELECT DISTINCT account_id id, month
+		FROM entry_list),
+	income AS (
+		SELECT account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 1
+		GROUP BY account_id, month),
+	expense AS (
+		SELECT account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 2
+		GROUP BY account_id, month),
+	moved AS (
+		SELECT account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 3
+		GROUP BY account_id, month),
+	received AS (
+		SELECT affected_account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 3
+		GROUP BY affected_account_id, month),
+	monthly_profit AS (
+		SELECT al.id account_id, al.month,
+			a.name, a.initial_amount,
+			COALESCE(i.amount, 0) income,
+			COALESCE(e.amount, 0) expense,
+			COALESCE(m.amount, 0) moved,
+			COALESCE(r.amount, 0) received,
+			COALESCE(i.amount, 0) -
+			COALESCE(e.amount, 0) -
+			COALESCE(m.amount, 0) +
+			COALESCE(r.amount, 0) profit
+		FROM account_list al
+		LEFT JOIN account a ON al.id = a.id
+		LEFT JOIN income i ON i.id = al.id AND i.month = al.month
+		LEFT JOIN expense e ON e.id = al.id AND e.month = al.month
+		LEFT JOIN moved m ON m.id = al.id AND m.month = al.month
+		LEFT JOIN received r ON r.id = al.id AND r.month = al.month)
+	SELECT account_id, month,
+		SUM(profit) OVER (PARTITION BY account_id ORDER BY month) + initial_amount amount
+	FROM monthly_profit
+`
+
+const ddlPostgresCreateCategory = `
+CREATE TABLE IF NOT EXISTS category (
+	id        SERIAL       NOT NULL,
+	parent_id INTEGER      DEFAULT NULL,
+	name      VARCHAR(100) NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT category_parent_id_FK FOREIGN KEY (parent_id) REFERENCES category (id)
+		ON DELETE SET NULL)
+`
+
+const ddlPostgresEntryAddCategory = `
+ALTER TABLE entry
+	ADD COLUMN category_id INTEGER DEFAULT NULL,
+	ADD CONSTRAINT entry_category_id_FK FOREIGN KEY (category_id) REFERENCES category (id)
+		ON UPDATE CASCADE ON DELETE SET NULL
+`
+
+const ddlPostgresCreateTag = `
+CREATE TABLE IF NOT EXISTS tag (
+	id   SERIAL      NOT NULL,
+	name VARCHAR(50) NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT tag_name_UNIQUE UNIQUE (name))
+`
+
+const ddlPostgresCreateEntryTag = `
+CREATE TABLE IF NOT EXISTS entry_tag (
+	entry_id INTEGER NOT NULL,
+	tag_id   INTEGER NOT NULL,
+	PRIMARY KEY (entry_id, tag_id),
+	CONSTRAINT entry_tag_entry_id_FK FOREIGN KEY (entry_id) REFERENCES entry (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT entry_tag_tag_id_FK FOREIGN KEY (tag_id) REFERENCES tag (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+`
+
+const ddlPostgresCreateEntrySplit = `
+CREATE TABLE IF NOT EXISTS entry_split (
+	id          SERIAL        NOT NULL,
+	entry_id    INTEGER       NOT NULL,
+	category_id INTEGER       DEFAULT NULL,
+	description VARCHAR(150)  DEFAULT NULL,
+	amount      DECIMAL(20,4) NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT entry_split_entry_id_FK FOREIGN KEY (entry_id) REFERENCES entry (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT entry_split_category_id_FK FOREIGN KEY (category_id) REFERENCES category (id)
+		ON UPDATE CASCADE ON DELETE SET NULL)
+`
+
+const ddlPostgresCreateCurrencyRate = `
+CREATE TABLE IF NOT EXISTS currency_rate (
+	id            SERIAL        NOT NULL,
+	date          DATE          NOT NULL,
+	from_currency CHAR(3)       NOT NULL,
+	to_currency   CHAR(3)       NOT NULL,
+	rate          DECIMAL(20,8) NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT currency_rate_UNIQUE UNIQUE (date, from_currency, to_currency))
+`
+
+const ddlPostgresAccountAddCurrency = `
+ALTER TABLE account
+	ADD COLUMN currency CHAR(3) DEFAULT NULL
+`
+
+const ddlPostgresAccountDropCurrency = `
+ALTER TABLE account
+	DROP COLUMN currency
+`
+
+const ddlPostgresEntryAddAffectedAmount = `
+ALTER TABLE entry
+	ADD COLUMN affected_amount DECIMAL(20,4) DEFAULT NULL
+`
+
+const ddlPostgresEntryDropAffectedAmount = `
+ALTER TABLE entry
+	DROP COLUMN affected_amount
+`
+
+// In transfer between accounts with different currency, the amount
+// received by destination account is saved in affected_amount.
+const ddlPostgresCreateViewAccountTotalWithCurrency = `
+CREATE VIEW account_total AS
+	WITH income AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 1
+		GROUP BY account_id),
+	expense AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 2
+		GROUP BY account_id),
+	moved AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 3
+		GROUP BY account_id),
+	received AS (
+		SELECT affected_account_id id,
+			SUM(COALESCE(affected_amount, amount)) amount FROM entry
+		WHERE type = 3
+		GROUP BY affected_account_id)
+	SELECT a.id, a.name, a.currency, a.initial_amount,
+		a.initial_amount +
+		COALESCE(i.amount, 0) -
+		COALESCE(e.amount, 0) -
+		COALESCE(m.amount, 0) +
+		COALESCE(r.amount, 0) total
+	FROM account a
+	LEFT JOIN income i ON i.id = a.id
+	LEFT JOIN expense e ON e.id = a.id
+	LEFT JOIN moved m ON m.id = a.id
+	LEFT JOIN received r ON r.id = a.id
+`
+
+const ddlPostgresCreateViewCumulativeAmountWithCurrency = `
+CREATE VIEW cumulative_amount AS
+	WITH entry_list AS (
+		SELECT id, account_id, affected_account_id, type,
+			description, amount, affected_amount, TO_CHAR(date, 'YYYY-MM') month
+		FROM entry),
+	account_list AS (
+		SELECT DISTINCT account_id id, month
+		FROM entry_list),
+	income AS (
+		SELECT account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 1
+		GROUP BY account_id, month),
+	expense AS (
+		SELECT account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 2
+		GROUP BY account_id, month),
+	moved AS (
+		SELECT account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 3
+		GROUP BY account_id, month),
+	received AS (
+		SELECT affected_account_id id, month,
+			SUM(COALESCE(affected_amount, amount)) amount
+		FROM entry_list
+		WHERE type = 3
+		GROUP BY affected_account_id, month),
+	monthly_profit AS (
+		SELECT al.id account_id, al.month,
+			a.name, a.initial_amount,
+			COALESCE(i.amount, 0) income,
+			COALESCE(e.amount, 0) expense,
+			COALESCE(m.amount, 0) moved,
+			COALESCE(r.amount, 0) received,
+			COALESCE(i.amount, 0) -
+			COALESCE(e.amount, 0) -
+			COALESCE(m.amount, 0) +
+			COALESCE(r.amount, 0) profit
+		FROM account_list al
+		LEFT JOIN account a ON al.id = a.id
+		LEFT JOIN income i ON i.id = al.id AND i.month = al.month
+		LEFT JOIN expense e ON e.id = al.id AND e.month = al.month
+		LEFT JOIN moved m ON m.id = al.id AND m.month = al.month
+		LEFT JOIN received r ON r.id = al.id AND r.month = al.month)
+	SELECT account_id, month,
+		SUM(profit) OVER (PARTITION BY account_id ORDER BY month) + initial_amount amount
+	FROM monthly_profit
+`
+
+const ddlPostgresCreateRecurring = `
+CREATE TABLE IF NOT EXISTS recurring (
+	id                  SERIAL        NOT NULL,
+	account_id          INTEGER       NOT NULL,
+	affected_account_id INTEGER       DEFAULT NULL,
+	category_id         INTEGER       DEFAULT NULL,
+	type                INTEGER       NOT NULL,
+	description         VARCHAR(150)  DEFAULT NULL,
+	amount              DECIMAL(20,4) NOT NULL,
+	affected_amount     DECIMAL(20,4) DEFAULT NULL,
+	frequency           VARCHAR(10)   NOT NULL,
+	repeat_every        INTEGER       NOT NULL DEFAULT 1,
+	day_rule            VARCHAR(30)   NOT NULL DEFAULT '',
+	start_date          DATE          NOT NULL,
+	end_date            DATE          DEFAULT NULL,
+	max_count           INTEGER       DEFAULT NULL,
+	last_seq            INTEGER       NOT NULL DEFAULT 0,
+	last_date           DATE          DEFAULT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT recurring_account_id_FK FOREIGN KEY (account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT recurring_affected_account_id_FK FOREIGN KEY (affected_account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT recurring_category_id_FK FOREIGN KEY (category_id) REFERENCES category (id)
+		ON UPDATE CASCADE ON DELETE SET NULL)
+`
+
+const ddlPostgresEntryAddRecurring = `
+ALTER TABLE entry
+	ADD COLUMN recurring_id INTEGER DEFAULT NULL,
+	ADD CONSTRAINT entry_recurring_id_FK FOREIGN KEY (recurring_id) REFERENCES recurring (id)
+		ON UPDATE CASCADE ON DELETE SET NULL
+`
+
+const ddlPostgresCreateBudget = `
+CREATE TABLE IF NOT EXISTS budget (
+	id          SERIAL        NOT NULL,
+	category_id INTEGER       DEFAULT NULL,
+	account_id  INTEGER       DEFAULT NULL,
+	amount      DECIMAL(20,4) NOT NULL,
+	rollover    BOOLEAN       NOT NULL DEFAULT FALSE,
+	start_month CHAR(7)       NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT budget_category_id_FK FOREIGN KEY (category_id) REFERENCES category (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT budget_account_id_FK FOREIGN KEY (account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CHECK (category_id IS NOT NULL OR account_id IS NOT NULL))
+`
+
+const ddlPostgresCreateImportProfile = `
+CREATE TABLE IF NOT EXISTS import_profile (
+	id         SERIAL       NOT NULL,
+	name       VARCHAR(100) NOT NULL,
+	account_id INTEGER      DEFAULT NULL,
+	mapping    TEXT         NOT NULL,
+	PRIMARY KEY (id),
+	CONSTRAINT import_profile_name_UNIQUE UNIQUE (name),
+	CONSTRAINT import_profile_account_id_FK FOREIGN KEY (account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE SET NULL)
+`
+
+const ddlPostgresEntryAddImportID = `
+ALTER TABLE entry
+	ADD COLUMN import_id VARCHAR(255) DEFAULT NULL,
+	ADD CONSTRAINT entry_import_id_UNIQUE UNIQUE (account_id, import_id)
+`
+
+const ddlPostgresEntryDropImportID = `
+ALTER TABLE entry
+	DROP CONSTRAINT entry_import_id_UNIQUE,
+	DROP COLUMN import_id
+`
+
+// The index must use the same expression as the one used in search
+// query, otherwise PostgreSQL won't use it.
+const ddlPostgresCreateEntryDescriptionIndex = `
+CREATE INDEX IF NOT EXISTS entry_description_fts
+	ON entry USING GIN (to_tsvector('simple', COALESCE(description, '')))
+`
+
+const ddlPostgresCreateSession = `
+CREATE TABLE IF NOT EXISTS session (
+	id         CHAR(64)     NOT NULL,
+	user_id    INTEGER      NOT NULL,
+	expires_at BIGINT       NOT NULL,
+	last_seen  BIGINT       NOT NULL,
+	user_agent VARCHAR(255) NOT NULL DEFAULT '',
+	PRIMARY KEY (id),
+	CONSTRAINT session_user_id_FK FOREIGN KEY (user_id) REFERENCES "user" (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+`
+
+const ddlPostgresCreateSessionExpiresAtIndex = `
+CREATE INDEX IF NOT EXISTS session_expires_at_IDX ON session (expires_at)
+`
+
+const ddlPostgresSessionAddClient = `
+ALTER TABLE session
+	ADD COLUMN created_at BIGINT      NOT NULL DEFAULT 0,
+	ADD COLUMN ip_address VARCHAR(45) NOT NULL DEFAULT ''
+`
+
+const ddlPostgresSessionDropClient = `
+ALTER TABLE session
+	DROP COLUMN created_at,
+	DROP COLUMN ip_address
+`
+
+const ddlPostgresCreateUserTOTP = `
+CREATE TABLE IF NOT EXISTS user_totp (
+	user_id   INTEGER     NOT NULL,
+	secret    VARCHAR(64) NOT NULL,
+	enabled   BOOLEAN     NOT NULL DEFAULT FALSE,
+	last_step BIGINT      NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id),
+	CONSTRAINT user_totp_user_id_FK FOREIGN KEY (user_id) REFERENCES "user" (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+`
+
+const ddlPostgresCreateRecoveryCode = `
+CREATE TABLE IF NOT EXISTS recovery_code (
+	user_id   INTEGER  NOT NULL,
+	code_hash CHAR(64) NOT NULL,
+	PRIMARY KEY (user_id, code_hash),
+	CONSTRAINT recovery_code_user_id_FK FOREIGN KEY (user_id) REFERENCES "user" (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+`
+
+const ddlPostgresCreateAPIToken = `
+CREATE TABLE IF NOT EXISTS api_token (
+	id         SERIAL       NOT NULL,
+	user_id    INTEGER      NOT NULL,
+	name       VARCHAR(100) NOT NULL,
+	scope      VARCHAR(20)  NOT NULL,
+	token_hash CHAR(64)     NOT NULL,
+	created_at BIGINT       NOT NULL,
+	last_used  BIGINT       NOT NULL DEFAULT 0,
+	PRIMARY KEY (id),
+	CONSTRAINT api_token_token_hash_UNIQUE UNIQUE (token_hash),
+	CONSTRAINT api_token_user_id_FK FOREIGN KEY (user_id) REFERENCES "user" (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+`
@@ -0,0 +1,432 @@
+package database
+
+const ddlSQLiteCreateUser = `
+CREATE TABLE IF NOT EXISTS user (
+	id       INTEGER     NOT NULL PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(40) NOT NULL,
+	name     VARCHAR(80) NOT NULL,
+	password BLOB        NOT NULL,
+	admin    BOOLEAN     NOT NULL DEFAULT 1,
+	CONSTRAINT user_username_UNIQUE UNIQUE (username))
+`
+
+const ddlSQLiteCreateAccount = `
+CREATE TABLE IF NOT EXISTS account (
+	id             INTEGER       NOT NULL PRIMARY KEY AUTOINCREMENT,
+	name           VARCHAR(100)  NOT NULL,
+	initial_amount DECIMAL(20,4) NOT NULL DEFAULT 0)
+`
+
+// SQLite doesn't have DATE type, so here date is saved as text
+// with format YYYY-MM-DD.
+const ddlSQLiteCreateEntry = `
+CREATE TABLE IF NOT EXISTS entry (
+	id                  INTEGER       NOT NULL PRIMARY KEY AUTOINCREMENT,
+	account_id          INTEGER       NOT NULL,
+	affected_account_id INTEGER       DEFAULT NULL,
+	type                INTEGER       NOT NULL,
+	description         VARCHAR(150)  DEFAULT NULL,
+	amount              DECIMAL(20,4) NOT NULL,
+	date                TEXT          NOT NULL,
+	CONSTRAINT entry_account_id_FK FOREIGN KEY (account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT entry_affected_account_id_FK FOREIGN KEY (affected_account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CHECK (affected_account_id <> account_id),
+	CHECK (type >= 1 AND type <= 3))
+`
+
+const ddlSQLiteCreateViewAccountTotal = `
+CREATE VIEW IF NOT EXISTS account_total AS
+	WITH income AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 1
+		GROUP BY account_id),
+	expense AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 2
+		GROUP BY account_id),
+	moved AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 3
+		GROUP BY account_id),
+	received AS (
+		SELECT affected_account_id id, SUM(amount) amount FROM entry
+		WHERE type = 3
+		GROUP BY affected_account_id)
+	SELECT a.id, a.name, a.initial_amount,
+		a.initial_amount +
+		IFNULL(i.amount, 0) -
+		IFNULL(e.amount, 0) -
+		IFNULL(m.amount, 0) +
+		IFNULL(r.amount, 0) total
+	FROM account a
+	LEFT JOIN income i ON i.id = a.id
+	LEFT JOIN expense e ON e.id = a.id
+	LEFT JOIN moved m ON m.id = a.id
+	LEFT JOIN received r ON r.id = a.id
+`
+
+const ddlSQLiteCreateViewCumulativeAmount = `
+CREATE VIEW IF NOT EXISTS cumulative_amount AS
+	WITH entry_list AS (
+		SELECT id, account_id, affected_account_id, type,
+			description, amount, STRFTIME('%Y-%m', date) month
+		FROM entry),
+	account_list AS (
+		SELECT DISTINCT account_id id, month
+		FROM entry_list),
+	income AS (
+		SELECT account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 1
+		GROUP BY account_id, month),
+	expense AS (
+		SELECT account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 2
+		GROUP BY account_id, month),
+	moved AS (
+		SELECT account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 3
+		GROUP BY account_id, month),
+	received AS (
+		SELECT affected_account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 3
+		GROUP BY affected_account_id, month),
+	monthly_profit AS (
+		SELECT al.id account_id, al.month,
+			a.name, a.initial_amount,
+			IFNULL(i.amount, 0) income,
+			IFNULL(e.amount, 0) expense,
+			IFNULL(m.amount, 0) moved,
+			IFNULL(r.amount, 0) received,
+			IFNULL(i.amount, 0) -
+			IFNULL(e.amount, 0) -
+			IFNULL(m.amount, 0) +
+			IFNULL(r.amount, 0) profit
+		FROM account_list al
+		LEFT JOIN account a ON al.id = a.id
+		LEFT JOIN income i ON i.id = al.id AND i.month = al.month
+		LEFT JOIN expense e ON e.id = al.id AND e.month = al.month
+		LEFT JOIN moved m ON m.id = al.id AND m.month = al.month
+		LEFT JOIN received r ON r.id = al.id AND r.month = al.month)
+	SELECT account_id, month,
+		SUM(profit) OVER (PARTITION BY account_id ORDER BY month) + initial_amount amount
+	FROM monthly_profit
+`
+
+const ddlSQLiteCreateCategory = `
+CREATE TABLE IF NOT EXISTS category (
+	id        INTEGER      NOT NULL PRIMARY KEY AUTOINCREMENT,
+	parent_id INTEGER      DEFAULT NULL,
+	name      VARCHAR(100) NOT NULL,
+	CONSTRAINT category_parent_id_FK FOREIGN KEY (parent_id) REFERENCES category (id)
+		ON DELETE SET NULL)
+`
+
+const ddlSQLiteEntryAddCategory = `
+ALTER TABLE entry
+	ADD COLUMN category_id INTEGER DEFAULT NULL
+	REFERENCES category (id) ON UPDATE CASCADE ON DELETE SET NULL
+`
+
+const ddlSQLiteCreateTag = `
+CREATE TABLE IF NOT EXISTS tag (
+	id   INTEGER     NOT NULL PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(50) NOT NULL,
+	CONSTRAINT tag_name_UNIQUE UNIQUE (name))
+`
+
+const ddlSQLiteCreateEntryTag = `
+CREATE TABLE IF NOT EXISTS entry_tag (
+	entry_id INTEGER NOT NULL,
+	tag_id   INTEGER NOT NULL,
+	PRIMARY KEY (entry_id, tag_id),
+	CONSTRAINT entry_tag_entry_id_FK FOREIGN KEY (entry_id) REFERENCES entry (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT entry_tag_tag_id_FK FOREIGN KEY (tag_id) REFERENCES tag (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+`
+
+const ddlSQLiteCreateEntrySplit = `
+CREATE TABLE IF NOT EXISTS entry_split (
+	id          INTEGER       NOT NULL PRIMARY KEY AUTOINCREMENT,
+	entry_id    INTEGER       NOT NULL,
+	category_id INTEGER       DEFAULT NULL,
+	description VARCHAR(150)  DEFAULT NULL,
+	amount      DECIMAL(20,4) NOT NULL,
+	CONSTRAINT entry_split_entry_id_FK FOREIGN KEY (entry_id) REFERENCES entry (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT entry_split_category_id_FK FOREIGN KEY (category_id) REFERENCES category (id)
+		ON UPDATE CASCADE ON DELETE SET NULL)
+`
+
+const ddlSQLiteCreateCurrencyRate = `
+CREATE TABLE IF NOT EXISTS currency_rate (
+	id            INTEGER       NOT NULL PRIMARY KEY AUTOINCREMENT,
+	date          TEXT          NOT NULL,
+	from_currency CHAR(3)       NOT NULL,
+	to_currency   CHAR(3)       NOT NULL,
+	rate          DECIMAL(20,8) NOT NULL,
+	CONSTRAINT currency_rate_UNIQUE UNIQUE (date, from_currency, to_currency))
+`
+
+const ddlSQLiteAccountAddCurrency = `
+ALTER TABLE account
+	ADD COLUMN currency CHAR(3) DEFAULT NULL
+`
+
+const ddlSQLiteEntryAddAffectedAmount = `
+ALTER TABLE entry
+	ADD COLUMN affected_amount DECIMAL(20,4) DEFAULT NULL
+`
+
+// In transfer between accounts with different currency, the amount
+// received by destination account is saved in affected_amount.
+const ddlSQLiteCreateViewAccountTotalWithCurrency = `
+CREATE VIEW account_total AS
+	WITH income AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 1
+		GROUP BY account_id),
+	expense AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 2
+		GROUP BY account_id),
+	moved AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 3
+		GROUP BY account_id),
+	received AS (
+		SELECT affected_account_id id,
+			SUM(COALESCE(affected_amount, amount)) amount FROM entry
+		WHERE type = 3
+		GROUP BY affected_account_id)
+	SELECT a.id, a.name, a.currency, a.initial_amount,
+		a.initial_amount +
+		IFNULL(i.amount, 0) -
+		IFNULL(e.amount, 0) -
+		IFNULL(m.amount, 0) +
+		IFNULL(r.amount, 0) total
+	FROM account a
+	LEFT JOIN income i ON i.id = a.id
+	LEFT JOIN expense e ON e.id = a.id
+	LEFT JOIN moved m ON m.id = a.id
+	LEFT JOIN received r ON r.id = a.id
+`
+
+const ddlSQLiteCreateViewCumulativeAmountWithCurrency = `
+CREATE VIEW cumulative_amount AS
+	WITH entry_list AS (
+		SELECT id, account_id, affected_account_id, type,
+			description, amount, affected_amount, STRFTIME('%Y-%m', date) month
+		FROM entry),
+	account_list AS (
+		SELECT DISTINCT account_id id, month
+		FROM entry_list),
+	income AS (
+		SELECT account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 1
+		GROUP BY account_id, month),
+	expense AS (
+		SELECT account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 2
+		GROUP BY account_id, month),
+	moved AS (
+		SELECT account_id id, month, SUM(amount) amount
+		FROM entry_list
+		WHERE type = 3
+		GROUP BY account_id, month),
+	received AS (
+		SELECT affected_account_id id, month,
+			SUM(COALESCE(affected_amount, amount)) amount
+		FROM entry_list
+		WHERE type = 3
+		GROUP BY affected_account_id, month),
+	monthly_profit AS (
+		SELECT al.id account_id, al.month,
+			a.name, a.initial_amount,
+			IFNULL(i.amount, 0) income,
+			IFNULL(e.amount, 0) expense,
+			IFNULL(m.amount, 0) moved,
+			IFNULL(r.amount, 0) received,
+			IFNULL(i.amount, 0) -
+			IFNULL(e.amount, 0) -
+			IFNULL(m.amount, 0) +
+			IFNULL(r.amount, 0) profit
+		FROM account_list al
+		LEFT JOIN account a ON al.id = a.id
+		LEFT JOIN income i ON i.id = al.id AND i.month = al.month
+		LEFT JOIN expense e ON e.id = al.id AND e.month = al.month
+		LEFT JOIN moved m ON m.id = al.id AND m.month = al.month
+		LEFT JOIN received r ON r.id = al.id AND r.month = al.month)
+	SELECT account_id, month,
+		SUM(profit) OVER (PARTITION BY account_id ORDER BY month) + initial_amount amount
+	FROM monthly_profit
+`
+
+const ddlSQLiteCreateRecurring = `
+CREATE TABLE IF NOT EXISTS recurring (
+	id                  INTEGER       NOT NULL PRIMARY KEY AUTOINCREMENT,
+	account_id          INTEGER       NOT NULL,
+	affected_account_id INTEGER       DEFAULT NULL,
+	category_id         INTEGER       DEFAULT NULL,
+	type                INTEGER       NOT NULL,
+	description         VARCHAR(150)  DEFAULT NULL,
+	amount              DECIMAL(20,4) NOT NULL,
+	affected_amount     DECIMAL(20,4) DEFAULT NULL,
+	frequency           VARCHAR(10)   NOT NULL,
+	repeat_every        INTEGER       NOT NULL DEFAULT 1,
+	day_rule            VARCHAR(30)   NOT NULL DEFAULT '',
+	start_date          TEXT          NOT NULL,
+	end_date            TEXT          DEFAULT NULL,
+	max_count           INTEGER       DEFAULT NULL,
+	last_seq            INTEGER       NOT NULL DEFAULT 0,
+	last_date           TEXT          DEFAULT NULL,
+	CONSTRAINT recurring_account_id_FK FOREIGN KEY (account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT recurring_affected_account_id_FK FOREIGN KEY (affected_account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT recurring_category_id_FK FOREIGN KEY (category_id) REFERENCES category (id)
+		ON UPDATE CASCADE ON DELETE SET NULL)
+`
+
+const ddlSQLiteEntryAddRecurring = `
+ALTER TABLE entry
+	ADD COLUMN recurring_id INTEGER DEFAULT NULL
+	REFERENCES recurring (id) ON UPDATE CASCADE ON DELETE SET NULL
+`
+
+const ddlSQLiteCreateBudget = `
+CREATE TABLE IF NOT EXISTS budget (
+	id          INTEGER       NOT NULL PRIMARY KEY AUTOINCREMENT,
+	category_id INTEGER       DEFAULT NULL,
+	account_id  INTEGER       DEFAULT NULL,
+	amount      DECIMAL(20,4) NOT NULL,
+	rollover    BOOLEAN       NOT NULL DEFAULT 0,
+	start_month CHAR(7)       NOT NULL,
+	CONSTRAINT budget_category_id_FK FOREIGN KEY (category_id) REFERENCES category (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT budget_account_id_FK FOREIGN KEY (account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CHECK (category_id IS NOT NULL OR account_id IS NOT NULL))
+`
+
+const ddlSQLiteCreateImportProfile = `
+CREATE TABLE IF NOT EXISTS import_profile (
+	id         INTEGER      NOT NULL PRIMARY KEY AUTOINCREMENT,
+	name       VARCHAR(100) NOT NULL,
+	account_id INTEGER      DEFAULT NULL,
+	mapping    TEXT         NOT NULL,
+	CONSTRAINT import_profile_name_UNIQUE UNIQUE (name),
+	CONSTRAINT import_profile_account_id_FK FOREIGN KEY (account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE SET NULL)
+`
+
+const ddlSQLiteEntryAddImportID = `
+ALTER TABLE entry
+	ADD COLUMN import_id VARCHAR(255) DEFAULT NULL
+`
+
+const ddlSQLiteCreateEntryImportIDIndex = `
+CREATE UNIQUE INDEX IF NOT EXISTS entry_import_id_UNIQUE
+	ON entry (account_id, import_id)
+`
+
+// entry_fts is full-text index for entry's description. It doesn't store
+// the description, so it must be kept in sync with entry using triggers.
+const ddlSQLiteCreateEntryFTS = `
+CREATE VIRTUAL TABLE IF NOT EXISTS entry_fts
+	USING fts4(content="entry", description, tokenize=unicode61)
+`
+
+const ddlSQLiteRebuildEntryFTS = `
+INSERT INTO entry_fts (entry_fts) VALUES ('rebuild')
+`
+
+const ddlSQLiteCreateEntryFTSTriggerBU = `
+CREATE TRIGGER IF NOT EXISTS entry_fts_bu BEFORE UPDATE ON entry BEGIN
+	DELETE FROM entry_fts WHERE docid = old.id;
+END
+`
+
+const ddlSQLiteCreateEntryFTSTriggerBD = `
+CREATE TRIGGER IF NOT EXISTS entry_fts_bd BEFORE DELETE ON entry BEGIN
+	DELETE FROM entry_fts WHERE docid = old.id;
+END
+`
+
+const ddlSQLiteCreateEntryFTSTriggerAU = `
+CREATE TRIGGER IF NOT EXISTS entry_fts_au AFTER UPDATE ON entry BEGIN
+	INSERT INTO entry_fts (docid, description) VALUES (new.id, new.description);
+END
+`
+
+const ddlSQLiteCreateEntryFTSTriggerAI = `
+CREATE TRIGGER IF NOT EXISTS entry_fts_ai AFTER INSERT ON entry BEGIN
+	INSERT INTO entry_fts (docid, description) VALUES (new.id, new.description);
+END
+`
+
+const ddlSQLiteCreateSession = `
+CREATE TABLE IF NOT EXISTS session (
+	id         CHAR(64)     NOT NULL PRIMARY KEY,
+	user_id    INTEGER      NOT NULL,
+	expires_at INTEGER      NOT NULL,
+	last_seen  INTEGER      NOT NULL,
+	user_agent VARCHAR(255) NOT NULL DEFAULT '',
+	CONSTRAINT session_user_id_FK FOREIGN KEY (user_id) REFERENCES user (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+`
+
+const ddlSQLiteCreateSessionExpiresAtIndex = `
+CREATE INDEX IF NOT EXISTS session_expires_at_IDX ON session (expires_at)
+`
+
+const ddlSQLiteSessionAddCreatedAt = `
+ALTER TABLE session
+	ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0
+`
+
+const ddlSQLiteSessionAddIPAddress = `
+ALTER TABLE session
+	ADD COLUMN ip_address VARCHAR(45) NOT NULL DEFAULT ''
+`
+
+const ddlSQLiteCreateUserTOTP = `
+CREATE TABLE IF NOT EXISTS user_totp (
+	user_id   INTEGER     NOT NULL PRIMARY KEY,
+	secret    VARCHAR(64) NOT NULL,
+	enabled   BOOLEAN     NOT NULL DEFAULT 0,
+	last_step INTEGER     NOT NULL DEFAULT 0,
+	CONSTRAINT user_totp_user_id_FK FOREIGN KEY (user_id) REFERENCES user (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+`
+
+const ddlSQLiteCreateRecoveryCode = `
+CREATE TABLE IF NOT EXISTS recovery_code (
+	user_id   INTEGER  NOT NULL,
+	code_hash CHAR(64) NOT NULL,
+	PRIMARY KEY (user_id, code_hash),
+	CONSTRAINT recovery_code_user_id_FK FOREIGN KEY (user_id) REFERENCES user (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+`
+
+const ddlSQLiteCreateAPIToken = `
+CREATE TABLE IF NOT EXISTS api_token (
+	id         INTEGER      NOT NULL PRIMARY KEY AUTOINCREMENT,
+	user_id    INTEGER      NOT NULL,
+	name       VARCHAR(100) NOT NULL,
+	scope      VARCHAR(20)  NOT NULL,
+	token_hash CHAR(64)     NOT NULL,
+	created_at INTEGER      NOT NULL,
+	last_used  INTEGER      NOT NULL DEFAULT 0,
+	CONSTRAINT api_token_token_hash_UNIQUE UNIQUE (token_hash),
+	CONSTRAINT api_token_user_id_FK FOREIGN KEY (user_id) REFERENCES user (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+`
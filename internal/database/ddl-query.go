@@ -75,7 +75,7 @@ const ddlCreateViewCumulativeAmount = `
 CREATE VIEW IF NOT EXISTS cumulative_amount AS
 	WITH entry_list AS (
 		SELECT id, account_id, affected_account_id, type,
-			description, amount, DATE_FORMAT(date, "%Y-%m") month
+			description, amount, DATE_FORMAT(date, '%Y-%m') month
 		FROM entry),
 	account_list AS (
 		SELECT DISTINCT account_id id, month
@@ -121,3 +121,338 @@ CREATE VIEW IF NOT EXISTS cumulative_amount AS
 		SUM(profit) OVER (PARTITION BY account_id ORDER BY month) + initial_amount amount
 	FROM monthly_profit
 `
+
+const ddlCreateCategory = `
+CREATE TABLE IF NOT EXISTS category (
+	id        INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	parent_id INT UNSIGNED DEFAULT NULL,
+	name      VARCHAR(100) NOT NULL,
+	PRIMARY KEY (id),
+	FOREIGN KEY category_parent_id_FK (parent_id) REFERENCES category (id)
+		ON DELETE SET NULL)
+	CHARACTER SET utf8mb4
+`
+
+const ddlEntryAddCategory = `
+ALTER TABLE entry
+	ADD COLUMN category_id INT UNSIGNED DEFAULT NULL,
+	ADD CONSTRAINT entry_category_id_FK FOREIGN KEY (category_id) REFERENCES category (id)
+		ON UPDATE CASCADE ON DELETE SET NULL
+`
+
+const ddlEntryDropCategory = `
+ALTER TABLE entry
+	DROP FOREIGN KEY entry_category_id_FK,
+	DROP COLUMN category_id
+`
+
+const ddlCreateTag = `
+CREATE TABLE IF NOT EXISTS tag (
+	id   INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	name VARCHAR(50)  NOT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY tag_name_UNIQUE (name))
+	CHARACTER SET utf8mb4
+`
+
+const ddlCreateEntryTag = `
+CREATE TABLE IF NOT EXISTS entry_tag (
+	entry_id INT UNSIGNED NOT NULL,
+	tag_id   INT UNSIGNED NOT NULL,
+	PRIMARY KEY (entry_id, tag_id),
+	FOREIGN KEY entry_tag_entry_id_FK (entry_id) REFERENCES entry (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	FOREIGN KEY entry_tag_tag_id_FK (tag_id) REFERENCES tag (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+	CHARACTER SET utf8mb4
+`
+
+const ddlCreateEntrySplit = `
+CREATE TABLE IF NOT EXISTS entry_split (
+	id          INT UNSIGNED  NOT NULL AUTO_INCREMENT,
+	entry_id    INT UNSIGNED  NOT NULL,
+	category_id INT UNSIGNED  DEFAULT NULL,
+	description VARCHAR(150)  DEFAULT NULL,
+	amount      DECIMAL(20,4) NOT NULL,
+	PRIMARY KEY (id),
+	FOREIGN KEY entry_split_entry_id_FK (entry_id) REFERENCES entry (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	FOREIGN KEY entry_split_category_id_FK (category_id) REFERENCES category (id)
+		ON UPDATE CASCADE ON DELETE SET NULL)
+	CHARACTER SET utf8mb4
+`
+
+const ddlCreateCurrencyRate = `
+CREATE TABLE IF NOT EXISTS currency_rate (
+	id            INT UNSIGNED  NOT NULL AUTO_INCREMENT,
+	date          DATE          NOT NULL,
+	from_currency CHAR(3)       NOT NULL,
+	to_currency   CHAR(3)       NOT NULL,
+	rate          DECIMAL(20,8) NOT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY currency_rate_UNIQUE (date, from_currency, to_currency))
+	CHARACTER SET utf8mb4
+`
+
+const ddlAccountAddCurrency = `
+ALTER TABLE account
+	ADD COLUMN currency CHAR(3) DEFAULT NULL
+`
+
+const ddlAccountDropCurrency = `
+ALTER TABLE account
+	DROP COLUMN currency
+`
+
+const ddlEntryAddAffectedAmount = `
+ALTER TABLE entry
+	ADD COLUMN affected_amount DECIMAL(20,4) DEFAULT NULL
+`
+
+const ddlEntryDropAffectedAmount = `
+ALTER TABLE entry
+	DROP COLUMN affected_amount
+`
+
+// In transfer between accounts with different currency, the amount
+// received by destination account is saved in affected_amount.
+const ddlCreateViewAccountTotalWithCurrency = `
+CREATE VIEW account_total AS 
+	WITH income AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 1
+		GROUP BY account_id),
+	expense AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 2
+		GROUP BY account_id),
+	moved AS (
+		SELECT account_id id, SUM(amount) amount FROM entry
+		WHERE type = 3
+		GROUP BY account_id),
+	received AS (
+		SELECT affected_account_id id,
+			SUM(COALESCE(affected_amount, amount)) amount FROM entry
+		WHERE type = 3
+		GROUP BY affected_account_id)
+	SELECT a.id, a.name, a.currency, a.initial_amount,
+		a.initial_amount + 
+		IFNULL(i.amount, 0) - 
+		IFNULL(e.amount, 0) - 
+		IFNULL(m.amount, 0) + 
+		IFNULL(r.amount, 0) total
+	FROM account a
+	LEFT JOIN income i ON i.id = a.id
+	LEFT JOIN expense e ON e.id = a.id
+	LEFT JOIN moved m ON m.id = a.id
+	LEFT JOIN received r ON r.id = a.id
+`
+
+const ddlCreateViewCumulativeAmountWithCurrency = `
+CREATE VIEW cumulative_amount AS
+	WITH entry_list AS (
+		SELECT id, account_id, affected_account_id, type,
+			description, amount, affected_amount, DATE_FORMAT(date, '%Y-%m') month
+		FROM entry),
+	account_list AS (
+		SELECT DISTINCT account_id id, month
+		FROM entry_list),
+	income AS (
+		SELECT account_id id, month, SUM(amount) amount 
+		FROM entry_list
+		WHERE type = 1
+		GROUP BY account_id, month),
+	expense AS (
+		SELECT account_id id, month, SUM(amount) amount 
+		FROM entry_list
+		WHERE type = 2
+		GROUP BY account_id, month),
+	moved AS (
+		SELECT account_id id, month, SUM(amount) amount 
+		FROM entry_list
+		WHERE type = 3
+		GROUP BY account_id, month),
+	received AS (
+		SELECT affected_account_id id, month,
+			SUM(COALESCE(affected_amount, amount)) amount
+		FROM entry_list
+		WHERE type = 3
+		GROUP BY affected_account_id, month),
+	monthly_profit AS (
+		SELECT al.id account_id, al.month, 
+			a.name, a.initial_amount,
+			IFNULL(i.amount, 0) income,
+			IFNULL(e.amount, 0) expense,
+			IFNULL(m.amount, 0) moved,
+			IFNULL(r.amount, 0) received,
+			IFNULL(i.amount, 0) - 
+			IFNULL(e.amount, 0) - 
+			IFNULL(m.amount, 0) + 
+			IFNULL(r.amount, 0) profit
+		FROM account_list al
+		LEFT JOIN account a ON al.id = a.id
+		LEFT JOIN income i ON i.id = al.id AND i.month = al.month
+		LEFT JOIN expense e ON e.id = al.id AND e.month = al.month
+		LEFT JOIN moved m ON m.id = al.id AND m.month = al.month
+		LEFT JOIN received r ON r.id = al.id AND r.month = al.month)
+	SELECT account_id, month, 
+		SUM(profit) OVER (PARTITION BY account_id ORDER BY month) + initial_amount amount
+	FROM monthly_profit
+`
+
+const ddlCreateRecurring = `
+CREATE TABLE IF NOT EXISTS recurring (
+	id                  INT UNSIGNED  NOT NULL AUTO_INCREMENT,
+	account_id          INT UNSIGNED  NOT NULL,
+	affected_account_id INT UNSIGNED  DEFAULT NULL,
+	category_id         INT UNSIGNED  DEFAULT NULL,
+	type                INT UNSIGNED  NOT NULL,
+	description         VARCHAR(150)  DEFAULT NULL,
+	amount              DECIMAL(20,4) NOT NULL,
+	affected_amount     DECIMAL(20,4) DEFAULT NULL,
+	frequency           VARCHAR(10)   NOT NULL,
+	repeat_every        INT UNSIGNED  NOT NULL DEFAULT 1,
+	day_rule            VARCHAR(30)   NOT NULL DEFAULT '',
+	start_date          DATE          NOT NULL,
+	end_date            DATE          DEFAULT NULL,
+	max_count           INT UNSIGNED  DEFAULT NULL,
+	last_seq            INT UNSIGNED  NOT NULL DEFAULT 0,
+	last_date           DATE          DEFAULT NULL,
+	PRIMARY KEY (id),
+	FOREIGN KEY recurring_account_id_FK (account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	FOREIGN KEY recurring_affected_account_id_FK (affected_account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	FOREIGN KEY recurring_category_id_FK (category_id) REFERENCES category (id)
+		ON UPDATE CASCADE ON DELETE SET NULL)
+	CHARACTER SET utf8mb4
+`
+
+const ddlEntryAddRecurring = `
+ALTER TABLE entry
+	ADD COLUMN recurring_id INT UNSIGNED DEFAULT NULL,
+	ADD CONSTRAINT entry_recurring_id_FK FOREIGN KEY (recurring_id) REFERENCES recurring (id)
+		ON UPDATE CASCADE ON DELETE SET NULL
+`
+
+const ddlEntryDropRecurring = `
+ALTER TABLE entry
+	DROP FOREIGN KEY entry_recurring_id_FK,
+	DROP COLUMN recurring_id
+`
+
+const ddlCreateBudget = `
+CREATE TABLE IF NOT EXISTS budget (
+	id          INT UNSIGNED  NOT NULL AUTO_INCREMENT,
+	category_id INT UNSIGNED  DEFAULT NULL,
+	account_id  INT UNSIGNED  DEFAULT NULL,
+	amount      DECIMAL(20,4) NOT NULL,
+	rollover    BOOLEAN       NOT NULL DEFAULT 0,
+	start_month CHAR(7)       NOT NULL,
+	PRIMARY KEY (id),
+	FOREIGN KEY budget_category_id_FK (category_id) REFERENCES category (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	FOREIGN KEY budget_account_id_FK (account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE CASCADE,
+	CONSTRAINT CHECK (category_id IS NOT NULL OR account_id IS NOT NULL))
+	CHARACTER SET utf8mb4
+`
+
+const ddlCreateImportProfile = `
+CREATE TABLE IF NOT EXISTS import_profile (
+	id         INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	name       VARCHAR(100) NOT NULL,
+	account_id INT UNSIGNED DEFAULT NULL,
+	mapping    TEXT         NOT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY import_profile_name_UNIQUE (name),
+	FOREIGN KEY import_profile_account_id_FK (account_id) REFERENCES account (id)
+		ON UPDATE CASCADE ON DELETE SET NULL)
+	CHARACTER SET utf8mb4
+`
+
+const ddlEntryAddImportID = `
+ALTER TABLE entry
+	ADD COLUMN import_id VARCHAR(255) DEFAULT NULL,
+	ADD UNIQUE KEY entry_import_id_UNIQUE (account_id, import_id)
+`
+
+const ddlEntryDropImportID = `
+ALTER TABLE entry
+	DROP INDEX entry_import_id_UNIQUE,
+	DROP COLUMN import_id
+`
+
+const ddlEntryAddFulltext = `
+ALTER TABLE entry
+	ADD FULLTEXT INDEX entry_description_FULLTEXT (description)
+`
+
+const ddlEntryDropFulltext = `
+ALTER TABLE entry
+	DROP INDEX entry_description_FULLTEXT
+`
+
+const ddlCreateSession = `
+CREATE TABLE IF NOT EXISTS session (
+	id         CHAR(64)     NOT NULL,
+	user_id    INT UNSIGNED NOT NULL,
+	expires_at BIGINT       NOT NULL,
+	last_seen  BIGINT       NOT NULL,
+	user_agent VARCHAR(255) NOT NULL DEFAULT '',
+	PRIMARY KEY (id),
+	KEY session_expires_at_IDX (expires_at),
+	FOREIGN KEY session_user_id_FK (user_id) REFERENCES user (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+	CHARACTER SET utf8mb4
+`
+
+const ddlSessionAddClient = `
+ALTER TABLE session
+	ADD COLUMN created_at BIGINT      NOT NULL DEFAULT 0,
+	ADD COLUMN ip_address VARCHAR(45) NOT NULL DEFAULT ''
+`
+
+const ddlSessionDropClient = `
+ALTER TABLE session
+	DROP COLUMN created_at,
+	DROP COLUMN ip_address
+`
+
+const ddlCreateUserTOTP = `
+CREATE TABLE IF NOT EXISTS user_totp (
+	user_id   INT UNSIGNED NOT NULL,
+	secret    VARCHAR(64)  NOT NULL,
+	enabled   BOOLEAN      NOT NULL DEFAULT 0,
+	last_step BIGINT       NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id),
+	FOREIGN KEY user_totp_user_id_FK (user_id) REFERENCES user (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+	CHARACTER SET utf8mb4
+`
+
+const ddlCreateRecoveryCode = `
+CREATE TABLE IF NOT EXISTS recovery_code (
+	user_id   INT UNSIGNED NOT NULL,
+	code_hash CHAR(64)     NOT NULL,
+	PRIMARY KEY (user_id, code_hash),
+	FOREIGN KEY recovery_code_user_id_FK (user_id) REFERENCES user (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+	CHARACTER SET utf8mb4
+`
+
+const ddlCreateAPIToken = `
+CREATE TABLE IF NOT EXISTS api_token (
+	id         INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	user_id    INT UNSIGNED NOT NULL,
+	name       VARCHAR(100) NOT NULL,
+	scope      VARCHAR(20)  NOT NULL,
+	token_hash CHAR(64)     NOT NULL,
+	created_at BIGINT       NOT NULL,
+	last_used  BIGINT       NOT NULL DEFAULT 0,
+	PRIMARY KEY (id),
+	UNIQUE KEY api_token_token_hash_UNIQUE (token_hash),
+	FOREIGN KEY api_token_user_id_FK (user_id) REFERENCES user (id)
+		ON UPDATE CASCADE ON DELETE CASCADE)
+	CHARACTER SET utf8mb4
+`
@@ -0,0 +1,158 @@
+package database
+
+var mysqlMigrations = []migration{{
+	version:     1,
+	description: "create initial schema",
+	up: []string{
+		ddlCreateUser,
+		ddlCreateAccount,
+		ddlCreateEntry,
+		ddlCreateViewAccountTotal,
+		ddlCreateViewCumulativeAmount,
+		ddlUpgradeUserAddAdmin,
+	},
+	down: []string{
+		`DROP VIEW IF EXISTS cumulative_amount`,
+		`DROP VIEW IF EXISTS account_total`,
+		`DROP TABLE IF EXISTS entry`,
+		`DROP TABLE IF EXISTS account`,
+		`DROP TABLE IF EXISTS "user"`,
+	},
+}, {
+	version:     2,
+	description: "add category",
+	up: []string{
+		ddlCreateCategory,
+		ddlEntryAddCategory,
+	},
+	down: []string{
+		ddlEntryDropCategory,
+		`DROP TABLE IF EXISTS category`,
+	},
+}, {
+	version:     3,
+	description: "add tag",
+	up: []string{
+		ddlCreateTag,
+		ddlCreateEntryTag,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS entry_tag`,
+		`DROP TABLE IF EXISTS tag`,
+	},
+}, {
+	version:     4,
+	description: "add entry split",
+	up: []string{
+		ddlCreateEntrySplit,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS entry_split`,
+	},
+}, {
+	version:     5,
+	description: "add currency",
+	up: []string{
+		ddlCreateCurrencyRate,
+		ddlAccountAddCurrency,
+		ddlEntryAddAffectedAmount,
+		`DROP VIEW IF EXISTS cumulative_amount`,
+		`DROP VIEW IF EXISTS account_total`,
+		ddlCreateViewAccountTotalWithCurrency,
+		ddlCreateViewCumulativeAmountWithCurrency,
+	},
+	down: []string{
+		`DROP VIEW IF EXISTS cumulative_amount`,
+		`DROP VIEW IF EXISTS account_total`,
+		ddlEntryDropAffectedAmount,
+		ddlAccountDropCurrency,
+		ddlCreateViewAccountTotal,
+		ddlCreateViewCumulativeAmount,
+		`DROP TABLE IF EXISTS currency_rate`,
+	},
+}, {
+	version:     6,
+	description: "add recurring entry",
+	up: []string{
+		ddlCreateRecurring,
+		ddlEntryAddRecurring,
+	},
+	down: []string{
+		ddlEntryDropRecurring,
+		`DROP TABLE IF EXISTS recurring`,
+	},
+}, {
+	version:     7,
+	description: "add budget",
+	up: []string{
+		ddlCreateBudget,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS budget`,
+	},
+}, {
+	version:     8,
+	description: "add import profile",
+	up: []string{
+		ddlCreateImportProfile,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS import_profile`,
+	},
+}, {
+	version:     9,
+	description: "add entry import ID",
+	up: []string{
+		ddlEntryAddImportID,
+	},
+	down: []string{
+		ddlEntryDropImportID,
+	},
+}, {
+	version:     10,
+	description: "add entry full-text index",
+	up: []string{
+		ddlEntryAddFulltext,
+	},
+	down: []string{
+		ddlEntryDropFulltext,
+	},
+}, {
+	version:     11,
+	description: "add session",
+	up: []string{
+		ddlCreateSession,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS session`,
+	},
+}, {
+	version:     12,
+	description: "add session client data",
+	up: []string{
+		ddlSessionAddClient,
+	},
+	down: []string{
+		ddlSessionDropClient,
+	},
+}, {
+	version:     13,
+	description: "add two-factor authentication",
+	up: []string{
+		ddlCreateUserTOTP,
+		ddlCreateRecoveryCode,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS recovery_code`,
+		`DROP TABLE IF EXISTS user_totp`,
+	},
+}, {
+	version:     14,
+	description: "add API token",
+	up: []string{
+		ddlCreateAPIToken,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS api_token`,
+	},
+}}
@@ -0,0 +1,158 @@
+package database
+
+var postgresMigrations = []migration{{
+	version:     1,
+	description: "create initial schema",
+	up: []string{
+		ddlPostgresCreateUser,
+		ddlPostgresCreateAccount,
+		ddlPostgresCreateEntry,
+		ddlPostgresCreateViewAccountTotal,
+		ddlPostgresCreateViewCumulativeAmount,
+	},
+	down: []string{
+		`DROP VIEW IF EXISTS cumulative_amount`,
+		`DROP VIEW IF EXISTS account_total`,
+		`DROP TABLE IF EXISTS entry`,
+		`DROP TABLE IF EXISTS account`,
+		`DROP TABLE IF EXISTS "user"`,
+	},
+}, {
+	version:     2,
+	description: "add category",
+	up: []string{
+		ddlPostgresCreateCategory,
+		ddlPostgresEntryAddCategory,
+	},
+	down: []string{
+		`ALTER TABLE entry DROP COLUMN category_id`,
+		`DROP TABLE IF EXISTS category`,
+	},
+}, {
+	version:     3,
+	description: "add tag",
+	up: []string{
+		ddlPostgresCreateTag,
+		ddlPostgresCreateEntryTag,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS entry_tag`,
+		`DROP TABLE IF EXISTS tag`,
+	},
+}, {
+	version:     4,
+	description: "add entry split",
+	up: []string{
+		ddlPostgresCreateEntrySplit,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS entry_split`,
+	},
+}, {
+	version:     5,
+	description: "add currency",
+	up: []string{
+		ddlPostgresCreateCurrencyRate,
+		ddlPostgresAccountAddCurrency,
+		ddlPostgresEntryAddAffectedAmount,
+		`DROP VIEW IF EXISTS cumulative_amount`,
+		`DROP VIEW IF EXISTS account_total`,
+		ddlPostgresCreateViewAccountTotalWithCurrency,
+		ddlPostgresCreateViewCumulativeAmountWithCurrency,
+	},
+	down: []string{
+		`DROP VIEW IF EXISTS cumulative_amount`,
+		`DROP VIEW IF EXISTS account_total`,
+		ddlPostgresEntryDropAffectedAmount,
+		ddlPostgresAccountDropCurrency,
+		ddlPostgresCreateViewAccountTotal,
+		ddlPostgresCreateViewCumulativeAmount,
+		`DROP TABLE IF EXISTS currency_rate`,
+	},
+}, {
+	version:     6,
+	description: "add recurring entry",
+	up: []string{
+		ddlPostgresCreateRecurring,
+		ddlPostgresEntryAddRecurring,
+	},
+	down: []string{
+		`ALTER TABLE entry DROP COLUMN recurring_id`,
+		`DROP TABLE IF EXISTS recurring`,
+	},
+}, {
+	version:     7,
+	description: "add budget",
+	up: []string{
+		ddlPostgresCreateBudget,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS budget`,
+	},
+}, {
+	version:     8,
+	description: "add import profile",
+	up: []string{
+		ddlPostgresCreateImportProfile,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS import_profile`,
+	},
+}, {
+	version:     9,
+	description: "add entry import ID",
+	up: []string{
+		ddlPostgresEntryAddImportID,
+	},
+	down: []string{
+		ddlPostgresEntryDropImportID,
+	},
+}, {
+	version:     10,
+	description: "add entry full-text index",
+	up: []string{
+		ddlPostgresCreateEntryDescriptionIndex,
+	},
+	down: []string{
+		`DROP INDEX IF EXISTS entry_description_fts`,
+	},
+}, {
+	version:     11,
+	description: "add session",
+	up: []string{
+		ddlPostgresCreateSession,
+		ddlPostgresCreateSessionExpiresAtIndex,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS session`,
+	},
+}, {
+	version:     12,
+	description: "add session client data",
+	up: []string{
+		ddlPostgresSessionAddClient,
+	},
+	down: []string{
+		ddlPostgresSessionDropClient,
+	},
+}, {
+	version:     13,
+	description: "add two-factor authentication",
+	up: []string{
+		ddlPostgresCreateUserTOTP,
+		ddlPostgresCreateRecoveryCode,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS recovery_code`,
+		`DROP TABLE IF EXISTS user_totp`,
+	},
+}, {
+	version:     14,
+	description: "add API token",
+	up: []string{
+		ddlPostgresCreateAPIToken,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS api_token`,
+	},
+}}
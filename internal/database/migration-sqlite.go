@@ -0,0 +1,161 @@
+package database
+
+var sqliteMigrations = []migration{{
+	version:     1,
+	description: "create initial schema",
+	up: []string{
+		ddlSQLiteCreateUser,
+		ddlSQLiteCreateAccount,
+		ddlSQLiteCreateEntry,
+		ddlSQLiteCreateViewAccountTotal,
+		ddlSQLiteCreateViewCumulativeAmount,
+	},
+	down: []string{
+		`DROP VIEW IF EXISTS cumulative_amount`,
+		`DROP VIEW IF EXISTS account_total`,
+		`DROP TABLE IF EXISTS entry`,
+		`DROP TABLE IF EXISTS account`,
+		`DROP TABLE IF EXISTS "user"`,
+	},
+}, {
+	version:     2,
+	description: "add category",
+	up: []string{
+		ddlSQLiteCreateCategory,
+		ddlSQLiteEntryAddCategory,
+	},
+	// SQLite can't drop column that used in foreign key,
+	// so this migration can't be reverted.
+	down: nil,
+}, {
+	version:     3,
+	description: "add tag",
+	up: []string{
+		ddlSQLiteCreateTag,
+		ddlSQLiteCreateEntryTag,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS entry_tag`,
+		`DROP TABLE IF EXISTS tag`,
+	},
+}, {
+	version:     4,
+	description: "add entry split",
+	up: []string{
+		ddlSQLiteCreateEntrySplit,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS entry_split`,
+	},
+}, {
+	version:     5,
+	description: "add currency",
+	up: []string{
+		ddlSQLiteCreateCurrencyRate,
+		ddlSQLiteAccountAddCurrency,
+		ddlSQLiteEntryAddAffectedAmount,
+		`DROP VIEW IF EXISTS cumulative_amount`,
+		`DROP VIEW IF EXISTS account_total`,
+		ddlSQLiteCreateViewAccountTotalWithCurrency,
+		ddlSQLiteCreateViewCumulativeAmountWithCurrency,
+	},
+	// The SQLite version that used here doesn't support
+	// DROP COLUMN, so this migration can't be reverted.
+	down: nil,
+}, {
+	version:     6,
+	description: "add recurring entry",
+	up: []string{
+		ddlSQLiteCreateRecurring,
+		ddlSQLiteEntryAddRecurring,
+	},
+	// SQLite can't drop column that used in foreign key,
+	// so this migration can't be reverted.
+	down: nil,
+}, {
+	version:     7,
+	description: "add budget",
+	up: []string{
+		ddlSQLiteCreateBudget,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS budget`,
+	},
+}, {
+	version:     8,
+	description: "add import profile",
+	up: []string{
+		ddlSQLiteCreateImportProfile,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS import_profile`,
+	},
+}, {
+	version:     9,
+	description: "add entry import ID",
+	up: []string{
+		ddlSQLiteEntryAddImportID,
+		ddlSQLiteCreateEntryImportIDIndex,
+	},
+	// The SQLite version that used here doesn't support
+	// DROP COLUMN, so this migration can't be reverted.
+	down: nil,
+}, {
+	version:     10,
+	description: "add entry full-text index",
+	up: []string{
+		ddlSQLiteCreateEntryFTS,
+		ddlSQLiteRebuildEntryFTS,
+		ddlSQLiteCreateEntryFTSTriggerBU,
+		ddlSQLiteCreateEntryFTSTriggerBD,
+		ddlSQLiteCreateEntryFTSTriggerAU,
+		ddlSQLiteCreateEntryFTSTriggerAI,
+	},
+	down: []string{
+		`DROP TRIGGER IF EXISTS entry_fts_ai`,
+		`DROP TRIGGER IF EXISTS entry_fts_au`,
+		`DROP TRIGGER IF EXISTS entry_fts_bd`,
+		`DROP TRIGGER IF EXISTS entry_fts_bu`,
+		`DROP TABLE IF EXISTS entry_fts`,
+	},
+}, {
+	version:     11,
+	description: "add session",
+	up: []string{
+		ddlSQLiteCreateSession,
+		ddlSQLiteCreateSessionExpiresAtIndex,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS session`,
+	},
+}, {
+	version:     12,
+	description: "add session client data",
+	up: []string{
+		ddlSQLiteSessionAddCreatedAt,
+		ddlSQLiteSessionAddIPAddress,
+	},
+	// The SQLite version that used here doesn't support
+	// DROP COLUMN, so this migration can't be reverted.
+	down: nil,
+}, {
+	version:     13,
+	description: "add two-factor authentication",
+	up: []string{
+		ddlSQLiteCreateUserTOTP,
+		ddlSQLiteCreateRecoveryCode,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS recovery_code`,
+		`DROP TABLE IF EXISTS user_totp`,
+	},
+}, {
+	version:     14,
+	description: "add API token",
+	up: []string{
+		ddlSQLiteCreateAPIToken,
+	},
+	down: []string{
+		`DROP TABLE IF EXISTS api_token`,
+	},
+}}
@@ -0,0 +1,252 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/guregu/null.v3"
+)
+
+// migration is a single step of change in database schema.
+// Up is list of queries to apply the change, while down is
+// list of queries to revert it. If down is nil, the migration
+// can't be reverted.
+type migration struct {
+	version     int
+	description string
+	up          []string
+	down        []string
+}
+
+// MigrationStatus is the state of a migration in database.
+type MigrationStatus struct {
+	Version     int         `db:"version"`
+	Description string      `db:"description"`
+	AppliedAt   null.String `db:"applied_at"`
+}
+
+const ddlCreateSchemaVersion = `
+CREATE TABLE IF NOT EXISTS schema_version (
+	version     INTEGER      NOT NULL,
+	description VARCHAR(100) NOT NULL,
+	applied_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (version))
+`
+
+// migrations returns list of migrations for the driver that used by db.
+func migrations(db *sqlx.DB) ([]migration, error) {
+	switch db.DriverName() {
+	case "mysql":
+		return mysqlMigrations, nil
+	case "sqlite3":
+		return sqliteMigrations, nil
+	case "postgres":
+		return postgresMigrations, nil
+	default:
+		return nil, fmt.Errorf("no migrations for driver %s", db.DriverName())
+	}
+}
+
+// LatestSchemaVersion returns the latest schema version
+// that known by this version of Duit.
+func LatestSchemaVersion(db *sqlx.DB) (int, error) {
+	list, err := migrations(db)
+	if err != nil {
+		return 0, err
+	}
+
+	return list[len(list)-1].version, nil
+}
+
+// SchemaVersion returns the current schema version of database.
+// If there are no migration applied yet, it will return 0.
+func SchemaVersion(db *sqlx.DB) (int, error) {
+	_, err := db.Exec(ddlCreateSchemaVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create schema version table: %w", err)
+	}
+
+	var version null.Int
+	err = db.Get(&version, `SELECT MAX(version) FROM schema_version`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	return int(version.Int64), nil
+}
+
+// CheckSchemaVersion makes sure the database schema is not newer
+// than the one known by this version of Duit.
+func CheckSchemaVersion(db *sqlx.DB) error {
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	latest, err := LatestSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	if current > latest {
+		return fmt.Errorf("database schema version %d is newer than "+
+			"the latest version known by duit (%d), please upgrade duit",
+			current, latest)
+	}
+
+	return nil
+}
+
+// Migrations returns status of every migration known by Duit.
+func Migrations(db *sqlx.DB) ([]MigrationStatus, error) {
+	list, err := migrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = SchemaVersion(db); err != nil {
+		return nil, err
+	}
+
+	// Fetch applied migrations
+	applied := []MigrationStatus{}
+	err = db.Select(&applied, `
+		SELECT version, description, applied_at
+		FROM schema_version ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	appliedAt := make(map[int]null.String)
+	for _, m := range applied {
+		appliedAt[m.Version] = m.AppliedAt
+	}
+
+	// Merge it with the known migrations
+	result := []MigrationStatus{}
+	for _, m := range list {
+		result = append(result, MigrationStatus{
+			Version:     m.version,
+			Description: m.description,
+			AppliedAt:   appliedAt[m.version],
+		})
+	}
+
+	return result, nil
+}
+
+// MigrateUp applies migrations until database schema reached the target
+// version. If target is zero, it will be migrated to the latest version.
+func MigrateUp(db *sqlx.DB, target int) error {
+	if err := CheckSchemaVersion(db); err != nil {
+		return err
+	}
+
+	list, err := migrations(db)
+	if err != nil {
+		return err
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	if target <= 0 {
+		target = list[len(list)-1].version
+	}
+
+	if target < current {
+		return fmt.Errorf("target version %d is older than current version %d", target, current)
+	}
+
+	for _, m := range list {
+		if m.version <= current || m.version > target {
+			continue
+		}
+
+		err = applyMigration(db, m.up, func(tx *sqlx.Tx) {
+			tx.MustExec(`INSERT INTO schema_version (version, description)
+				VALUES (?, ?)`, m.version, m.description)
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d failed: %w", m.version, err)
+		}
+	}
+
+	return nil
+}
+
+// MigrateDown reverts the specified number of the latest applied migrations.
+func MigrateDown(db *sqlx.DB, steps int) error {
+	if err := CheckSchemaVersion(db); err != nil {
+		return err
+	}
+
+	list, err := migrations(db)
+	if err != nil {
+		return err
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	for i := len(list) - 1; i >= 0 && steps > 0; i-- {
+		m := list[i]
+		if m.version > current {
+			continue
+		}
+
+		if m.down == nil {
+			return fmt.Errorf("migration %d can't be reverted", m.version)
+		}
+
+		err = applyMigration(db, m.down, func(tx *sqlx.Tx) {
+			tx.MustExec(`DELETE FROM schema_version WHERE version = ?`, m.version)
+		})
+		if err != nil {
+			return fmt.Errorf("reverting migration %d failed: %w", m.version, err)
+		}
+
+		steps--
+	}
+
+	return nil
+}
+
+// applyMigration executes the queries then update schema version in one
+// transaction. Note that in MySQL most DDL can't be rolled back, so if a
+// migration failed, the database might need to be fixed manually.
+func applyMigration(db *sqlx.DB, queries []string, updateVersion func(*sqlx.Tx)) (err error) {
+	// Create transaction
+	var tx *sqlx.Tx
+	tx, err = db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// Make sure to rollback if panic ever happened
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+
+			var ok bool
+			if err, ok = r.(error); !ok {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	for _, query := range queries {
+		tx.MustExec(query)
+	}
+
+	updateVersion(tx)
+
+	err = tx.Commit()
+	checkError(err)
+
+	return err
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestApplyMigrationFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "duit-database")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := Open(model.Config{
+		DbDriver: DriverSQLite,
+		DbPath:   filepath.Join(dir, "duit.db"),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	tests := map[string]func(*sqlx.Tx){
+		"panic with error":  func(tx *sqlx.Tx) { tx.MustExec(`INSERT INTO missing VALUES (1)`) },
+		"panic with string": func(tx *sqlx.Tx) { panic("failed to update version") },
+	}
+
+	for name, updateVersion := range tests {
+		err = applyMigration(db, []string{`CREATE TABLE dummy (id INTEGER)`}, updateVersion)
+		if err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+
+		// The queries must be rolled back
+		var count int
+		err = db.Get(&count, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'dummy'`)
+		if err != nil || count != 0 {
+			t.Errorf("%s: migration is not rolled back (%d, %v)", name, count, err)
+		}
+	}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "duit-database")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := Open(model.Config{
+		DbDriver: DriverSQLite,
+		DbPath:   filepath.Join(dir, "duit.db"),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	latest, _ := LatestSchemaVersion(db)
+	if err = MigrateUp(db, 0); err != nil {
+		t.Fatalf("failed to migrate up: %v", err)
+	}
+
+	if version, _ := SchemaVersion(db); version != latest {
+		t.Errorf("expected version %d, got %d", latest, version)
+	}
+
+	if err = MigrateDown(db, 1); err != nil {
+		t.Fatalf("failed to migrate down: %v", err)
+	}
+
+	if version, _ := SchemaVersion(db); version != latest-1 {
+		t.Errorf("expected version %d, got %d", latest-1, version)
+	}
+}
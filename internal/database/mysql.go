@@ -0,0 +1,42 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// openMySQL connects to MySQL or MariaDB server.
+func openMySQL(config model.Config) (*sqlx.DB, error) {
+	// Specify default value
+	if config.DbHost == "" {
+		config.DbHost = "127.0.0.1:3306"
+	}
+
+	if config.DbName == "" {
+		config.DbName = "duit"
+	}
+
+	// Connect to database. Here we enable ANSI_QUOTES so double quote
+	// is treated as identifier quote, same as in SQLite and PostgreSQL.
+	// This way queries that use reserved word like "user" as table name
+	// can be shared between all database.
+	sqlMode := url.QueryEscape(`CONCAT(@@sql_mode, ',ANSI_QUOTES')`)
+	dataSource := fmt.Sprintf("%s:%s@tcp(%s)/%s?sql_mode=%s",
+		config.DbUser,
+		config.DbPassword,
+		config.DbHost,
+		config.DbName,
+		sqlMode)
+
+	db, err := sqlx.Connect("mysql", dataSource)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetConnMaxLifetime(time.Minute)
+	return db, nil
+}
@@ -0,0 +1,212 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+func init() {
+	sql.Register("duit-postgres", postgresDriver{})
+}
+
+// openPostgres connects to PostgreSQL server.
+func openPostgres(config model.Config) (*sqlx.DB, error) {
+	// Specify default value
+	if config.DbHost == "" {
+		config.DbHost = "127.0.0.1:5432"
+	}
+
+	if config.DbName == "" {
+		config.DbName = "duit"
+	}
+
+	if config.DbSSLMode == "" {
+		config.DbSSLMode = "disable"
+	}
+
+	// If port is not specified, use the default one
+	if _, _, err := net.SplitHostPort(config.DbHost); err != nil {
+		config.DbHost = net.JoinHostPort(config.DbHost, "5432")
+	}
+
+	// Connect to database
+	dataSource := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(config.DbUser, config.DbPassword),
+		Host:     config.DbHost,
+		Path:     config.DbName,
+		RawQuery: "sslmode=" + url.QueryEscape(config.DbSSLMode),
+	}
+
+	db, err := sql.Open("duit-postgres", dataSource.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	db.SetConnMaxLifetime(time.Minute)
+	return sqlx.NewDb(db, "postgres"), nil
+}
+
+// postgresDriver is wrapper for lib/pq driver, which make PostgreSQL behave
+// like the other database that used by Duit. It converts the "?" placeholder
+// into "$1", "$2" and so on, implements LastInsertId using LASTVAL() and
+// returns DATE column as string formatted YYYY-MM-DD, same as MySQL.
+type postgresDriver struct{}
+
+func (postgresDriver) Open(name string) (driver.Conn, error) {
+	conn, err := pq.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return postgresConn{conn}, nil
+}
+
+type postgresConn struct {
+	driver.Conn
+}
+
+func (c postgresConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(postgresRebind(query))
+	if err != nil {
+		return nil, err
+	}
+
+	return postgresStmt{Stmt: stmt, conn: c.Conn}, nil
+}
+
+// BeginTx starts transaction using the specified isolation level, which
+// needed to read the whole database in one consistent snapshot.
+func (c postgresConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+
+	return c.Conn.Begin()
+}
+
+type postgresStmt struct {
+	driver.Stmt
+	conn driver.Conn
+}
+
+func (s postgresStmt) Exec(args []driver.Value) (driver.Result, error) {
+	res, err := s.Stmt.Exec(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return postgresResult{Result: res, conn: s.conn}, nil
+}
+
+func (s postgresStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.Stmt.Query(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return postgresRows{rows}, nil
+}
+
+type postgresResult struct {
+	driver.Result
+	conn driver.Conn
+}
+
+// LastInsertId returns the last value generated by sequence in current
+// session. Since every table in Duit uses SERIAL as its primary key,
+// this will be the ID of the last inserted row.
+func (r postgresResult) LastInsertId() (int64, error) {
+	queryer, ok := r.conn.(driver.Queryer)
+	if !ok {
+		return 0, fmt.Errorf("connection doesn't support query")
+	}
+
+	rows, err := queryer.Query("SELECT LASTVAL()", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	dest := make([]driver.Value, 1)
+	if err = rows.Next(dest); err != nil {
+		return 0, err
+	}
+
+	id, ok := dest[0].(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected last insert ID: %v", dest[0])
+	}
+
+	return id, nil
+}
+
+type postgresRows struct {
+	driver.Rows
+}
+
+func (r postgresRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err != nil {
+		return err
+	}
+
+	typeNamer, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName)
+	if !ok {
+		return nil
+	}
+
+	for i, value := range dest {
+		t, isTime := value.(time.Time)
+		if isTime && typeNamer.ColumnTypeDatabaseTypeName(i) == "DATE" {
+			dest[i] = []byte(t.Format("2006-01-02"))
+		}
+	}
+
+	return nil
+}
+
+// postgresRebind converts "?" placeholder into "$n" placeholder. Question
+// mark that located inside quoted string or identifier will be left as it is.
+func postgresRebind(query string) string {
+	var (
+		sb      strings.Builder
+		nArg    int
+		inQuote rune
+	)
+
+	for _, r := range query {
+		switch {
+		case inQuote != 0:
+			if r == inQuote {
+				inQuote = 0
+			}
+		case r == '\'' || r == '"':
+			inQuote = r
+		case r == '?':
+			nArg++
+			sb.WriteString("$" + strconv.Itoa(nArg))
+			continue
+		}
+
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
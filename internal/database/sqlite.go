@@ -0,0 +1,30 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// openSQLite opens SQLite database file. If the file doesn't exist yet,
+// it will be created.
+func openSQLite(config model.Config) (*sqlx.DB, error) {
+	// Specify default value
+	if config.DbPath == "" {
+		config.DbPath = "duit.db"
+	}
+
+	// By default SQLite doesn't enforce foreign key, so we need to
+	// enable it manually. Since SQLite only allows one writer at a time,
+	// every transaction is started as immediate and will wait for the
+	// other writers to finish instead of failing right away.
+	dataSource := fmt.Sprintf("file:%s?"+
+		"_foreign_keys=1&"+
+		"_busy_timeout=5000&"+
+		"_journal_mode=WAL&"+
+		"_txlock=immediate",
+		config.DbPath)
+
+	return sqlx.Connect("sqlite3", dataSource)
+}
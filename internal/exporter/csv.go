@@ -0,0 +1,66 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var entryTypeNames = map[int]string{
+	1: "income",
+	2: "expense",
+	3: "transfer",
+}
+
+// writeCSV writes initial amount of each account as an opening row, followed
+// by the entries. Entry with splits is written as one row for each split, so
+// the amount column can be summed directly.
+func writeCSV(w io.Writer, data Data) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "date", "type", "account", "currency", "amount",
+		"affected_account", "affected_amount", "category", "description", "tags"})
+
+	currencies := make(map[int64]string)
+	date := openingDate(data)
+	for _, account := range data.Accounts {
+		currencies[account.ID] = account.Currency
+		cw.Write([]string{"", date, "opening", account.Name, account.Currency,
+			account.InitialAmount.String(), "", "", "", "Opening balance", ""})
+	}
+
+	for _, entry := range data.Entries {
+		id := strconv.FormatInt(entry.ID, 10)
+		tags := strings.Join(entry.Tags, ",")
+
+		affectedAmount := ""
+		if entry.Type == 3 {
+			affectedAmount = entry.Amount.String()
+			if entry.AffectedAmount.Valid {
+				affectedAmount = entry.AffectedAmount.Decimal.String()
+			}
+		}
+
+		if len(entry.Splits) == 0 {
+			cw.Write([]string{id, entry.Date, entryTypeNames[entry.Type],
+				entry.Account, currencies[entry.AccountID], entry.Amount.String(),
+				entry.AffectedAccount.String, affectedAmount, entry.Category.String,
+				entry.Description.String, tags})
+			continue
+		}
+
+		for _, split := range entry.Splits {
+			description := split.Description.String
+			if description == "" {
+				description = entry.Description.String
+			}
+
+			cw.Write([]string{id, entry.Date, entryTypeNames[entry.Type],
+				entry.Account, currencies[entry.AccountID], split.Amount.String(),
+				"", "", split.Category.String, description, tags})
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,76 @@
+// Package exporter writes accounts and entries into formats that
+// can be read by other applications, e.g. hledger and beancount.
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+)
+
+// Supported export formats.
+const (
+	FormatCSV       = "csv"
+	FormatJSON      = "json"
+	FormatLedger    = "ledger"
+	FormatBeancount = "beancount"
+)
+
+// Data is the content that will be exported. Entries must be sorted by
+// date, and their tags and splits must be filled.
+type Data struct {
+	BaseCurrency string           `json:"baseCurrency"`
+	Accounts     []model.Account  `json:"accounts"`
+	Categories   []model.Category `json:"categories"`
+	Entries      []model.Entry    `json:"entries"`
+}
+
+// Write writes the data into w using the specified format.
+func Write(w io.Writer, format string, data Data) error {
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, data)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(&data)
+	case FormatLedger:
+		return writeLedger(w, data)
+	case FormatBeancount:
+		return writeBeancount(w, data)
+	default:
+		return fmt.Errorf("format must be csv, json, ledger or beancount")
+	}
+}
+
+// ContentType returns MIME type of the format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv"
+	case FormatJSON:
+		return "application/json"
+	default:
+		return "text/plain"
+	}
+}
+
+// openingDate returns the date used for opening balances, which is the
+// date of the earliest entry. If there are no entries, today is used.
+func openingDate(data Data) string {
+	if len(data.Entries) == 0 {
+		return time.Now().Format("2006-01-02")
+	}
+
+	date := data.Entries[0].Date
+	for _, entry := range data.Entries {
+		if entry.Date < date {
+			date = entry.Date
+		}
+	}
+
+	return date
+}
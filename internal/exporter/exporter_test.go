@@ -0,0 +1,224 @@
+package exporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/RadhiFadlillah/duit/internal/importer"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v3"
+)
+
+// testData returns accounts in two currencies, with income, splitted
+// expense and transfer between the currencies.
+func testData() Data {
+	return Data{
+		BaseCurrency: "IDR",
+		Accounts: []model.Account{
+			{ID: 1, Name: "Bank", Currency: "IDR", InitialAmount: decimal.New(1000000, 0)},
+			{ID: 2, Name: "Travel: Wallet", Currency: "USD"},
+		},
+		Categories: []model.Category{
+			{ID: 1, Name: "Food"},
+			{ID: 2, ParentID: null.IntFrom(1), Name: "Snack"},
+			{ID: 3, Name: "Salary"},
+		},
+		Entries: []model.Entry{{
+			ID: 1, AccountID: 1, Type: 1, Date: "2020-01-02",
+			Account:     "Bank",
+			Amount:      decimal.New(5000000, 0),
+			CategoryID:  null.IntFrom(3),
+			Category:    null.StringFrom("Salary"),
+			Description: null.StringFrom("January; salary"),
+		}, {
+			ID: 2, AccountID: 1, Type: 2, Date: "2020-01-03",
+			Account:     "Bank",
+			Amount:      decimal.New(75000, 0),
+			Description: null.StringFrom(`Shop "Mart"`),
+			Tags:        []string{"weekly trip"},
+			Splits: []model.EntrySplit{{
+				CategoryID:  null.IntFrom(2),
+				Category:    null.StringFrom("Food:Snack"),
+				Description: null.StringFrom("chips"),
+				Amount:      decimal.New(50000, 0),
+			}, {
+				Amount: decimal.New(25000, 0),
+			}},
+		}, {
+			ID: 3, AccountID: 1, AffectedAccountID: null.IntFrom(2), Type: 3, Date: "2020-01-04",
+			Account:         "Bank",
+			AffectedAccount: null.StringFrom("Travel: Wallet"),
+			Amount:          decimal.New(150000, 0),
+			AffectedAmount:  decimal.NullDecimal{Decimal: decimal.New(10, 0), Valid: true},
+		}},
+	}
+}
+
+func TestLedgerDataBalanced(t *testing.T) {
+	accounts, transactions := ledgerData(testData())
+
+	names := []string{}
+	for _, account := range accounts {
+		names = append(names, strings.Join(account, ":"))
+	}
+
+	expected := "Assets:Bank,Assets:Travel: Wallet,Equity:Opening Balances," +
+		"Expenses:Food:Snack,Expenses:Uncategorized,Income:Salary"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("expected accounts %s, got %s", expected, strings.Join(names, ","))
+	}
+
+	if len(transactions) != 4 {
+		t.Fatalf("expected opening balance and 3 entries, got %d transactions", len(transactions))
+	}
+
+	// Every transaction must be balanced in each currency,
+	// where posting with price is counted in its price currency
+	for _, tr := range transactions {
+		sums := map[string]decimal.Decimal{}
+		for _, p := range tr.postings {
+			if p.price.Valid {
+				sums[p.priceCurrency] = sums[p.priceCurrency].Add(p.price.Decimal.Abs().Mul(decimal.New(int64(p.amount.Sign()), 0)))
+			} else {
+				sums[p.currency] = sums[p.currency].Add(p.amount)
+			}
+		}
+
+		for currency, sum := range sums {
+			if !sum.IsZero() {
+				t.Errorf("%s %s: unbalanced %s %s", tr.date, tr.description, sum, currency)
+			}
+		}
+	}
+}
+
+func TestWriteLedger(t *testing.T) {
+	expected := `account Assets:Bank
+account Assets:Travel- Wallet
+account Equity:Opening Balances
+account Expenses:Food:Snack
+account Expenses:Uncategorized
+account Income:Salary
+
+2020-01-02 Opening balance
+    Assets:Bank  1000000 IDR
+    Equity:Opening Balances  -1000000 IDR
+
+2020-01-02 January, salary
+    Assets:Bank  5000000 IDR
+    Income:Salary  -5000000 IDR
+
+2020-01-03 Shop "Mart"  ; weekly-trip:
+    Assets:Bank  -75000 IDR
+    Expenses:Food:Snack  50000 IDR  ; chips
+    Expenses:Uncategorized  25000 IDR
+
+2020-01-04
+    Assets:Bank  -150000 IDR @@ 10 USD
+    Assets:Travel- Wallet  10 USD
+`
+
+	checkExport(t, FormatLedger, expected)
+}
+
+func TestWriteBeancount(t *testing.T) {
+	expected := `option "operating_currency" "IDR"
+
+2020-01-02 open Assets:Bank
+2020-01-02 open Assets:Travel-Wallet
+2020-01-02 open Equity:Opening-Balances
+2020-01-02 open Expenses:Food:Snack
+2020-01-02 open Expenses:Uncategorized
+2020-01-02 open Income:Salary
+
+2020-01-02 * "Opening balance"
+  Assets:Bank  1000000 IDR
+  Equity:Opening-Balances  -1000000 IDR
+
+2020-01-02 * "January; salary"
+  Assets:Bank  5000000 IDR
+  Income:Salary  -5000000 IDR
+
+2020-01-03 * "Shop \"Mart\"" #weekly-trip
+  Assets:Bank  -75000 IDR
+  Expenses:Food:Snack  50000 IDR
+    description: "chips"
+  Expenses:Uncategorized  25000 IDR
+
+2020-01-04 * ""
+  Assets:Bank  -150000 IDR @@ 10 USD
+  Assets:Travel-Wallet  10 USD
+`
+
+	checkExport(t, FormatBeancount, expected)
+}
+
+func TestWriteCSV(t *testing.T) {
+	expected := `id,date,type,account,currency,amount,affected_account,affected_amount,category,description,tags
+,2020-01-02,opening,Bank,IDR,1000000,,,,Opening balance,
+,2020-01-02,opening,Travel: Wallet,USD,0,,,,Opening balance,
+1,2020-01-02,income,Bank,IDR,5000000,,,Salary,January; salary,
+2,2020-01-03,expense,Bank,IDR,50000,,,Food:Snack,chips,weekly trip
+2,2020-01-03,expense,Bank,IDR,25000,,,,"Shop ""Mart""",weekly trip
+3,2020-01-04,transfer,Bank,IDR,150000,Travel: Wallet,10,,,
+`
+
+	checkExport(t, FormatCSV, expected)
+}
+
+func TestLedgerRoundTrip(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := Write(&buffer, FormatLedger, testData()); err != nil {
+		t.Fatalf("failed to write ledger: %v", err)
+	}
+
+	journal, err := importer.ParseJournal(&buffer)
+	if err != nil {
+		t.Fatalf("failed to parse exported ledger: %v", err)
+	}
+
+	if len(journal.Accounts) != 2 || !journal.Accounts[0].InitialAmount.Equal(decimal.New(1000000, 0)) {
+		t.Errorf("unexpected accounts %+v", journal.Accounts)
+	}
+
+	if len(journal.Rows) != 3 {
+		t.Fatalf("expected 3 rows, got %+v", journal.Rows)
+	}
+
+	for i, row := range journal.Rows {
+		original := testData().Entries[i]
+		entry := row.Entry
+		if row.Error != "" || entry.Type != original.Type || !entry.Amount.Equal(original.Amount) ||
+			len(entry.Splits) != len(original.Splits) {
+			t.Errorf("entry %d: expected %+v, got %+v (%s)", i, original, entry, row.Error)
+		}
+	}
+
+	transfer := journal.Rows[2].Entry
+	if !transfer.AffectedAmount.Decimal.Equal(decimal.New(10, 0)) {
+		t.Errorf("expected affected amount 10, got %s", transfer.AffectedAmount.Decimal)
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "xml", testData()); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
+
+// checkExport makes sure the test data exported in the format
+// matches the expected output.
+func checkExport(t *testing.T, format, expected string) {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	if err := Write(&buffer, format, testData()); err != nil {
+		t.Fatalf("failed to write %s: %v", format, err)
+	}
+
+	if got := buffer.String(); got != expected {
+		t.Errorf("unexpected %s output:\n%s\nexpected:\n%s", format, got, expected)
+	}
+}
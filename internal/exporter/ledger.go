@@ -0,0 +1,310 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+// posting is a line of double-entry transaction. Account is the
+// path of account name, e.g. ["Expenses", "Food", "Snack"].
+type posting struct {
+	account  []string
+	amount   decimal.Decimal
+	currency string
+	comment  string
+
+	// price is the total cost of posting in other currency. It's used
+	// in transfer between accounts with different currencies, so the
+	// transaction is still balanced.
+	price         decimal.NullDecimal
+	priceCurrency string
+}
+
+type transaction struct {
+	date        string
+	description string
+	tags        []string
+	postings    []posting
+}
+
+// ledgerData converts accounts and entries into balanced transactions.
+// Initial amount of accounts become opening balance transactions, while
+// income and expense are balanced by accounts made from their categories.
+func ledgerData(data Data) (accounts [][]string, transactions []transaction) {
+	// Prepare account names
+	accountByID := make(map[int64]model.Account)
+	for _, account := range data.Accounts {
+		accountByID[account.ID] = account
+		accounts = append(accounts, []string{"Assets", account.Name})
+	}
+
+	categoryByID := make(map[int64]model.Category)
+	for _, category := range data.Categories {
+		categoryByID[category.ID] = category
+	}
+
+	categoryAccount := func(root string, categoryID int64, valid bool) []string {
+		if !valid {
+			return []string{root, "Uncategorized"}
+		}
+
+		// Walk to the top level category. The depth is limited,
+		// just in case the categories somehow become circular.
+		path := []string{}
+		for depth := 0; valid && depth < 20; depth++ {
+			category, exist := categoryByID[categoryID]
+			if !exist {
+				break
+			}
+
+			path = append([]string{category.Name}, path...)
+			categoryID, valid = category.ParentID.Int64, category.ParentID.Valid
+		}
+
+		return append([]string{root}, path...)
+	}
+
+	// Create opening balances
+	equity := []string{"Equity", "Opening Balances"}
+	date := openingDate(data)
+	for _, account := range data.Accounts {
+		if account.InitialAmount.IsZero() {
+			continue
+		}
+
+		transactions = append(transactions, transaction{
+			date:        date,
+			description: "Opening balance",
+			postings: []posting{{
+				account:  []string{"Assets", account.Name},
+				amount:   account.InitialAmount,
+				currency: account.Currency,
+			}, {
+				account:  equity,
+				amount:   account.InitialAmount.Neg(),
+				currency: account.Currency,
+			}},
+		})
+	}
+
+	// Convert entries
+	for _, entry := range data.Entries {
+		account := accountByID[entry.AccountID]
+		t := transaction{
+			date:        entry.Date,
+			description: entry.Description.String,
+			tags:        entry.Tags,
+		}
+
+		source := posting{
+			account:  []string{"Assets", account.Name},
+			amount:   entry.Amount,
+			currency: account.Currency,
+		}
+
+		root, sign := "Income", decimal.New(-1, 0)
+		if entry.Type == 2 {
+			root, sign = "Expenses", decimal.New(1, 0)
+			source.amount = entry.Amount.Neg()
+		}
+
+		switch {
+		case entry.Type == 3:
+			affected := accountByID[entry.AffectedAccountID.Int64]
+			target := posting{
+				account:  []string{"Assets", affected.Name},
+				amount:   entry.Amount,
+				currency: affected.Currency,
+			}
+
+			if entry.AffectedAmount.Valid {
+				target.amount = entry.AffectedAmount.Decimal
+			}
+
+			source.amount = entry.Amount.Neg()
+			if source.currency != target.currency {
+				source.price = decimal.NullDecimal{Decimal: target.amount, Valid: true}
+				source.priceCurrency = target.currency
+			}
+
+			t.postings = []posting{source, target}
+
+		case len(entry.Splits) > 0:
+			t.postings = []posting{source}
+			for _, split := range entry.Splits {
+				t.postings = append(t.postings, posting{
+					account:  categoryAccount(root, split.CategoryID.Int64, split.CategoryID.Valid),
+					amount:   split.Amount.Mul(sign),
+					currency: account.Currency,
+					comment:  split.Description.String,
+				})
+			}
+
+		default:
+			t.postings = []posting{source, {
+				account:  categoryAccount(root, entry.CategoryID.Int64, entry.CategoryID.Valid),
+				amount:   entry.Amount.Mul(sign),
+				currency: account.Currency,
+			}}
+		}
+
+		transactions = append(transactions, t)
+	}
+
+	// Collect the other accounts that used in transactions
+	exist := make(map[string]struct{})
+	for _, account := range accounts {
+		exist[strings.Join(account, ":")] = struct{}{}
+	}
+
+	others := [][]string{}
+	for _, t := range transactions {
+		for _, p := range t.postings {
+			key := strings.Join(p.account, ":")
+			if _, found := exist[key]; !found {
+				exist[key] = struct{}{}
+				others = append(others, p.account)
+			}
+		}
+	}
+
+	sort.Slice(others, func(i, j int) bool {
+		return strings.Join(others[i], ":") < strings.Join(others[j], ":")
+	})
+
+	return append(accounts, others...), transactions
+}
+
+// writeLedger writes the data in journal format used by hledger and ledger.
+func writeLedger(w io.Writer, data Data) error {
+	bw := bufio.NewWriter(w)
+	accounts, transactions := ledgerData(data)
+
+	accountName := func(path []string) string {
+		names := make([]string, len(path))
+		for i, name := range path {
+			name = strings.Join(strings.Fields(name), " ")
+			name = strings.ReplaceAll(name, ":", "-")
+			if name == "" {
+				name = "Unnamed"
+			}
+			names[i] = name
+		}
+		return strings.Join(names, ":")
+	}
+
+	oneLine := func(s string) string {
+		s = strings.Join(strings.Fields(s), " ")
+		return strings.ReplaceAll(s, ";", ",")
+	}
+
+	for _, account := range accounts {
+		fmt.Fprintf(bw, "account %s\n", accountName(account))
+	}
+
+	for _, t := range transactions {
+		fmt.Fprintf(bw, "\n%s", t.date)
+		if description := oneLine(t.description); description != "" {
+			fmt.Fprintf(bw, " %s", description)
+		}
+		if len(t.tags) > 0 {
+			tags := make([]string, len(t.tags))
+			for i, tag := range t.tags {
+				tags[i] = strings.Map(func(r rune) rune {
+					if unicode.IsSpace(r) || r == ':' || r == ',' {
+						return '-'
+					}
+					return r
+				}, tag) + ":"
+			}
+			fmt.Fprintf(bw, "  ; %s", strings.Join(tags, ", "))
+		}
+		fmt.Fprintln(bw)
+
+		for _, p := range t.postings {
+			fmt.Fprintf(bw, "    %s  %s %s", accountName(p.account), p.amount, p.currency)
+			if p.price.Valid {
+				fmt.Fprintf(bw, " @@ %s %s", p.price.Decimal.Abs(), p.priceCurrency)
+			}
+			if p.comment != "" {
+				fmt.Fprintf(bw, "  ; %s", oneLine(p.comment))
+			}
+			fmt.Fprintln(bw)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// writeBeancount writes the data in beancount format. Beancount is stricter
+// than ledger, so every account is opened first and the account names only
+// contain letters, numbers and dash.
+func writeBeancount(w io.Writer, data Data) error {
+	bw := bufio.NewWriter(w)
+	accounts, transactions := ledgerData(data)
+
+	accountName := func(path []string) string {
+		names := make([]string, len(path))
+		for i, name := range path {
+			words := strings.FieldsFunc(name, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			})
+
+			name = strings.Join(words, "-")
+			if name == "" {
+				name = "Unnamed"
+			}
+
+			runes := []rune(name)
+			runes[0] = unicode.ToUpper(runes[0])
+			names[i] = string(runes)
+		}
+		return strings.Join(names, ":")
+	}
+
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ")
+
+	if data.BaseCurrency != "" {
+		fmt.Fprintf(bw, "option \"operating_currency\" \"%s\"\n\n", data.BaseCurrency)
+	}
+
+	date := openingDate(data)
+	for _, account := range accounts {
+		fmt.Fprintf(bw, "%s open %s\n", date, accountName(account))
+	}
+
+	for _, t := range transactions {
+		fmt.Fprintf(bw, "\n%s * \"%s\"", t.date, quote.Replace(t.description))
+		for _, tag := range t.tags {
+			fmt.Fprintf(bw, " #%s", strings.Map(func(r rune) rune {
+				if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) ||
+					strings.ContainsRune("-_/.", r)) {
+					return r
+				}
+				return '-'
+			}, tag))
+		}
+		fmt.Fprintln(bw)
+
+		for _, p := range t.postings {
+			fmt.Fprintf(bw, "  %s  %s %s", accountName(p.account), p.amount, p.currency)
+			if p.price.Valid {
+				fmt.Fprintf(bw, " @@ %s %s", p.price.Decimal.Abs(), p.priceCurrency)
+			}
+			fmt.Fprintln(bw)
+
+			if p.comment != "" {
+				fmt.Fprintf(bw, "    description: \"%s\"\n", quote.Replace(p.comment))
+			}
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,104 @@
+// Package importer reads transactions from statement files
+// exported by banks, e.g. OFX and QIF.
+package importer
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v3"
+)
+
+// Transaction is a single transaction in statement file.
+// Positive amount means money is received, while negative
+// amount means money is spent.
+type Transaction struct {
+	ID          string
+	Date        string
+	Amount      decimal.Decimal
+	Description string
+}
+
+// Rows converts transactions into rows that can be imported as entries.
+// Transaction with positive amount becomes income, while negative amount
+// becomes expense. Transactions without ID will use hash of its content
+// as ID, so they still can be detected when imported twice.
+func Rows(transactions []Transaction) []model.ImportRow {
+	occurrences := make(map[string]int)
+	rows := make([]model.ImportRow, len(transactions))
+
+	for i, t := range transactions {
+		importID := t.ID
+		if importID == "" {
+			// Identical transactions in the same file (e.g. buying the same
+			// thing twice in a day) are differentiated by their order
+			key := fmt.Sprintf("%s|%s|%s", t.Date, t.Amount.String(), t.Description)
+			occurrences[key]++
+
+			hash := sha1.Sum([]byte(fmt.Sprintf("%s|%d", key, occurrences[key])))
+			importID = "sha1:" + hex.EncodeToString(hash[:])
+		}
+
+		description := strings.TrimSpace(t.Description)
+		entry := model.Entry{
+			Type:        1,
+			Description: null.NewString(description, description != ""),
+			Amount:      t.Amount.Abs(),
+			Date:        t.Date,
+			ImportID:    null.StringFrom(importID),
+		}
+
+		if t.Amount.IsNegative() {
+			entry.Type = 2
+		}
+
+		rows[i] = model.ImportRow{Line: i + 1, Entry: entry}
+		if t.Amount.IsZero() {
+			rows[i].Error = "amount must not zero"
+		}
+	}
+
+	return rows
+}
+
+// DateLayout converts date format like DD/MM/YYYY into layout that
+// used by Go. If format is empty, ISO 8601 format will be used.
+func DateLayout(format string) string {
+	if format == "" {
+		return "2006-01-02"
+	}
+
+	tokens := []struct{ token, layout string }{
+		{"YYYY", "2006"},
+		{"MMM", "Jan"},
+		{"YY", "06"},
+		{"MM", "01"},
+		{"DD", "02"},
+		{"M", "1"},
+		{"D", "2"},
+	}
+
+	var sb strings.Builder
+	for len(format) > 0 {
+		found := false
+		for _, t := range tokens {
+			if strings.HasPrefix(format, t.token) {
+				sb.WriteString(t.layout)
+				format = format[len(t.token):]
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			sb.WriteByte(format[0])
+			format = format[1:]
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDateLayout(t *testing.T) {
+	tests := []struct {
+		format string
+		layout string
+	}{
+		{"", "2006-01-02"},
+		{"YYYY-MM-DD", "2006-01-02"},
+		{"DD/MM/YYYY", "02/01/2006"},
+		{"M/D/YY", "1/2/06"},
+		{"DD MMM YYYY", "02 Jan 2006"},
+		{"YYYYMMDD", "20060102"},
+	}
+
+	for _, test := range tests {
+		if layout := DateLayout(test.format); layout != test.layout {
+			t.Errorf("%q: expected layout %q, got %q", test.format, test.layout, layout)
+		}
+	}
+}
+
+func TestRows(t *testing.T) {
+	transactions := []Transaction{
+		{ID: "T1", Date: "2020-01-01", Amount: decimal.New(100, 0), Description: " Salary "},
+		{Date: "2020-01-02", Amount: decimal.New(-5, 0), Description: "Coffee"},
+		{Date: "2020-01-02", Amount: decimal.New(-5, 0), Description: "Coffee"},
+		{Date: "2020-01-03", Amount: decimal.Zero},
+	}
+
+	rows := Rows(transactions)
+	if len(rows) != len(transactions) {
+		t.Fatalf("expected %d rows, got %d", len(transactions), len(rows))
+	}
+
+	first := rows[0].Entry
+	if first.Type != 1 || first.ImportID.String != "T1" || first.Description.String != "Salary" {
+		t.Errorf("unexpected income %+v", first)
+	}
+
+	// Identical transactions without ID get different hash IDs
+	second, third := rows[1].Entry, rows[2].Entry
+	if second.Type != 2 || !second.Amount.Equal(decimal.New(5, 0)) {
+		t.Errorf("unexpected expense %+v", second)
+	}
+
+	if second.ImportID.String == "" || second.ImportID.String == third.ImportID.String {
+		t.Errorf("expected different import IDs, got %q and %q",
+			second.ImportID.String, third.ImportID.String)
+	}
+
+	// Hash ID is stable when imported again
+	if again := Rows(transactions); again[1].Entry.ImportID != second.ImportID {
+		t.Errorf("expected stable import ID, got %q and %q",
+			second.ImportID.String, again[1].Entry.ImportID.String)
+	}
+
+	if rows[3].Error == "" || rows[3].Entry.Description.Valid {
+		t.Errorf("expected zero amount to be error, got %+v", rows[3])
+	}
+}
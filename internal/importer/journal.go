@@ -0,0 +1,713 @@
+package importer
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/shopspring/decimal"
+	"gopkg.in/guregu/null.v3"
+)
+
+// JournalAccount is an asset or liability account in journal.
+// Its initial amount is taken from opening balance transactions,
+// i.e. transactions between the account and an equity account.
+type JournalAccount struct {
+	Name          string          `json:"name"`
+	Currency      string          `json:"currency"`
+	InitialAmount decimal.Decimal `json:"initialAmount"`
+}
+
+// Journal is accounts and entries read from hledger, ledger or beancount
+// journal. In the entries, account and category are specified by their
+// name, where sub category is separated from its parent by colon. The
+// transactions that can't be represented as entry are kept as row with
+// error, so they can be reported to user.
+type Journal struct {
+	Accounts []JournalAccount  `json:"accounts"`
+	Rows     []model.ImportRow `json:"rows"`
+}
+
+type journalPosting struct {
+	account  string
+	amount   decimal.NullDecimal
+	currency string
+	comment  string
+	err      string
+
+	// price is the total cost of the posting in other currency
+	price         decimal.NullDecimal
+	priceCurrency string
+}
+
+type journalTransaction struct {
+	line        int
+	date        string
+	description string
+	comment     string
+	tags        []string
+	postings    []journalPosting
+}
+
+// journalDateLayouts is the date layouts allowed in journal.
+var journalDateLayouts = []string{"2006-1-2", "2006/1/2", "2006.1.2"}
+
+// commoditySymbols is the currency symbols that commonly used in ledger.
+var commoditySymbols = map[string]string{
+	"$":  "USD",
+	"€":  "EUR",
+	"£":  "GBP",
+	"¥":  "JPY",
+	"Rp": "IDR",
+}
+
+var (
+	rxJournalAmount = regexp.MustCompile(`^([-+]?)\s*([^\s\d.,+-]*)\s*([-+]?[\d.,]*\d[\d.,]*)\s*([^\s\d.,+-]*)$`)
+	rxMetadata      = regexp.MustCompile(`^[a-z][a-zA-Z0-9_-]*:(\s|$)`)
+)
+
+// ParseJournal reads accounts and entries from hledger, ledger or beancount
+// journal. Only the common subset of their syntax is supported, i.e. dated
+// transactions, account and open directives, and comments. Comments are used
+// as description when the transaction doesn't have any.
+func ParseJournal(r io.Reader) (Journal, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	journal := Journal{
+		Accounts: []JournalAccount{},
+		Rows:     []model.ImportRow{},
+	}
+
+	accountIdx := make(map[string]int)
+	declareAccount := func(name, currency string) {
+		if _, exist := accountIdx[name]; !exist && name != "" {
+			accountIdx[name] = len(journal.Accounts)
+			journal.Accounts = append(journal.Accounts, JournalAccount{
+				Name:     name,
+				Currency: currency,
+			})
+		}
+	}
+
+	transactions := []journalTransaction{}
+	var current *journalTransaction
+
+	for nLine := 1; scanner.Scan(); nLine++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" {
+			current = nil
+			continue
+		}
+
+		// Indented line is either a posting, comment or metadata of
+		// the transaction above it. Indented lines below directives
+		// are not needed, so they are skipped.
+		if line[0] == ' ' || line[0] == '\t' {
+			if current == nil {
+				continue
+			}
+
+			line = strings.TrimSpace(line)
+			nPostings := len(current.postings)
+
+			switch {
+			case line[0] == ';' || line[0] == '#':
+				comment, tags := parseJournalComment(line[1:])
+				current.tags = append(current.tags, tags...)
+				if nPostings > 0 {
+					current.postings[nPostings-1].comment = joinDescription(
+						current.postings[nPostings-1].comment, comment)
+				} else {
+					current.comment = joinDescription(current.comment, comment)
+				}
+
+			case rxMetadata.MatchString(line):
+				parts := strings.SplitN(line, ":", 2)
+				if parts[0] != "description" {
+					continue
+				}
+
+				value := unquoteJournalString(strings.TrimSpace(parts[1]))
+				if nPostings > 0 {
+					current.postings[nPostings-1].comment = value
+				} else {
+					current.comment = joinDescription(current.comment, value)
+				}
+
+			default:
+				current.postings = append(current.postings, parseJournalPosting(line))
+			}
+
+			continue
+		}
+
+		// Unindented line is either a transaction or a directive.
+		// Directive that doesn't declare account is skipped.
+		current = nil
+		fields := strings.Fields(line)
+
+		if fields[0] == "account" && len(fields) > 1 {
+			account, _ := splitJournalComment(line[len("account"):])
+			if name, root := journalAccountName(strings.TrimSpace(account)); isJournalAsset(root) {
+				declareAccount(name, "")
+			}
+			continue
+		}
+
+		if line[0] < '0' || line[0] > '9' {
+			continue
+		}
+
+		// Here the line starts with date. Ledger allows secondary date
+		// after equal sign, which is not needed here.
+		dateText := strings.SplitN(fields[0], "=", 2)[0]
+		date, err := parseJournalDate(dateText)
+		if err != nil {
+			journal.Rows = append(journal.Rows, model.ImportRow{
+				Line:  nLine,
+				Error: fmt.Sprintf("invalid date %q", dateText),
+			})
+			continue
+		}
+
+		if len(fields) > 1 {
+			switch fields[1] {
+			case "open":
+				if len(fields) > 2 {
+					currency := ""
+					if len(fields) > 3 && fields[3][0] != ';' {
+						currency = strings.Split(fields[3], ",")[0]
+					}
+
+					if name, root := journalAccountName(fields[2]); isJournalAsset(root) {
+						declareAccount(name, currency)
+					}
+				}
+				continue
+
+			case "close", "commodity", "price", "balance", "pad", "note",
+				"document", "event", "custom", "query":
+				continue
+			}
+		}
+
+		header := strings.TrimSpace(line[len(fields[0]):])
+		description, comment, tags := parseJournalHeader(header)
+		transactions = append(transactions, journalTransaction{
+			line:        nLine,
+			date:        date,
+			description: description,
+			comment:     comment,
+			tags:        tags,
+		})
+		current = &transactions[len(transactions)-1]
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Journal{}, err
+	}
+
+	// Convert the transactions into entries
+	occurrences := make(map[string]int)
+	for _, t := range transactions {
+		row, postings, err := journalEntry(t)
+		if err == nil {
+			err = checkJournalCurrencies(journal, accountIdx, postings)
+		}
+
+		if err != nil {
+			row.Error = err.Error()
+			journal.Rows = append(journal.Rows, row)
+			continue
+		}
+
+		for _, p := range postings {
+			name, _ := journalAccountName(p.account)
+			declareAccount(name, p.currency)
+
+			account := &journal.Accounts[accountIdx[name]]
+			if account.Currency == "" {
+				account.Currency = p.currency
+			}
+		}
+
+		// Opening balance is added to the initial amount of account
+		if row.Entry.Type == 0 {
+			name, _ := journalAccountName(postings[0].account)
+			account := &journal.Accounts[accountIdx[name]]
+			account.InitialAmount = account.InitialAmount.Add(postings[0].amount.Decimal)
+			continue
+		}
+
+		// Journal doesn't have transaction ID, so hash of its content is
+		// used instead, so it won't be imported twice.
+		entry := row.Entry
+		key := fmt.Sprintf("%s|%d|%s|%s|%s|%s", entry.Date, entry.Type,
+			entry.Amount.String(), entry.Account, entry.AffectedAccount.String,
+			entry.Description.String)
+		occurrences[key]++
+
+		hash := sha1.Sum([]byte(fmt.Sprintf("%s|%d", key, occurrences[key])))
+		row.Entry.ImportID = null.StringFrom("journal:" + hex.EncodeToString(hash[:]))
+		journal.Rows = append(journal.Rows, row)
+	}
+
+	return journal, nil
+}
+
+// journalEntry converts a transaction into entry. It returns the postings
+// of assets, which used to check the currency of accounts. If the entry type
+// is zero, the transaction is an opening balance for the first asset.
+func journalEntry(t journalTransaction) (model.ImportRow, []journalPosting, error) {
+	description := t.description
+	if description == "" {
+		description = t.comment
+	}
+
+	row := model.ImportRow{
+		Line: t.line,
+		Entry: model.Entry{
+			Date:        t.date,
+			Description: null.NewString(description, description != ""),
+			Tags:        t.tags,
+		},
+	}
+
+	// Make sure all postings are valid
+	for _, p := range t.postings {
+		if p.err != "" {
+			return row, nil, fmt.Errorf("%s: %s", p.account, p.err)
+		}
+	}
+
+	postings, err := balanceJournalPostings(t.postings)
+	if err != nil {
+		return row, nil, err
+	}
+
+	// Group the postings by the root of its account
+	var assets, equities, others []journalPosting
+	for _, p := range postings {
+		_, root := journalAccountName(p.account)
+		switch {
+		case isJournalAsset(root):
+			assets = append(assets, p)
+		case root == "equity":
+			equities = append(equities, p)
+		case root == "income" || root == "revenue" || root == "revenues" || root == "expenses":
+			others = append(others, p)
+		default:
+			return row, nil, fmt.Errorf("type of account %s is unknown", p.account)
+		}
+	}
+
+	switch {
+	// Transfer between two accounts
+	case len(assets) == 2 && len(equities) == 0 && len(others) == 0:
+		source, target := assets[0], assets[1]
+		if source.amount.Decimal.IsPositive() {
+			source, target = target, source
+		}
+
+		if !source.amount.Decimal.IsNegative() || !target.amount.Decimal.IsPositive() {
+			return row, nil, fmt.Errorf("transfer must be from one account to another")
+		}
+
+		sourceName, _ := journalAccountName(source.account)
+		targetName, _ := journalAccountName(target.account)
+		if sourceName == targetName {
+			return row, nil, fmt.Errorf("transfer must be between different accounts")
+		}
+
+		row.Entry.Type = 3
+		row.Entry.Account = sourceName
+		row.Entry.AffectedAccount = null.StringFrom(targetName)
+		row.Entry.Amount = source.amount.Decimal.Neg()
+		if source.currency != target.currency {
+			row.Entry.AffectedAmount = target.amount
+		}
+
+	// Opening balance
+	case len(assets) == 1 && len(equities) > 0 && len(others) == 0:
+		row.Entry.Account, _ = journalAccountName(assets[0].account)
+
+	// Income or expense, which might be splitted into several categories
+	case len(assets) == 1 && len(equities) == 0 && len(others) > 0:
+		asset := assets[0]
+		if asset.amount.Decimal.IsZero() {
+			return row, nil, fmt.Errorf("amount must not zero")
+		}
+
+		row.Entry.Type = 1
+		row.Entry.Account, _ = journalAccountName(asset.account)
+		row.Entry.Amount = asset.amount.Decimal
+		if asset.amount.Decimal.IsNegative() {
+			row.Entry.Type = 2
+			row.Entry.Amount = asset.amount.Decimal.Neg()
+		}
+
+		if len(others) == 1 {
+			row.Entry.Category = journalCategory(others[0].account)
+			break
+		}
+
+		for _, p := range others {
+			amount := p.amount.Decimal
+			if row.Entry.Type == 1 {
+				amount = amount.Neg()
+			}
+
+			if !amount.IsPositive() {
+				return row, nil, fmt.Errorf("postings of income and expense can't be mixed")
+			}
+
+			if p.currency != asset.currency {
+				return row, nil, fmt.Errorf("splitted postings must use the same currency")
+			}
+
+			row.Entry.Splits = append(row.Entry.Splits, model.EntrySplit{
+				Category:    journalCategory(p.account),
+				Description: null.NewString(p.comment, p.comment != ""),
+				Amount:      amount,
+			})
+		}
+
+	default:
+		return row, nil, fmt.Errorf("transaction with %d assets, %d equities and "+
+			"%d income or expense postings can't be represented",
+			len(assets), len(equities), len(others))
+	}
+
+	return row, assets, nil
+}
+
+// balanceJournalPostings fills amount of the posting that doesn't have it,
+// using the negated sum of the other postings.
+func balanceJournalPostings(postings []journalPosting) ([]journalPosting, error) {
+	if len(postings) < 2 {
+		return nil, fmt.Errorf("transaction must have at least two postings")
+	}
+
+	emptyIdx := -1
+	sums := make(map[string]decimal.Decimal)
+	for i, p := range postings {
+		if !p.amount.Valid {
+			if emptyIdx >= 0 {
+				return nil, fmt.Errorf("only one posting may have no amount")
+			}
+			emptyIdx = i
+			continue
+		}
+
+		if p.price.Valid {
+			sums[p.priceCurrency] = sums[p.priceCurrency].Add(p.price.Decimal)
+		} else {
+			sums[p.currency] = sums[p.currency].Add(p.amount.Decimal)
+		}
+	}
+
+	if emptyIdx < 0 {
+		return postings, nil
+	}
+
+	if len(sums) != 1 {
+		return nil, fmt.Errorf("amount of posting %s can't be inferred", postings[emptyIdx].account)
+	}
+
+	result := append([]journalPosting{}, postings...)
+	for currency, sum := range sums {
+		result[emptyIdx].amount = decimal.NullDecimal{Decimal: sum.Neg(), Valid: true}
+		result[emptyIdx].currency = currency
+	}
+
+	return result, nil
+}
+
+// checkJournalCurrencies makes sure each asset posting uses the same
+// currency as its account.
+func checkJournalCurrencies(journal Journal, accountIdx map[string]int, postings []journalPosting) error {
+	for _, p := range postings {
+		name, _ := journalAccountName(p.account)
+		idx, exist := accountIdx[name]
+		if !exist {
+			continue
+		}
+
+		currency := journal.Accounts[idx].Currency
+		if currency != "" && p.currency != "" && currency != p.currency {
+			return fmt.Errorf("account %s uses %s, but the posting uses %s",
+				p.account, currency, p.currency)
+		}
+	}
+
+	return nil
+}
+
+// parseJournalHeader parses the line of transaction after its date. In
+// beancount the description is quoted and optionally preceded by payee,
+// while in ledger it's the rest of line after status and code.
+func parseJournalHeader(header string) (description, comment string, tags []string) {
+	header, comment = splitJournalComment(header)
+	comment, tags = parseJournalComment(comment)
+
+	// Remove status, flag and code
+	header = strings.TrimSpace(header)
+	header = strings.TrimPrefix(header, "txn")
+	header = strings.TrimLeft(header, "*! \t")
+	if strings.HasPrefix(header, "(") {
+		if idx := strings.Index(header, ")"); idx >= 0 {
+			header = strings.TrimSpace(header[idx+1:])
+		}
+	}
+
+	if !strings.HasPrefix(header, `"`) {
+		return header, comment, tags
+	}
+
+	// Beancount has quoted payee and narration, followed by tags and links
+	texts := []string{}
+	for len(header) > 0 {
+		if header[0] == '"' {
+			end := 1
+			for end < len(header) && header[end] != '"' {
+				if header[end] == '\\' {
+					end++
+				}
+				end++
+			}
+
+			// Unterminated quote is read until the end of line
+			if end >= len(header) {
+				end = len(header) - 1
+			}
+
+			if text := unquoteJournalString(header[:end+1]); text != "" {
+				texts = append(texts, text)
+			}
+			header = strings.TrimSpace(header[end+1:])
+			continue
+		}
+
+		fields := strings.Fields(header)
+		if strings.HasPrefix(fields[0], "#") {
+			tags = append(tags, fields[0][1:])
+		}
+		header = strings.TrimSpace(header[len(fields[0]):])
+	}
+
+	return strings.Join(texts, " - "), comment, tags
+}
+
+// parseJournalComment separates hledger tags, i.e. words followed by colon,
+// from the comment.
+func parseJournalComment(comment string) (string, []string) {
+	texts := []string{}
+	tags := []string{}
+
+	for _, part := range strings.Split(comment, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if idx := strings.Index(part, ":"); idx > 0 && !strings.ContainsAny(part[:idx], " \t") {
+			tags = append(tags, part[:idx])
+			continue
+		}
+
+		texts = append(texts, part)
+	}
+
+	return strings.Join(texts, ", "), tags
+}
+
+// parseJournalPosting parses posting line, which contains account, amount and
+// comment. Ledger separates account and amount by at least two spaces,
+// since account name may contain space.
+func parseJournalPosting(line string) journalPosting {
+	line, comment := splitJournalComment(line)
+	line = strings.TrimSpace(strings.TrimLeft(line, "*!"))
+	comment = strings.TrimSpace(comment)
+
+	var p journalPosting
+	p.account, p.comment = line, comment
+
+	amountText := ""
+	if idx := strings.IndexAny(line, "\t"); idx >= 0 {
+		p.account, amountText = line[:idx], line[idx:]
+	} else if idx := strings.Index(line, "  "); idx >= 0 {
+		p.account, amountText = line[:idx], line[idx:]
+	} else if fields := strings.Fields(line); len(fields) > 1 {
+		// Beancount account doesn't contain space, so
+		// the rest of line is amount if it can be parsed
+		rest := strings.TrimSpace(line[len(fields[0]):])
+		units := strings.FieldsFunc(rest, func(r rune) bool {
+			return r == '@' || r == '=' || r == '{'
+		})
+
+		if len(units) > 0 {
+			if _, _, err := parseJournalAmount(units[0]); err == nil {
+				p.account, amountText = fields[0], rest
+			}
+		}
+	}
+
+	p.account = strings.TrimSpace(p.account)
+	amountText = strings.TrimSpace(amountText)
+
+	if strings.HasPrefix(p.account, "(") || strings.HasPrefix(p.account, "[") {
+		p.err = "virtual posting is not supported"
+		return p
+	}
+
+	// Balance assertion is not needed
+	if idx := strings.Index(amountText, "="); idx >= 0 {
+		amountText = strings.TrimSpace(amountText[:idx])
+	}
+
+	if amountText == "" {
+		return p
+	}
+
+	if strings.ContainsAny(amountText, "{}") {
+		p.err = "cost basis is not supported"
+		return p
+	}
+
+	// Parse price, which either total price (@@) or unit price (@)
+	priceText := ""
+	totalPrice := false
+	if idx := strings.Index(amountText, "@@"); idx >= 0 {
+		amountText, priceText, totalPrice = amountText[:idx], amountText[idx+2:], true
+	} else if idx := strings.Index(amountText, "@"); idx >= 0 {
+		amountText, priceText = amountText[:idx], amountText[idx+1:]
+	}
+
+	amount, currency, err := parseJournalAmount(amountText)
+	if err != nil {
+		p.err = err.Error()
+		return p
+	}
+
+	p.amount = decimal.NullDecimal{Decimal: amount, Valid: true}
+	p.currency = currency
+
+	if priceText != "" {
+		price, currency, err := parseJournalAmount(priceText)
+		if err != nil {
+			p.err = err.Error()
+			return p
+		}
+
+		if !totalPrice {
+			price = price.Mul(amount.Abs())
+		}
+
+		if amount.IsNegative() {
+			price = price.Abs().Neg()
+		}
+
+		p.price = decimal.NullDecimal{Decimal: price, Valid: true}
+		p.priceCurrency = currency
+	}
+
+	return p
+}
+
+// parseJournalAmount parses amount along with its commodity,
+// which might be written before or after the number.
+func parseJournalAmount(text string) (decimal.Decimal, string, error) {
+	text = strings.TrimSpace(text)
+	matches := rxJournalAmount.FindStringSubmatch(text)
+	if matches == nil || (matches[2] != "" && matches[4] != "") {
+		return decimal.Zero, "", fmt.Errorf("invalid amount %q", text)
+	}
+
+	amount, err := decimal.NewFromString(strings.ReplaceAll(matches[3], ",", ""))
+	if err != nil {
+		return decimal.Zero, "", fmt.Errorf("invalid amount %q", text)
+	}
+
+	if matches[1] == "-" {
+		amount = amount.Neg()
+	}
+
+	currency := matches[2] + matches[4]
+	if code, exist := commoditySymbols[currency]; exist {
+		currency = code
+	}
+
+	return amount, currency, nil
+}
+
+// parseJournalDate parses date which written as year, month and day.
+func parseJournalDate(text string) (string, error) {
+	for _, layout := range journalDateLayouts {
+		if date, err := time.Parse(layout, text); err == nil {
+			return date.Format("2006-01-02"), nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid date %q", text)
+}
+
+// journalAccountName returns the name of account without its root, e.g.
+// Assets:Bank:Saving becomes Bank:Saving. The root is returned in lowercase.
+func journalAccountName(account string) (name, root string) {
+	parts := strings.SplitN(account, ":", 2)
+	root = strings.ToLower(parts[0])
+	if len(parts) == 1 {
+		return parts[0], root
+	}
+
+	return parts[1], root
+}
+
+// isJournalAsset checks if the account root is for account in duit.
+func isJournalAsset(root string) bool {
+	return root == "assets" || root == "liabilities"
+}
+
+// journalCategory returns the category of income or expense account.
+func journalCategory(account string) null.String {
+	name, _ := journalAccountName(account)
+	if !strings.Contains(account, ":") || name == "Uncategorized" {
+		return null.String{}
+	}
+
+	return null.StringFrom(name)
+}
+
+// splitJournalComment separates the text from its comment.
+// Semicolon inside quoted text is not a comment.
+func splitJournalComment(line string) (string, string) {
+	quoted := false
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ';' && !quoted:
+			return line[:i], line[i+1:]
+		}
+	}
+
+	return line, ""
+}
+
+// unquoteJournalString removes quote and escape character from the string.
+func unquoteJournalString(text string) string {
+	if len(text) < 2 || text[0] != '"' || text[len(text)-1] != '"' {
+		return strings.TrimFunc(text, unicode.IsSpace)
+	}
+
+	text = text[1 : len(text)-1]
+	return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(text)
+}
@@ -0,0 +1,222 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/shopspring/decimal"
+)
+
+func TestParseJournalAmount(t *testing.T) {
+	tests := []struct {
+		text     string
+		amount   string
+		currency string
+		invalid  bool
+	}{
+		{"10", "10", "", false},
+		{"$1,234.50", "1234.5", "USD", false},
+		{"-$5", "-5", "USD", false},
+		{"$-5", "-5", "USD", false},
+		{"25.00 EUR", "25", "EUR", false},
+		{"Rp 50000", "50000", "IDR", false},
+		{"-3 AAPL", "-3", "AAPL", false},
+		{"$5 USD", "", "", true},
+		{"EUR", "", "", true},
+	}
+
+	for _, test := range tests {
+		amount, currency, err := parseJournalAmount(test.text)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("%q: expected error, got %s %s", test.text, amount, currency)
+			}
+			continue
+		}
+
+		if err != nil || !amount.Equal(decimal.RequireFromString(test.amount)) || currency != test.currency {
+			t.Errorf("%q: expected %s %s, got %s %s (%v)",
+				test.text, test.amount, test.currency, amount, currency, err)
+		}
+	}
+}
+
+func TestParseJournalLedger(t *testing.T) {
+	content := `; hledger journal
+account Assets:Bank
+account Expenses:Food
+
+2020-01-01 Opening balance
+    Assets:Bank          $1,000.00
+    Equity:Opening
+
+2020/01/02 * (42) Supermarket  ; weekly, trip:yes
+    Expenses:Food:Groceries     $30.00  ; vegetables
+    Expenses:Household          $20.00
+    Assets:Bank
+
+2020-01-03 Salary
+    Assets:Bank          $2,000.00
+    Income:Salary
+
+2020-01-04 Withdraw
+    Assets:Bank          $-100.00
+    Assets:Cash          $100.00
+
+2020-01-05 Travel money
+    Assets:Wallet        100.00 EUR @@ $110.00
+    Assets:Bank
+
+2020-01-06 Virtual
+    Assets:Bank          $5
+    (Budget:Food)        $-5
+
+2020-01-07 Mixed
+    Expenses:Food        $10
+    Income:Refund        $-15
+    Assets:Bank
+
+2020-13-01 Bad date
+    Assets:Bank          $5
+    Expenses:Food
+`
+
+	journal, err := ParseJournal(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to parse journal: %v", err)
+	}
+
+	// Accounts are declared by directive or posting, with
+	// initial amount from the opening balance
+	accounts := map[string]JournalAccount{}
+	for _, account := range journal.Accounts {
+		accounts[account.Name] = account
+	}
+
+	if len(accounts) != 3 {
+		t.Errorf("expected 3 accounts, got %+v", journal.Accounts)
+	}
+
+	if bank := accounts["Bank"]; bank.Currency != "USD" || !bank.InitialAmount.Equal(decimal.New(1000, 0)) {
+		t.Errorf("unexpected bank account %+v", bank)
+	}
+
+	if wallet := accounts["Wallet"]; wallet.Currency != "EUR" {
+		t.Errorf("unexpected wallet account %+v", wallet)
+	}
+
+	if len(journal.Rows) != 7 {
+		t.Fatalf("expected 7 rows, got %d (%+v)", len(journal.Rows), journal.Rows)
+	}
+
+	rowAt := func(line int) model.ImportRow {
+		for _, row := range journal.Rows {
+			if row.Line == line {
+				return row
+			}
+		}
+
+		t.Fatalf("row at line %d doesn't exist", line)
+		return model.ImportRow{}
+	}
+
+	// Expense splitted into several categories, with inferred amount
+	row := rowAt(9)
+	entry := row.Entry
+	if row.Error != "" || entry.Type != 2 || entry.Account != "Bank" ||
+		!entry.Amount.Equal(decimal.New(50, 0)) || entry.Date != "2020-01-02" ||
+		entry.Description.String != "Supermarket" || entry.ImportID.String == "" {
+		t.Errorf("unexpected splitted expense %+v (%s)", entry, row.Error)
+	}
+
+	if strings.Join(entry.Tags, ",") != "trip" {
+		t.Errorf("expected tag trip, got %v", entry.Tags)
+	}
+
+	if len(entry.Splits) != 2 ||
+		entry.Splits[0].Category.String != "Food:Groceries" ||
+		entry.Splits[0].Description.String != "vegetables" ||
+		!entry.Splits[0].Amount.Equal(decimal.New(30, 0)) ||
+		entry.Splits[1].Category.String != "Household" ||
+		!entry.Splits[1].Amount.Equal(decimal.New(20, 0)) {
+		t.Errorf("unexpected splits %+v", entry.Splits)
+	}
+
+	// Income with a single category
+	entry = rowAt(14).Entry
+	if entry.Type != 1 || !entry.Amount.Equal(decimal.New(2000, 0)) || entry.Category.String != "Salary" {
+		t.Errorf("unexpected income %+v", entry)
+	}
+
+	// Transfers, where the one between currencies has affected amount
+	entry = rowAt(18).Entry
+	if entry.Type != 3 || entry.Account != "Bank" || entry.AffectedAccount.String != "Cash" ||
+		!entry.Amount.Equal(decimal.New(100, 0)) || entry.AffectedAmount.Valid {
+		t.Errorf("unexpected transfer %+v", entry)
+	}
+
+	entry = rowAt(22).Entry
+	if entry.Type != 3 || entry.Account != "Bank" || entry.AffectedAccount.String != "Wallet" ||
+		!entry.Amount.Equal(decimal.New(110, 0)) ||
+		!entry.AffectedAmount.Decimal.Equal(decimal.New(100, 0)) {
+		t.Errorf("unexpected transfer between currencies %+v", entry)
+	}
+
+	// Transactions that can't be represented are kept with error
+	for _, line := range []int{26, 30, 35} {
+		if row := rowAt(line); row.Error == "" {
+			t.Errorf("line %d: expected error, got %+v", line, row.Entry)
+		}
+	}
+}
+
+func TestParseJournalBeancount(t *testing.T) {
+	content := `option "operating_currency" "USD"
+
+2020-01-01 open Assets:Checking USD
+2020-01-01 open Liabilities:CreditCard USD
+2020-01-01 open Expenses:Dining
+
+2020-01-02 * "Cafe" "Lunch with \"Bob\"" #work
+  Liabilities:CreditCard  -12.50 USD
+  Expenses:Dining          12.50 USD
+
+2020-01-03 txn "Pay card"
+  description: "Monthly payment"
+  Assets:Checking         -12.50 USD
+  Liabilities:CreditCard
+`
+
+	journal, err := ParseJournal(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to parse journal: %v", err)
+	}
+
+	if len(journal.Accounts) != 2 || journal.Accounts[0].Name != "Checking" ||
+		journal.Accounts[0].Currency != "USD" || journal.Accounts[1].Name != "CreditCard" {
+		t.Errorf("unexpected accounts %+v", journal.Accounts)
+	}
+
+	if len(journal.Rows) != 2 {
+		t.Fatalf("expected 2 rows, got %+v", journal.Rows)
+	}
+
+	entry := journal.Rows[0].Entry
+	if entry.Type != 2 || entry.Account != "CreditCard" || entry.Category.String != "Dining" ||
+		entry.Description.String != `Cafe - Lunch with "Bob"` || strings.Join(entry.Tags, ",") != "work" {
+		t.Errorf("unexpected expense %+v", entry)
+	}
+
+	entry = journal.Rows[1].Entry
+	if entry.Type != 3 || entry.Account != "Checking" || entry.AffectedAccount.String != "CreditCard" ||
+		!entry.Amount.Equal(decimal.RequireFromString("12.5")) || entry.Description.String != "Pay card" {
+		t.Errorf("unexpected transfer %+v", entry)
+	}
+
+	// The same journal produces the same import IDs
+	again, _ := ParseJournal(strings.NewReader(content))
+	if again.Rows[0].Entry.ImportID != journal.Rows[0].Entry.ImportID {
+		t.Errorf("expected stable import ID")
+	}
+}
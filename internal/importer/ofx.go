@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ParseOFX reads transactions from OFX or QFX file. Both SGML (OFX 1.x)
+// and XML (OFX 2.x) variants are supported. In SGML the closing tag of
+// an element is optional, so here the file is read as a stream of tags
+// where each tag might be followed by its value.
+func ParseOFX(r io.Reader) ([]Transaction, error) {
+	reader := bufio.NewReader(r)
+	transactions := []Transaction{}
+
+	var current *Transaction
+	var name, memo string
+
+	finishTransaction := func() error {
+		if current == nil {
+			return nil
+		}
+
+		if current.Date == "" {
+			return fmt.Errorf("transaction %q doesn't have date", current.ID)
+		}
+
+		current.Description = joinDescription(name, memo)
+		transactions = append(transactions, *current)
+		current = nil
+		return nil
+	}
+
+	for {
+		tag, value, err := nextOFXTag(reader)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch tag {
+		case "STMTTRN":
+			if err = finishTransaction(); err != nil {
+				return nil, err
+			}
+
+			current = &Transaction{}
+			name, memo = "", ""
+
+		case "/STMTTRN", "/BANKTRANLIST":
+			if err = finishTransaction(); err != nil {
+				return nil, err
+			}
+
+		case "DTPOSTED":
+			if current == nil {
+				continue
+			}
+
+			if len(value) < 8 {
+				return nil, fmt.Errorf("invalid date %q", value)
+			}
+
+			date, err := time.Parse("20060102", value[:8])
+			if err != nil {
+				return nil, fmt.Errorf("invalid date %q", value)
+			}
+
+			current.Date = date.Format("2006-01-02")
+
+		case "TRNAMT":
+			if current == nil {
+				continue
+			}
+
+			// Some banks use comma as decimal separator
+			if !strings.Contains(value, ".") {
+				value = strings.Replace(value, ",", ".", 1)
+			}
+
+			current.Amount, err = decimal.NewFromString(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid amount %q", value)
+			}
+
+		case "FITID":
+			if current != nil {
+				current.ID = value
+			}
+
+		case "NAME":
+			name = value
+
+		case "MEMO":
+			memo = value
+		}
+	}
+
+	// Handle SGML file that ends without closing tag
+	if err := finishTransaction(); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// nextOFXTag returns the next tag in OFX file along with its value.
+// Header, processing instruction and comment are skipped.
+func nextOFXTag(reader *bufio.Reader) (string, string, error) {
+	for {
+		// Find the start of tag
+		if _, err := reader.ReadString('<'); err != nil {
+			return "", "", err
+		}
+
+		tag, err := reader.ReadString('>')
+		if err != nil {
+			return "", "", err
+		}
+
+		tag = strings.TrimSuffix(tag, ">")
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		// Read the value until the next tag
+		value, err := reader.ReadString('<')
+		if err != nil && err != io.EOF {
+			return "", "", err
+		}
+
+		if err == nil {
+			reader.UnreadByte()
+		}
+
+		value = strings.TrimSuffix(value, "<")
+		value = html.UnescapeString(strings.TrimSpace(value))
+		return strings.ToUpper(strings.TrimSpace(tag)), value, nil
+	}
+}
+
+// joinDescription combines name and memo of transaction.
+func joinDescription(name, memo string) string {
+	switch {
+	case memo == "" || memo == name:
+		return name
+	case name == "":
+		return memo
+	default:
+		return name + " - " + memo
+	}
+}
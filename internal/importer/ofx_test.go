@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseOFX(t *testing.T) {
+	sgml := `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKTRANLIST>
+<DTSTART>20200101
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20200105120000[-5:EST]
+<TRNAMT>-25,50
+<FITID>A1
+<NAME>Coffee &amp; Co
+<MEMO>Card 1234
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20200106
+<TRNAMT>1000.00
+<FITID>A2
+<NAME>Salary
+<MEMO>Salary
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>`
+
+	xml := `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+  <BANKMSGSRSV1><STMTTRNRS><STMTRS>
+    <BANKTRANLIST>
+      <!-- first transaction -->
+      <STMTTRN>
+        <TRNTYPE>DEBIT</TRNTYPE>
+        <DTPOSTED>20200105</DTPOSTED>
+        <TRNAMT>-25.50</TRNAMT>
+        <FITID>A1</FITID>
+        <NAME>Coffee &amp; Co</NAME>
+        <MEMO>Card 1234</MEMO>
+      </STMTTRN>
+      <STMTTRN>
+        <TRNTYPE>CREDIT</TRNTYPE>
+        <DTPOSTED>20200106000000</DTPOSTED>
+        <TRNAMT>1000.00</TRNAMT>
+        <FITID>A2</FITID>
+        <NAME>Salary</NAME>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>`
+
+	expected := []Transaction{
+		{ID: "A1", Date: "2020-01-05", Amount: decimal.RequireFromString("-25.5"), Description: "Coffee & Co - Card 1234"},
+		{ID: "A2", Date: "2020-01-06", Amount: decimal.RequireFromString("1000"), Description: "Salary"},
+	}
+
+	for name, content := range map[string]string{"SGML": sgml, "XML": xml} {
+		transactions, err := ParseOFX(strings.NewReader(content))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+			continue
+		}
+
+		checkTransactions(t, name, transactions, expected)
+	}
+}
+
+func TestParseOFXError(t *testing.T) {
+	tests := map[string]string{
+		"invalid date":   "<STMTTRN><DTPOSTED>2020-01-05<TRNAMT>10</STMTTRN>",
+		"invalid amount": "<STMTTRN><DTPOSTED>20200105<TRNAMT>ten</STMTTRN>",
+		"missing date":   "<STMTTRN><TRNAMT>10<FITID>A1</STMTTRN>",
+	}
+
+	for name, content := range tests {
+		if _, err := ParseOFX(strings.NewReader(content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// checkTransactions compares the parsed transactions with the expected ones.
+func checkTransactions(t *testing.T, name string, transactions, expected []Transaction) {
+	t.Helper()
+
+	if len(transactions) != len(expected) {
+		t.Fatalf("%s: expected %d transactions, got %d (%+v)",
+			name, len(expected), len(transactions), transactions)
+	}
+
+	for i := range expected {
+		got, want := transactions[i], expected[i]
+		if !got.Amount.Equal(want.Amount) {
+			t.Errorf("%s: transaction %d: expected amount %s, got %s", name, i, want.Amount, got.Amount)
+		}
+
+		got.Amount, want.Amount = decimal.Zero, decimal.Zero
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: transaction %d: expected %+v, got %+v", name, i, want, got)
+		}
+	}
+}
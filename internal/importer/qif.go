@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// qifDateLayouts is the date layouts that commonly used in QIF file.
+// QIF is mostly generated by US software, so month is written first.
+var qifDateLayouts = []string{
+	"1/2/2006",
+	"1/2/06",
+	"1-2-2006",
+	"1-2-06",
+	"2006-01-02",
+}
+
+// ParseQIF reads transactions from QIF file. QIF doesn't have a standard
+// date and number format, so they can be specified using layout and decimal
+// separator. If layout is empty, the common US formats will be tried. If
+// decimal separator is empty, it will be guessed from each amount.
+func ParseQIF(r io.Reader, layout string, decimalSeparator string) ([]Transaction, error) {
+	switch decimalSeparator {
+	case "", ".", ",":
+	default:
+		return nil, fmt.Errorf("decimal separator must be either . or ,")
+	}
+
+	layouts := qifDateLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+
+	scanner := bufio.NewScanner(r)
+	transactions := []Transaction{}
+
+	var current Transaction
+	var payee, memo string
+	var hasContent bool
+
+	for nLine := 1; scanner.Scan(); nLine++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// Skip header, e.g. !Type:Bank
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+
+		code, value := line[0], strings.TrimSpace(line[1:])
+		switch code {
+		case 'D':
+			// Some software write year after 2000 with apostrophe
+			value = strings.ReplaceAll(value, "'", "/")
+			value = strings.ReplaceAll(value, " ", "")
+
+			date, err := parseQIFDate(value, layouts)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid date %q", nLine, value)
+			}
+
+			current.Date = date.Format("2006-01-02")
+			hasContent = true
+
+		case 'T', 'U':
+			amount, err := parseQIFAmount(value, decimalSeparator)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", nLine, err)
+			}
+
+			current.Amount = amount
+			hasContent = true
+
+		case 'P':
+			payee = value
+			hasContent = true
+
+		case 'M':
+			memo = value
+			hasContent = true
+
+		case '^':
+			if hasContent {
+				if current.Date == "" {
+					return nil, fmt.Errorf("line %d: transaction doesn't have date", nLine)
+				}
+
+				current.Description = joinDescription(payee, memo)
+				transactions = append(transactions, current)
+			}
+
+			current = Transaction{}
+			payee, memo = "", ""
+			hasContent = false
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Handle file that doesn't end with separator
+	if hasContent && current.Date != "" {
+		current.Description = joinDescription(payee, memo)
+		transactions = append(transactions, current)
+	}
+
+	return transactions, nil
+}
+
+// parseQIFDate parses date using the first layout that matches.
+func parseQIFDate(value string, layouts []string) (time.Time, error) {
+	var err error
+	var date time.Time
+	for _, layout := range layouts {
+		date, err = time.Parse(layout, value)
+		if err == nil {
+			return date, nil
+		}
+	}
+
+	return date, err
+}
+
+// parseQIFAmount parses amount using the specified decimal separator. If it's
+// empty, the last separator in amount is used as decimal separator, e.g. 1,234.5
+// and 1.234,5 are both parsed as 1234.5. Amount that only has one separator
+// followed by three digits is ambiguous, since 1,234 might be 1234 or 1.234, so
+// it's rejected and the decimal separator must be specified.
+func parseQIFAmount(value string, decimalSeparator string) (decimal.Decimal, error) {
+	str := strings.NewReplacer(" ", "", "\u00a0", "", "'", "").Replace(value)
+
+	if decimalSeparator == "" {
+		lastDot := strings.LastIndex(str, ".")
+		lastComma := strings.LastIndex(str, ",")
+
+		last := lastDot
+		decimalSeparator = "."
+		if lastComma > lastDot {
+			last = lastComma
+			decimalSeparator = ","
+		}
+
+		switch {
+		case last < 0:
+		case strings.Count(str, decimalSeparator) > 1:
+			// Separator that used several times is thousands separator,
+			// e.g. 1,234,567, so the decimal separator is the other one
+			if decimalSeparator == "." {
+				decimalSeparator = ","
+			} else {
+				decimalSeparator = "."
+			}
+		case lastDot < 0 || lastComma < 0:
+			if len(str)-last-1 == 3 {
+				return decimal.Zero, fmt.Errorf("ambiguous amount %q, "+
+					"decimal separator must be specified", value)
+			}
+		}
+	}
+
+	thousandsSeparator := ","
+	if decimalSeparator == "," {
+		thousandsSeparator = "."
+	}
+
+	str = strings.ReplaceAll(str, thousandsSeparator, "")
+	str = strings.Replace(str, decimalSeparator, ".", 1)
+
+	amount, err := decimal.NewFromString(str)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid amount %q", value)
+	}
+
+	return amount, nil
+}
@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseQIF(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		layout    string
+		separator string
+		expected  []Transaction
+	}{{
+		name: "common US dates",
+		content: `!Type:Bank
+D1/5/2020
+T-1,234.50
+PRent
+MJanuary
+^
+D1/6'20
+U100.00
+PSalary
+^
+`,
+		expected: []Transaction{
+			{Date: "2020-01-05", Amount: decimal.RequireFromString("-1234.5"), Description: "Rent - January"},
+			{Date: "2020-01-06", Amount: decimal.RequireFromString("100"), Description: "Salary"},
+		},
+	}, {
+		name: "splits are imported as their total",
+		content: `!Type:Bank
+D01/07/2020
+T-75.00
+PSupermarket
+LFood
+SFood:Groceries
+EVegetables
+$-50.00
+SHousehold
+$-25.00
+^
+`,
+		expected: []Transaction{
+			{Date: "2020-01-07", Amount: decimal.RequireFromString("-75"), Description: "Supermarket"},
+		},
+	}, {
+		name:   "custom layout and no final separator",
+		layout: "02.01.2006",
+		content: `!Type:CCard
+D07.01.2020
+T-9.99
+MStreaming`,
+		expected: []Transaction{
+			{Date: "2020-01-07", Amount: decimal.RequireFromString("-9.99"), Description: "Streaming"},
+		},
+	}, {
+		name:   "European amounts are detected from the last separator",
+		layout: "02.01.2006",
+		content: `!Type:Bank
+D07.01.2020
+T-1.234,56
+^
+D08.01.2020
+T2.500.000
+^
+D09.01.2020
+T12,5
+^
+`,
+		expected: []Transaction{
+			{Date: "2020-01-07", Amount: decimal.RequireFromString("-1234.56")},
+			{Date: "2020-01-08", Amount: decimal.RequireFromString("2500000")},
+			{Date: "2020-01-09", Amount: decimal.RequireFromString("12.5")},
+		},
+	}, {
+		name:      "specified decimal separator",
+		separator: ",",
+		content: `!Type:Bank
+D1/7/2020
+T-1.234
+^
+D1/8/2020
+T1.234,5
+^
+`,
+		expected: []Transaction{
+			{Date: "2020-01-07", Amount: decimal.RequireFromString("-1234")},
+			{Date: "2020-01-08", Amount: decimal.RequireFromString("1234.5")},
+		},
+	}}
+
+	for _, test := range tests {
+		transactions, err := ParseQIF(strings.NewReader(test.content), test.layout, test.separator)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+
+		checkTransactions(t, test.name, transactions, test.expected)
+	}
+}
+
+func TestParseQIFError(t *testing.T) {
+	tests := map[string]string{
+		"invalid date":    "D31/31/2020\nT10\n^\n",
+		"invalid amount":  "D1/5/2020\nTten\n^\n",
+		"missing date":    "T10\nPShop\n^\n",
+		"ambiguous comma": "D1/5/2020\nT-1,234\n^\n",
+		"ambiguous dot":   "D1/5/2020\nT1.234\n^\n",
+		"two decimals":    "D1/5/2020\nT1.234.567,8,9\n^\n",
+	}
+
+	for name, content := range tests {
+		if _, err := ParseQIF(strings.NewReader(content), "", ""); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
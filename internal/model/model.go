@@ -1,16 +1,53 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
 	"github.com/shopspring/decimal"
 	"gopkg.in/guregu/null.v3"
 )
 
 // Config is content of configuration file
 type Config struct {
+	DbDriver   string
 	DbUser     string
 	DbPassword string
 	DbHost     string
 	DbName     string
+	DbPath     string
+	DbSSLMode  string
+
+	// BaseCurrency is currency that used when amounts from
+	// accounts with different currencies are combined.
+	BaseCurrency string
+
+	// BackupDir is directory for the scheduled backups. If it's
+	// empty, the server won't create backup automatically.
+	BackupDir string
+
+	// BackupSchedule is cron expression for the scheduled backup,
+	// e.g. "0 2 * * *" for every day at 02:00.
+	BackupSchedule string
+
+	// BackupKeepDaily, BackupKeepWeekly and BackupKeepMonthly are
+	// the number of daily, weekly and monthly backups to keep.
+	BackupKeepDaily   int
+	BackupKeepWeekly  int
+	BackupKeepMonthly int
+
+	// SessionStore is where login sessions are saved, either "memory"
+	// (the default) or "database". Sessions in database are kept
+	// when the server restarted.
+	SessionStore string
+
+	// LoginMaxAttempts and LoginMaxAttemptsPerIP are the number of failed
+	// logins for an username and an IP address before the login is locked
+	// for LoginLockoutMinutes.
+	LoginMaxAttempts      int
+	LoginMaxAttemptsPerIP int
+	LoginLockoutMinutes   int
 }
 
 // User is container for user's data
@@ -26,10 +63,15 @@ type User struct {
 type Account struct {
 	ID            int64           `db:"id"             json:"id"`
 	Name          string          `db:"name"           json:"name"`
+	Currency      string          `db:"currency"       json:"currency"`
 	InitialAmount decimal.Decimal `db:"initial_amount" json:"initialAmount"`
 
 	// Additional fields that used in view
 	Total decimal.Decimal `db:"total" json:"total"`
+
+	// BaseTotal is total that converted into base currency.
+	// It's null if there are no exchange rate for it.
+	BaseTotal decimal.NullDecimal `db:"-" json:"baseTotal"`
 }
 
 // Entry is container for book entries
@@ -41,10 +83,184 @@ type Entry struct {
 	Description       null.String     `db:"description"         json:"description"`
 	Amount            decimal.Decimal `db:"amount"              json:"amount"`
 	Date              string          `db:"date"                json:"date"`
+	CategoryID        null.Int        `db:"category_id"         json:"categoryId"`
+
+	// AffectedAmount is amount that received by affected account in
+	// transfer, which might be different if the currency is different.
+	AffectedAmount decimal.NullDecimal `db:"affected_amount" json:"affectedAmount"`
+
+	// RecurringID is ID of recurring template that generates this entry.
+	RecurringID null.Int `db:"recurring_id" json:"recurringId"`
+
+	// ImportID is ID of transaction in imported statement file,
+	// used to make sure the same transaction not imported twice.
+	ImportID null.String `db:"import_id" json:"importId"`
 
 	// Additional foreign key fields
 	Account         string      `db:"account"          json:"account"`
 	AffectedAccount null.String `db:"affected_account" json:"affectedAccount"`
+	Category        null.String `db:"category"         json:"category"`
+
+	// Tags and splits are saved in separate table,
+	// so they are not fetched directly
+	Tags   []string     `db:"-" json:"tags"`
+	Splits []EntrySplit `db:"-" json:"splits"`
+}
+
+// EntrySplit is a part of entry which has its own amount, category and
+// description. Useful when a single entry covers several categories.
+type EntrySplit struct {
+	ID          int64           `db:"id"          json:"id"`
+	EntryID     int64           `db:"entry_id"    json:"entryId"`
+	CategoryID  null.Int        `db:"category_id" json:"categoryId"`
+	Description null.String     `db:"description" json:"description"`
+	Amount      decimal.Decimal `db:"amount"      json:"amount"`
+
+	// Additional foreign key fields
+	Category null.String `db:"category" json:"category"`
+}
+
+// Recurring is template for entry that repeated periodically.
+// Frequency is either daily, weekly, monthly or yearly, which repeated
+// every N period. The repetition stopped after end date or after it
+// generated the specified number of entries.
+type Recurring struct {
+	ID                int64               `db:"id"                  json:"id"`
+	AccountID         int64               `db:"account_id"          json:"accountId"`
+	AffectedAccountID null.Int            `db:"affected_account_id" json:"affectedAccountId"`
+	CategoryID        null.Int            `db:"category_id"         json:"categoryId"`
+	Type              int                 `db:"type"                json:"type"`
+	Description       null.String         `db:"description"         json:"description"`
+	Amount            decimal.Decimal     `db:"amount"              json:"amount"`
+	AffectedAmount    decimal.NullDecimal `db:"affected_amount"     json:"affectedAmount"`
+	Frequency         string              `db:"frequency"           json:"frequency"`
+	Every             int                 `db:"repeat_every"        json:"every"`
+	DayRule           string              `db:"day_rule"            json:"dayRule"`
+	StartDate         string              `db:"start_date"          json:"startDate"`
+	EndDate           null.String         `db:"end_date"            json:"endDate"`
+	MaxCount          null.Int            `db:"max_count"           json:"maxCount"`
+	LastSeq           int                 `db:"last_seq"            json:"generatedCount"`
+	LastDate          null.String         `db:"last_date"           json:"lastDate"`
+
+	// Additional foreign key fields
+	Account         string      `db:"account"          json:"account"`
+	AffectedAccount null.String `db:"affected_account" json:"affectedAccount"`
+	Category        null.String `db:"category"         json:"category"`
+}
+
+// Budget is monthly spending limit for a category or an account.
+// If rollover is enabled, the unspent amount is carried to the next month.
+type Budget struct {
+	ID         int64           `db:"id"          json:"id"`
+	CategoryID null.Int        `db:"category_id" json:"categoryId"`
+	AccountID  null.Int        `db:"account_id"  json:"accountId"`
+	Amount     decimal.Decimal `db:"amount"      json:"amount"`
+	Rollover   bool            `db:"rollover"    json:"rollover"`
+	StartMonth string          `db:"start_month" json:"startMonth"`
+
+	// Additional foreign key fields
+	Category null.String `db:"category" json:"category"`
+	Account  null.String `db:"account"  json:"account"`
+	Currency string      `db:"currency" json:"currency"`
+}
+
+// BudgetStatus is usage of a budget in a month.
+type BudgetStatus struct {
+	Budget
+	Month      string          `json:"month"`
+	Carried    decimal.Decimal `json:"carried"`
+	Spent      decimal.Decimal `json:"spent"`
+	Remaining  decimal.Decimal `json:"remaining"`
+	Percentage decimal.Decimal `json:"percentage"`
+
+	// MissingRates is currencies of expenses that not included
+	// in spending, since their exchange rate is not entered yet.
+	MissingRates []string `json:"missingRates,omitempty"`
+}
+
+// CSVMapping describes how to read entries from CSV file. Column is
+// zero-based index of the column. Amount is either read from a single
+// amount column whose sign decides the entry type, or from separate
+// debit (expense) and credit (income) columns.
+type CSVMapping struct {
+	Delimiter         string   `json:"delimiter"`
+	SkipRows          int      `json:"skipRows"`
+	DateColumn        int      `json:"dateColumn"`
+	DateFormat        string   `json:"dateFormat"`
+	AmountColumn      null.Int `json:"amountColumn"`
+	DebitColumn       null.Int `json:"debitColumn"`
+	CreditColumn      null.Int `json:"creditColumn"`
+	SignConvention    string   `json:"signConvention"`
+	DescriptionColumn null.Int `json:"descriptionColumn"`
+	DecimalSeparator  string   `json:"decimalSeparator"`
+}
+
+// Value implements driver.Valuer, so mapping can be saved as JSON.
+func (m CSVMapping) Value() (driver.Value, error) {
+	bt, err := json.Marshal(m)
+	return string(bt), err
+}
+
+// Scan implements sql.Scanner, so mapping can be read from JSON.
+func (m *CSVMapping) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	default:
+		return fmt.Errorf("can't scan %T into CSV mapping", src)
+	}
+}
+
+// ImportProfile is saved setting for importing statement from a bank.
+type ImportProfile struct {
+	ID        int64      `db:"id"         json:"id"`
+	Name      string     `db:"name"       json:"name"`
+	AccountID null.Int   `db:"account_id" json:"accountId"`
+	Mapping   CSVMapping `db:"mapping"    json:"mapping"`
+}
+
+// ImportRow is an entry that parsed from imported file.
+// If the row can't be parsed, the error will be specified.
+// Duplicate means the row already imported before.
+type ImportRow struct {
+	Line      int    `json:"line"`
+	Entry     Entry  `json:"entry"`
+	Error     string `json:"error,omitempty"`
+	Duplicate bool   `json:"duplicate,omitempty"`
+}
+
+// Category is container for entry category.
+// Category can be nested by specifying its parent.
+type Category struct {
+	ID       int64    `db:"id"        json:"id"`
+	ParentID null.Int `db:"parent_id" json:"parentId"`
+	Name     string   `db:"name"      json:"name"`
+}
+
+// Tag is container for entry tag
+type Tag struct {
+	ID   int64  `db:"id"   json:"id"`
+	Name string `db:"name" json:"name"`
+
+	// Additional fields that used in view. Income and expense
+	// are converted into base currency, while currencies that
+	// can't be converted are listed in missing rates.
+	Count        int             `db:"count"   json:"count"`
+	Income       decimal.Decimal `db:"-"       json:"income"`
+	Expense      decimal.Decimal `db:"-"       json:"expense"`
+	MissingRates []string        `db:"-"       json:"missingRates,omitempty"`
+}
+
+// Rate is exchange rate between two currencies at a date,
+// i.e. 1 unit of From currency is equal to Rate unit of To currency.
+type Rate struct {
+	ID   int64           `db:"id"            json:"id"`
+	Date string          `db:"date"          json:"date"`
+	From string          `db:"from_currency" json:"from"`
+	To   string          `db:"to_currency"   json:"to"`
+	Rate decimal.Decimal `db:"rate"          json:"rate"`
 }
 
 // ChartSeries is container for chart series
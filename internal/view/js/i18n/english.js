@@ -41,6 +41,8 @@ export default new Map([
 	["Repeat password"],
 	["Welcome, new user"],
 	["Original logo by $author from $website"],
+	["Authentication or recovery code"],
+	["Verify"],
 	// Register and login screen -- API message
 	["new password doesn't match"],
 
@@ -1 +1 @@
-export default new Map([["locale","en-US"],["Jan"],["Feb"],["Mar"],["Apr"],["May"],["Jun"],["Jul"],["Aug"],["Sep"],["Oct"],["Nov"],["Dec"],["January"],["February"],["March"],["April"],["May"],["June"],["July"],["August"],["September"],["October"],["November"],["December"],["Yes"],["No"],["OK"],["Cancel"],["Login"],["Register"],["Name"],["Username"],["Password"],["Repeat password"],["Welcome, new user"],["Original logo by $author from $website"],["new password doesn't match"],["Logout"],["Change Password"],["Change Language"],["Log out from the application ?"],["Home"],["Money chart"],["User management"],["Change password"],["Change language"],["New Account"],["Edit Account"],["Delete Account"],["Entry Type"],["New Income"],["New Expense"],["New Transfer"],["Edit Income"],["Edit Expense"],["Edit Transfer"],["Delete Entry"],["Permanently delete $n accounts ?"],["Permanently delete $n entries ?"],["New User"],["Edit User"],["Delete User"],["Reset Password"],["Permanently delete $n users ?"],["Reset password for $name ?"],["Data for active user has been updated, please login again"],["Current active user has been deleted, please login again"],["Password for active user has been reset, please login again"],["User saved with password $password"],["New password: $password"],["No chart data available"],["Last year"],["Next year"],["Account List"],["Edit account"],["Delete account"],["New account"],["No accounts registered"],["Entry List"],["Edit entry"],["Delete entry"],["New entry"],["No entries registered"],["Received from $name"],["Transferred to $name"],["First page"],["Previous page"],["Next page"],["Last page"],["Go back"],["User List"],["Edit user"],["Reset user's password"],["Delete user"],["New user"],["No users registered"],["English"],["Indonesia"],["Income"],["Expense"],["Transfer"],["Initial amount"],["Amount"],["Entry date"],["Description"],["Target"],["Old password"],["New password"],["Repeat"],["User is administrator"]]);
\ No newline at end of file
+export default new Map([["locale","en-US"],["Jan"],["Feb"],["Mar"],["Apr"],["May"],["Jun"],["Jul"],["Aug"],["Sep"],["Oct"],["Nov"],["Dec"],["January"],["February"],["March"],["April"],["May"],["June"],["July"],["August"],["September"],["October"],["November"],["December"],["Yes"],["No"],["OK"],["Cancel"],["Login"],["Register"],["Name"],["Username"],["Password"],["Repeat password"],["Welcome, new user"],["Original logo by $author from $website"],["Authentication or recovery code"],["Verify"],["new password doesn't match"],["Logout"],["Change Password"],["Change Language"],["Log out from the application ?"],["Home"],["Money chart"],["User management"],["Change password"],["Change language"],["New Account"],["Edit Account"],["Delete Account"],["Entry Type"],["New Income"],["New Expense"],["New Transfer"],["Edit Income"],["Edit Expense"],["Edit Transfer"],["Delete Entry"],["Permanently delete $n accounts ?"],["Permanently delete $n entries ?"],["New User"],["Edit User"],["Delete User"],["Reset Password"],["Permanently delete $n users ?"],["Reset password for $name ?"],["Data for active user has been updated, please login again"],["Current active user has been deleted, please login again"],["Password for active user has been reset, please login again"],["User saved with password $password"],["New password: $password"],["No chart data available"],["Last year"],["Next year"],["Account List"],["Edit account"],["Delete account"],["New account"],["No accounts registered"],["Entry List"],["Edit entry"],["Delete entry"],["New entry"],["No entries registered"],["Received from $name"],["Transferred to $name"],["First page"],["Previous page"],["Next page"],["Last page"],["Go back"],["User List"],["Edit user"],["Reset user's password"],["Delete user"],["New user"],["No users registered"],["English"],["Indonesia"],["Income"],["Expense"],["Transfer"],["Initial amount"],["Amount"],["Entry date"],["Description"],["Target"],["Old password"],["New password"],["Repeat"],["User is administrator"]]);
\ No newline at end of file
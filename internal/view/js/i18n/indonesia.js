@@ -41,6 +41,8 @@ export default new Map([
 	["Repeat password", "Ulangi password"],
 	["Welcome, new user", "Selamat datang, user baru"],
 	["Original logo by $author from $website", "Logo asli dibuat oleh $author dari $website"],
+	["Authentication or recovery code", "Kode autentikasi atau kode pemulihan"],
+	["Verify", "Verifikasi"],
 	// Register and login screen -- API message
 	["new password doesn't match", "password baru yang diulang tidak cocok"],
 
@@ -1 +1 @@
-export default new Map([["locale","id-ID"],["Jan","Jan"],["Feb","Feb"],["Mar","Mar"],["Apr","Apr"],["May","Mei"],["Jun","Jun"],["Jul","Jul"],["Aug","Agu"],["Sep","Sep"],["Oct","Okt"],["Nov","Nov"],["Dec","Dec"],["January","Januari"],["February","Februari"],["March","Maret"],["April","April"],["May","Mei"],["June","Juni"],["July","Juli"],["August","Agustus"],["September","September"],["October","Oktober"],["November","November"],["December","Desember"],["Yes","Ya"],["No","Tidak"],["OK","OK"],["Cancel","Cancel"],["Login","Login"],["Register","Register"],["Name","Nama"],["Username","Username"],["Password","Password"],["Repeat password","Ulangi password"],["Welcome, new user","Selamat datang, user baru"],["Original logo by $author from $website","Logo asli dibuat oleh $author dari $website"],["new password doesn't match","password baru yang diulang tidak cocok"],["Logout","Logout"],["Change Password","Ganti Password"],["Change Language","Ganti Bahasa"],["Log out from the application ?","Yakin ingin keluar dari aplikasi ?"],["Home","Home"],["Money chart","Grafik keuangan"],["User management","Kelola user"],["Change password","Ganti password"],["Change language","Ganti bahasa"],["New Account","Akun Baru"],["Edit Account","Edit Akun"],["Delete Account","Hapus Akun"],["Entry Type","Jenis Entry"],["New Income","Pemasukan Baru"],["New Expense","Pengeluaran Baru"],["New Transfer","Transfer Baru"],["Edit Income","Edit Pemasukan"],["Edit Expense","Edit Pengeluaran"],["Edit Transfer","Edit Transfer"],["Delete Entry","Hapus Entry"],["Permanently delete $n accounts ?","Yakin ingin menghapus $n akun ?"],["Permanently delete $n entries ?","Yakin ingin menghapus $n entry ?"],["New User","User Baru"],["Edit User","Edit User"],["Delete User","Hapus User"],["Reset Password","Reset Password"],["Permanently delete $n users ?","Yakin ingin menghapus $n user ?"],["Reset password for $name ?","Reset password untuk user $name ?"],["Data for active user has been updated, please login again","Data untuk user yang aktif telah diperbarui, silakan login kembali"],["Current active user has been deleted, please login again","User yang aktif telah dihapus, silakan login kembali"],["Password for active user has been reset, please login again","Password untuk user yang aktif telah direset, silakan login kembali"],["User saved with password $password","User disimpan dengan password $password"],["New password: $password","Password yang baru: $password"],["No chart data available","Tidak ada data yang tersedia"],["Last year","Tahun lalu"],["Next year","Tahun depan"],["Account List","Daftar Akun"],["Edit account","Edit akun"],["Delete account","Hapus akun"],["New account","Akun baru"],["No accounts registered","Belum ada akun yang terdaftar"],["Entry List","Daftar Entry"],["Edit entry","Edit entry"],["Delete entry","Hapus entry"],["New entry","Entry baru"],["No entries registered","Belum ada entry yang terdaftar"],["Received from $name","Masuk dari $name"],["Transferred to $name","Dipindah ke $name"],["First page","Halaman pertama"],["Previous page","Halaman sebelumnya"],["Next page","Halaman selanjutnya"],["Last page","Halaman terakhir"],["Go back","Kembali"],["User List","Daftar User"],["Edit user","Edit user"],["Reset user's password","Reset password user"],["Delete user","Hapus user"],["New user","User baru"],["No users registered","Belum ada user yang terdaftar"],["English","Inggris"],["Indonesia","Indonesia"],["Income","Pemasukan"],["Expense","Pengeluaran"],["Transfer","Transfer"],["Initial amount","Jumlah awal"],["Amount","Jumlah"],["Entry date","Tanggal entry"],["Description","Deskripsi"],["Target","Tujuan"],["Old password","Password lama"],["New password","Password baru"],["Repeat","Ulangi"],["User is administrator","User adalah administrator"]]);
\ No newline at end of file
+export default new Map([["locale","id-ID"],["Jan","Jan"],["Feb","Feb"],["Mar","Mar"],["Apr","Apr"],["May","Mei"],["Jun","Jun"],["Jul","Jul"],["Aug","Agu"],["Sep","Sep"],["Oct","Okt"],["Nov","Nov"],["Dec","Dec"],["January","Januari"],["February","Februari"],["March","Maret"],["April","April"],["May","Mei"],["June","Juni"],["July","Juli"],["August","Agustus"],["September","September"],["October","Oktober"],["November","November"],["December","Desember"],["Yes","Ya"],["No","Tidak"],["OK","OK"],["Cancel","Cancel"],["Login","Login"],["Register","Register"],["Name","Nama"],["Username","Username"],["Password","Password"],["Repeat password","Ulangi password"],["Welcome, new user","Selamat datang, user baru"],["Original logo by $author from $website","Logo asli dibuat oleh $author dari $website"],["Authentication or recovery code","Kode autentikasi atau kode pemulihan"],["Verify","Verifikasi"],["new password doesn't match","password baru yang diulang tidak cocok"],["Logout","Logout"],["Change Password","Ganti Password"],["Change Language","Ganti Bahasa"],["Log out from the application ?","Yakin ingin keluar dari aplikasi ?"],["Home","Home"],["Money chart","Grafik keuangan"],["User management","Kelola user"],["Change password","Ganti password"],["Change language","Ganti bahasa"],["New Account","Akun Baru"],["Edit Account","Edit Akun"],["Delete Account","Hapus Akun"],["Entry Type","Jenis Entry"],["New Income","Pemasukan Baru"],["New Expense","Pengeluaran Baru"],["New Transfer","Transfer Baru"],["Edit Income","Edit Pemasukan"],["Edit Expense","Edit Pengeluaran"],["Edit Transfer","Edit Transfer"],["Delete Entry","Hapus Entry"],["Permanently delete $n accounts ?","Yakin ingin menghapus $n akun ?"],["Permanently delete $n entries ?","Yakin ingin menghapus $n entry ?"],["New User","User Baru"],["Edit User","Edit User"],["Delete User","Hapus User"],["Reset Password","Reset Password"],["Permanently delete $n users ?","Yakin ingin menghapus $n user ?"],["Reset password for $name ?","Reset password untuk user $name ?"],["Data for active user has been updated, please login again","Data untuk user yang aktif telah diperbarui, silakan login kembali"],["Current active user has been deleted, please login again","User yang aktif telah dihapus, silakan login kembali"],["Password for active user has been reset, please login again","Password untuk user yang aktif telah direset, silakan login kembali"],["User saved with password $password","User disimpan dengan password $password"],["New password: $password","Password yang baru: $password"],["No chart data available","Tidak ada data yang tersedia"],["Last year","Tahun lalu"],["Next year","Tahun depan"],["Account List","Daftar Akun"],["Edit account","Edit akun"],["Delete account","Hapus akun"],["New account","Akun baru"],["No accounts registered","Belum ada akun yang terdaftar"],["Entry List","Daftar Entry"],["Edit entry","Edit entry"],["Delete entry","Hapus entry"],["New entry","Entry baru"],["No entries registered","Belum ada entry yang terdaftar"],["Received from $name","Masuk dari $name"],["Transferred to $name","Dipindah ke $name"],["First page","Halaman pertama"],["Previous page","Halaman sebelumnya"],["Next page","Halaman selanjutnya"],["Last page","Halaman terakhir"],["Go back","Kembali"],["User List","Daftar User"],["Edit user","Edit user"],["Reset user's password","Reset password user"],["Delete user","Hapus user"],["New user","User baru"],["No users registered","Belum ada user yang terdaftar"],["English","Inggris"],["Indonesia","Indonesia"],["Income","Pemasukan"],["Expense","Pengeluaran"],["Transfer","Transfer"],["Initial amount","Jumlah awal"],["Amount","Jumlah"],["Entry date","Tanggal entry"],["Description","Deskripsi"],["Target","Tujuan"],["Old password","Password lama"],["New password","Password baru"],["Repeat","Ulangi"],["User is administrator","User adalah administrator"]]);
\ No newline at end of file
@@ -32,8 +32,12 @@ export async function request(url, ms, options) {
 		response = await (timeout(ms, fetchRequest))
 
 	if (!response.ok) {
-		let responseText = await response.text()
-		throw Error(`${responseText.trim()} (${response.status})`)
+		let message = (await response.text()).trim()
+		if (response.headers.get("content-type") === "application/json") {
+			try { message = JSON.parse(message).message } catch (e) { }
+		}
+
+		throw Error(`${message} (${response.status})`)
 	}
 
 	if (response.headers.get("content-type") === "application/json") {
@@ -18,24 +18,30 @@ function loginScreen() {
 		loading: false,
 		username: "",
 		password: "",
+		token: "",
+		code: "",
 		error: ""
 	}
 
-	function login() {
+	function submit(url, body) {
 		state.loading = true
 		state.error = ""
 		m.redraw()
 
 		let options = {
 			method: "POST",
-			body: JSON.stringify({
-				username: state.username,
-				password: state.password
-			})
+			body: JSON.stringify(body)
 		}
 
-		request("/api/login", "5s", options)
+		request(url, "5s", options)
 			.then(json => {
+				// If two-factor is enabled, ask for the code first
+				if (json.twoFactor) {
+					state.token = json.token
+					state.code = ""
+					return
+				}
+
 				let session = json.session,
 					user = json.user || null
 
@@ -52,6 +58,20 @@ function loginScreen() {
 			})
 	}
 
+	function login() {
+		submit("/api/login", {
+			username: state.username,
+			password: state.password
+		})
+	}
+
+	function verifyCode() {
+		submit("/api/login/2fa", {
+			token: state.token,
+			code: state.code
+		})
+	}
+
 	function renderView() {
 		let errorNodes = []
 		if (state.error !== "") {
@@ -83,6 +103,39 @@ function loginScreen() {
 			return str + " "
 		})
 
+		if (state.token !== "") {
+			return m(".login",
+				m(".login__body",
+					...errorNodes,
+					m(".login__form",
+						m("img.login__logo", {
+							src: "/res/logo.svg"
+						}),
+						m("input[type=text].login__input", {
+							value: state.code,
+							placeholder: i18n("Authentication or recovery code"),
+							autocomplete: "one-time-code",
+							oninput(e) { state.code = e.target.value }
+						}),
+						m(Button, {
+							class: "login__button",
+							caption: i18n("Verify"),
+							loading: state.loading,
+							onclick() {
+								if (state.code === "") {
+									return
+								}
+
+								verifyCode()
+							}
+						})
+					),
+				),
+				m("p.attribution", attributionNodes),
+				...loadingCover
+			)
+		}
+
 		return m(".login",
 			m(".login__body",
 				...errorNodes,
@@ -124,9 +177,17 @@ function loginScreen() {
 		vnode.dom.querySelector(".login__input").focus()
 	}
 
+	function onViewUpdated(vnode) {
+		// Focus the code input once it's shown
+		if (state.token !== "" && state.code === "") {
+			vnode.dom.querySelector(".login__input").focus()
+		}
+	}
+
 	return {
 		view: renderView,
 		oncreate: onViewCreated,
+		onupdate: onViewUpdated,
 	}
 }
 
@@ -1 +1 @@
-import{Button,LoadingCover}from"./components/_components.min.js";import{request}from"./libs/utils.min.js";import{i18n}from"./i18n/i18n.min.js";import Cookies from"./libs/js-cookie.min.js";function loginScreen(){let o={loading:!1,username:"",password:"",error:""};return{view:function(){let e=[];""!==o.error&&e.push(m("p.login__error",o.error));let n=[];o.loading&&n.push(m(LoadingCover));let r=i18n("Original logo by $author from $website").split(" ").map(o=>"$author"===o?m("a.attribution__link",{target:"_blank",rel:"noopener",href:"https://www.flaticon.com/authors/freepik"},"Freepik "):"$website"===o?m("a.attribution__link",{target:"_blank",rel:"noopener",href:"https://www.flaticon.com"},"www.flaticon.com "):o+" ");return m(".login",m(".login__body",...e,m(".login__form",m("img.login__logo",{src:"/res/logo.svg"}),m("input[type=text].login__input",{value:o.username,placeholder:i18n("Username"),oninput(e){o.username=e.target.value}}),m("input[type=password].login__input",{value:o.password,placeholder:i18n("Password"),oninput(e){o.password=e.target.value}}),m(Button,{class:"login__button",caption:i18n("Login"),loading:o.loading,onclick(){""!==o.username&&""!==o.password&&function(){o.loading=!0,o.error="",m.redraw();let e={method:"POST",body:JSON.stringify({username:o.username,password:o.password})};request("/api/login","5s",e).then(o=>{let e=o.session,n=o.user||null;Cookies.set("session-duit",e,{expires:365}),localStorage.setItem("duit-user",JSON.stringify(n)),window.location.href="/"}).catch(e=>{o.error=e.message}).finally(()=>{o.loading=!1,m.redraw()})}()}}))),m("p.attribution",r),...n)},oncreate:function(o){o.dom.querySelector(".login__input").focus()}}}export function startApp(){m.mount(document.body,loginScreen)}
\ No newline at end of file
+import{Button,LoadingCover}from"./components/_components.min.js";import{request}from"./libs/utils.min.js";import{i18n}from"./i18n/i18n.min.js";import Cookies from"./libs/js-cookie.min.js";function loginScreen(){let o={loading:!1,username:"",password:"",token:"",code:"",error:""};function t(t,e){o.loading=!0,o.error="",m.redraw();let n={method:"POST",body:JSON.stringify(e)};request(t,"5s",n).then(t=>{if(t.twoFactor)return o.token=t.token,void(o.code="");let e=t.session,n=t.user||null;Cookies.set("session-duit",e,{expires:365}),localStorage.setItem("duit-user",JSON.stringify(n)),window.location.href="/"}).catch(t=>{o.error=t.message}).finally(()=>{o.loading=!1,m.redraw()})}return{view:function(){let e=[];""!==o.error&&e.push(m("p.login__error",o.error));let n=[];o.loading&&n.push(m(LoadingCover));let r=i18n("Original logo by $author from $website").split(" ").map(o=>"$author"===o?m("a.attribution__link",{target:"_blank",rel:"noopener",href:"https://www.flaticon.com/authors/freepik"},"Freepik "):"$website"===o?m("a.attribution__link",{target:"_blank",rel:"noopener",href:"https://www.flaticon.com"},"www.flaticon.com "):o+" ");return""!==o.token?m(".login",m(".login__body",...e,m(".login__form",m("img.login__logo",{src:"/res/logo.svg"}),m("input[type=text].login__input",{value:o.code,placeholder:i18n("Authentication or recovery code"),autocomplete:"one-time-code",oninput(t){o.code=t.target.value}}),m(Button,{class:"login__button",caption:i18n("Verify"),loading:o.loading,onclick(){""!==o.code&&t("/api/login/2fa",{token:o.token,code:o.code})}}))),m("p.attribution",r),...n):m(".login",m(".login__body",...e,m(".login__form",m("img.login__logo",{src:"/res/logo.svg"}),m("input[type=text].login__input",{value:o.username,placeholder:i18n("Username"),oninput(t){o.username=t.target.value}}),m("input[type=password].login__input",{value:o.password,placeholder:i18n("Password"),oninput(t){o.password=t.target.value}}),m(Button,{class:"login__button",caption:i18n("Login"),loading:o.loading,onclick(){""!==o.username&&""!==o.password&&t("/api/login",{username:o.username,password:o.password})}}))),m("p.attribution",r),...n)},oncreate:function(o){o.dom.querySelector(".login__input").focus()},onupdate:function(t){""!==o.token&&""===o.code&&t.dom.querySelector(".login__input").focus()}}}export function startApp(){m.mount(document.body,loginScreen)}
\ No newline at end of file
@@ -12,6 +12,8 @@ import (
 	"github.com/RadhiFadlillah/duit/internal/database"
 	"github.com/RadhiFadlillah/duit/internal/model"
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -36,7 +38,14 @@ func main() {
 	}
 
 	cmd.Flags().IntP("port", "p", 8080, "port used by the server")
-	cmd.Flags().StringP("config", "c", defaultConfigPath, "path to config file")
+	cmd.PersistentFlags().StringP("config", "c", defaultConfigPath, "path to config file")
+
+	cmd.AddCommand(migrateCmd())
+	cmd.AddCommand(ratesCmd())
+	cmd.AddCommand(importCmd())
+	cmd.AddCommand(exportCmd())
+	cmd.AddCommand(backupCmd())
+	cmd.AddCommand(restoreCmd())
 
 	// Execute
 	err := cmd.Execute()
@@ -48,27 +57,59 @@ func main() {
 func cmdHandler(cmd *cobra.Command, args []string) error {
 	// Get flags value
 	port, _ := cmd.Flags().GetInt("port")
-	configPath, _ := cmd.Flags().GetString("config")
 
-	// Decode config file
-	var config model.Config
-	_, err := toml.DecodeFile(configPath, &config)
+	// Open database
+	config, err := readConfig(cmd)
 	if err != nil {
-		logrus.Fatalf("Server error: %v\n", err)
+		return err
 	}
 
-	// Open database
-	db, err := database.Open(config)
+	db, err := openDatabase(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return err
 	}
 	defer db.Close()
 
+	// Make sure database schema is up to date
+	err = database.MigrateUp(db, 0)
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
 	// Start backend
-	err = backend.ServeApp(db, port)
+	err = backend.ServeApp(db, config, port)
 	if err != nil {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
 
 	return nil
 }
+
+// readConfig decodes config file from the command flag.
+func readConfig(cmd *cobra.Command) (model.Config, error) {
+	var config model.Config
+	configPath, _ := cmd.Flags().GetString("config")
+	_, err := toml.DecodeFile(configPath, &config)
+	if err != nil {
+		return model.Config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	return config, nil
+}
+
+// openDatabase opens database using config file from the command flag.
+func openDatabase(cmd *cobra.Command) (*sqlx.DB, error) {
+	// Decode config file
+	config, err := readConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Open database
+	db, err := database.Open(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/RadhiFadlillah/duit/internal/database"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+)
+
+func migrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage version of database schema",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show status of every migration",
+		Args:  cobra.NoArgs,
+		RunE:  migrateStatusHandler,
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "up [version]",
+		Short: "Apply migrations up to the specified version, or to the latest if not specified",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  migrateUpHandler,
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "down [steps]",
+		Short: "Revert the specified number of latest migrations, 1 by default",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  migrateDownHandler,
+	})
+
+	return cmd
+}
+
+func migrateStatusHandler(cmd *cobra.Command, args []string) error {
+	// Open database
+	db, err := openDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Fetch migrations status
+	migrations, err := database.Migrations(db)
+	if err != nil {
+		return err
+	}
+
+	current, err := database.SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	// Print the status
+	fmt.Printf("Current schema version: %d\n\n", current)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tDESCRIPTION\tAPPLIED AT")
+	for _, m := range migrations {
+		appliedAt := m.AppliedAt.String
+		if !m.AppliedAt.Valid {
+			appliedAt = "pending"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\n", m.Version, m.Description, appliedAt)
+	}
+
+	return w.Flush()
+}
+
+func migrateUpHandler(cmd *cobra.Command, args []string) error {
+	// Parse target version
+	var target int
+	if len(args) > 0 {
+		var err error
+		target, err = strconv.Atoi(args[0])
+		if err != nil || target <= 0 {
+			return fmt.Errorf("invalid version: %s", args[0])
+		}
+	}
+
+	// Open database
+	db, err := openDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Apply migrations
+	err = database.MigrateUp(db, target)
+	if err != nil {
+		return err
+	}
+
+	return printSchemaVersion(db)
+}
+
+func migrateDownHandler(cmd *cobra.Command, args []string) error {
+	// Parse number of steps
+	steps := 1
+	if len(args) > 0 {
+		var err error
+		steps, err = strconv.Atoi(args[0])
+		if err != nil || steps <= 0 {
+			return fmt.Errorf("invalid number of steps: %s", args[0])
+		}
+	}
+
+	// Open database
+	db, err := openDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Revert migrations
+	err = database.MigrateDown(db, steps)
+	if err != nil {
+		return err
+	}
+
+	return printSchemaVersion(db)
+}
+
+func printSchemaVersion(db *sqlx.DB) error {
+	version, err := database.SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Schema version is now", version)
+	return nil
+}
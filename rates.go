@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/RadhiFadlillah/duit/internal/backend/api"
+	"github.com/RadhiFadlillah/duit/internal/model"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/cobra"
+)
+
+func ratesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rates",
+		Short: "Manage currency exchange rates",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "import [file]",
+		Short: "Import exchange rates from CSV file with columns date,from,to,rate",
+		Long: "Import exchange rates from CSV file with columns date,from,to,rate.\n" +
+			"Date is formatted as YYYY-MM-DD and rate is how many units of \"to\" currency\n" +
+			"equals to one unit of \"from\" currency. The existing rate for the same date\n" +
+			"and currencies will be replaced.",
+		Args: cobra.ExactArgs(1),
+		RunE: ratesImportHandler,
+	})
+
+	return cmd
+}
+
+func ratesImportHandler(cmd *cobra.Command, args []string) error {
+	// Read rates from file
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	rates, err := parseRatesCSV(f)
+	if err != nil {
+		return err
+	}
+
+	// Open database
+	db, err := openDatabase(cmd)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Save to database in one transaction
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmtDelete, err := tx.Preparex(`DELETE FROM currency_rate
+		WHERE date = ? AND from_currency = ? AND to_currency = ?`)
+	if err != nil {
+		return err
+	}
+
+	stmtInsert, err := tx.Preparex(`INSERT INTO currency_rate
+		(date, from_currency, to_currency, rate) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+
+	for _, rate := range rates {
+		_, err = stmtDelete.Exec(rate.Date, rate.From, rate.To)
+		if err != nil {
+			return err
+		}
+
+		_, err = stmtInsert.Exec(rate.Date, rate.From, rate.To, rate.Rate)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d exchange rates\n", len(rates))
+	return nil
+}
+
+// parseRatesCSV parses exchange rates from CSV. The first line
+// will be skipped if it's a header, i.e. its date is not valid.
+func parseRatesCSV(r io.Reader) ([]model.Rate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	rates := []model.Rate{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err = time.Parse("2006-01-02", record[0]); err != nil {
+			if line == 1 {
+				continue
+			}
+
+			return nil, fmt.Errorf("line %d: invalid date %q", line, record[0])
+		}
+
+		value, err := decimal.NewFromString(strings.TrimSpace(record[3]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, record[3])
+		}
+
+		rate := model.Rate{
+			Date: record[0],
+			From: record[1],
+			To:   record[2],
+			Rate: value,
+		}
+
+		if err = api.NormalizeRate(&rate); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseRatesCSV(t *testing.T) {
+	content := "date,from,to,rate\n" +
+		"2020-01-01,usd,IDR,13882.5\n" +
+		"2020-01-02, EUR , usd , 1.12\n"
+
+	rates, err := parseRatesCSV(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expected := []struct {
+		date, from, to, rate string
+	}{
+		{"2020-01-01", "USD", "IDR", "13882.5"},
+		{"2020-01-02", "EUR", "USD", "1.12"},
+	}
+
+	if len(rates) != len(expected) {
+		t.Fatalf("expected %d rates, got %d", len(expected), len(rates))
+	}
+
+	for i, e := range expected {
+		got := rates[i]
+		if got.Date != e.date || got.From != e.from || got.To != e.to ||
+			!got.Rate.Equal(decimal.RequireFromString(e.rate)) {
+			t.Errorf("rate %d: expected %v, got %+v", i, e, got)
+		}
+	}
+}
+
+func TestParseRatesCSVError(t *testing.T) {
+	tests := map[string]string{
+		"invalid date":         "2020-01-01,USD,IDR,1\n2020-13-01,USD,IDR,1\n",
+		"symbol in currency":   "2020-01-01,US$,IDR,1\n",
+		"digit in currency":    "2020-01-01,USD,12A,1\n",
+		"long currency":        "2020-01-01,USDT,IDR,1\n",
+		"same currencies":      "2020-01-01,usd,USD,1\n",
+		"invalid rate":         "2020-01-01,USD,IDR,abc\n",
+		"zero rate":            "2020-01-01,USD,IDR,0\n",
+		"wrong number of cols": "2020-01-01,USD,IDR\n",
+	}
+
+	for name, content := range tests {
+		if _, err := parseRatesCSV(strings.NewReader(content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}